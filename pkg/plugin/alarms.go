@@ -0,0 +1,562 @@
+// Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+// Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+
+package plugin
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+// queryScope resolves where the alarms or audit events of a query are read: the tenancy access, the compartment
+// and the regions. When no compartment is selected the root compartment of the tenancy is used, with its subtree.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - qm: The query model.
+//
+// Returns:
+//   - *TenancyAccess: The tenancy access of the query.
+//   - string: The compartment OCID.
+//   - bool: True when the compartment subtree has to be included.
+//   - []string: The regions to query.
+//   - error: An error if the tenancy is not configured or no region is selected.
+func (o *OCIDatasource) queryScope(ctx context.Context, qm *models.QueryModel) (*TenancyAccess, string, bool, []string, error) {
+	var takey string
+	if qm.TenancyOCID == "select tenancy" && o.settings.TenancyMode != "multitenancy" {
+		takey = o.GetTenancyAccessKey(constants.DEFAULT_PROFILE)
+	} else {
+		takey = o.GetTenancyAccessKey(qm.TenancyOCID)
+	}
+	if len(takey) == 0 {
+		return nil, "", false, nil, errors.New("Datasource not configured (invalid takey)")
+	}
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		return nil, "", false, nil, err
+	}
+	if stErr := o.checkSessionToken(takey); stErr != nil {
+		return nil, "", false, nil, stErr
+	}
+
+	compartmentOCID := qm.CompartmentOCID
+	inSubtree := false
+	if compartmentOCID == "" || compartmentOCID == constants.DEFAULT_COMPARTMENT_PLACEHOLDER {
+		tenancyocid, err := o.FetchTenancyOCID(takey)
+		if err != nil {
+			return nil, "", false, nil, err
+		}
+		compartmentOCID = tenancyocid
+		inSubtree = true
+	}
+
+	var regions []string
+	switch qm.Region {
+	case "", "select region":
+		return nil, "", false, nil, errors.New("region is mandatory for alarm and audit queries")
+	case constants.ALL_REGION:
+		for _, region := range o.GetSubscribedRegions(ctx, qm.TenancyOCID) {
+			if region != constants.ALL_REGION {
+				regions = append(regions, region)
+			}
+		}
+	default:
+		regions = []string{qm.Region}
+	}
+
+	return ta, compartmentOCID, inSubtree, regions, nil
+}
+
+// listAlarms lists the alarms of a compartment in a region, following the pagination.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - client: The monitoring client of the region.
+//   - compartmentOCID: The compartment OCID.
+//   - inSubtree: True to include the alarms of the sub-compartments.
+//   - name: The display name of the alarm, empty for all alarms.
+//
+// Returns:
+//   - []monitoring.AlarmSummary: The alarms.
+//   - error: An error if the alarms cannot be listed.
+func listAlarms(ctx context.Context, client monitoring.MonitoringClient, compartmentOCID string, inSubtree bool, name string) ([]monitoring.AlarmSummary, error) {
+	req := monitoring.ListAlarmsRequest{
+		CompartmentId:          common.String(compartmentOCID),
+		CompartmentIdInSubtree: common.Bool(inSubtree),
+		LifecycleState:         monitoring.AlarmLifecycleStateActive,
+	}
+	if name != "" {
+		req.DisplayName = common.String(name)
+	}
+
+	var alarms []monitoring.AlarmSummary
+	for {
+		res, err := client.ListAlarms(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		alarms = append(alarms, res.Items...)
+		if res.OpcNextPage == nil {
+			break
+		}
+		req.Page = res.OpcNextPage
+	}
+
+	return alarms, nil
+}
+
+// GetAlarmTransitions returns the state transitions of the alarms selected by the query between start and end.
+//
+// The alarms of every region are listed first, then their state transition history is fetched by a bounded
+// pool of workers (constants.MAX_ALARM_WORKERS). A failure in one region does not stop the others.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - qm: The query model, its tenancy, compartment, region and alarm name select the alarms.
+//   - start: The start of the time range.
+//   - end: The end of the time range.
+//
+// Returns:
+//   - []models.OCIAlarmTransition: The transitions sorted by time.
+//   - map[string]error: The error, keyed by region, for the regions that failed.
+//   - error: An error if the query is not valid.
+func (o *OCIDatasource) GetAlarmTransitions(ctx context.Context, qm *models.QueryModel, start time.Time, end time.Time) ([]models.OCIAlarmTransition, map[string]error, error) {
+	ta, compartmentOCID, inSubtree, regions, err := o.queryScope(ctx, qm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type alarmInRegion struct {
+		alarm  monitoring.AlarmSummary
+		region string
+	}
+
+	regionErrors := map[string]error{}
+	var toFetch []alarmInRegion
+	for _, region := range regions {
+		alarms, err := listAlarms(ctx, ta.MonitoringClientForRegion(region), compartmentOCID, inSubtree, qm.AlarmName)
+		if err != nil {
+			backend.Logger.Error("client", "GetAlarmTransitions", "region "+region+": "+err.Error())
+			regionErrors[region] = err
+			continue
+		}
+		for _, alarm := range alarms {
+			toFetch = append(toFetch, alarmInRegion{alarm: alarm, region: region})
+		}
+	}
+
+	var transitions []models.OCIAlarmTransition
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	workers := constants.MAX_ALARM_WORKERS
+	if len(toFetch) < workers {
+		workers = len(toFetch)
+	}
+
+	alarmCh := make(chan alarmInRegion)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range alarmCh {
+				entries, err := alarmHistory(ctx, ta.MonitoringClientForRegion(item.region), *item.alarm.Id, start, end)
+
+				mu.Lock()
+				if err != nil {
+					backend.Logger.Error("client", "GetAlarmTransitions", "alarm "+*item.alarm.Id+": "+err.Error())
+					if _, ok := regionErrors[item.region]; !ok {
+						regionErrors[item.region] = err
+					}
+				}
+				dimensions := alarmQueryDimensions(stringValue(item.alarm.Query))
+				for _, entry := range entries {
+					transition := models.OCIAlarmTransition{
+						AlarmID:    *item.alarm.Id,
+						AlarmName:  stringValue(item.alarm.DisplayName),
+						Severity:   string(item.alarm.Severity),
+						State:      alarmTransitionState(stringValue(entry.Summary)),
+						Summary:    stringValue(entry.Summary),
+						Dimensions: dimensions,
+						Region:     item.region,
+						Time:       entry.Timestamp.Time,
+					}
+					if entry.AlarmSummary != nil {
+						transition.AlarmSummary = *entry.AlarmSummary
+					}
+					// a firing transition is placed when the alarm triggered, the others when they happened
+					if entry.TimestampTriggered != nil && transition.State == "FIRING" {
+						transition.Time = entry.TimestampTriggered.Time
+					}
+					transitions = append(transitions, transition)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, item := range toFetch {
+		select {
+		case alarmCh <- item:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(alarmCh)
+	wg.Wait()
+
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].Time.Before(transitions[j].Time)
+	})
+
+	return transitions, regionErrors, nil
+}
+
+// listAlarmsStatus lists the status of the alarms of a compartment in a region, following the pagination.
+func listAlarmsStatus(ctx context.Context, client monitoring.MonitoringClient, compartmentOCID string, inSubtree bool, name string) ([]monitoring.AlarmStatusSummary, error) {
+	req := monitoring.ListAlarmsStatusRequest{
+		CompartmentId:          common.String(compartmentOCID),
+		CompartmentIdInSubtree: common.Bool(inSubtree),
+	}
+	if name != "" {
+		req.DisplayName = common.String(name)
+	}
+
+	var statuses []monitoring.AlarmStatusSummary
+	for {
+		res, err := client.ListAlarmsStatus(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, res.Items...)
+		if res.OpcNextPage == nil {
+			break
+		}
+		req.Page = res.OpcNextPage
+	}
+
+	return statuses, nil
+}
+
+// GetAlarmsStatus returns the current status of the alarms selected by the query.
+//
+// The status of the alarms is read with ListAlarmsStatus and completed with the namespace and the MQL query
+// of the alarm definitions. A failure in one region does not stop the others.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - qm: The query model, its tenancy, compartment, region and alarm name select the alarms.
+//
+// Returns:
+//   - []models.OCIAlarmStatus: The alarms, firing alarms first, then by severity and name.
+//   - map[string]error: The error, keyed by region, for the regions that failed.
+//   - error: An error if the query is not valid.
+func (o *OCIDatasource) GetAlarmsStatus(ctx context.Context, qm *models.QueryModel) ([]models.OCIAlarmStatus, map[string]error, error) {
+	ta, compartmentOCID, inSubtree, regions, err := o.queryScope(ctx, qm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	regionErrors := map[string]error{}
+	var alarmsStatus []models.OCIAlarmStatus
+	for _, region := range regions {
+		client := ta.MonitoringClientForRegion(region)
+
+		statuses, err := listAlarmsStatus(ctx, client, compartmentOCID, inSubtree, qm.AlarmName)
+		if err != nil {
+			backend.Logger.Error("client", "GetAlarmsStatus", "region "+region+": "+err.Error())
+			regionErrors[region] = err
+			continue
+		}
+		// the status does not hold the alarm query, it is read from the alarm definitions
+		alarms, err := listAlarms(ctx, client, compartmentOCID, inSubtree, qm.AlarmName)
+		if err != nil {
+			backend.Logger.Error("client", "GetAlarmsStatus", "region "+region+": "+err.Error())
+			regionErrors[region] = err
+		}
+		definitions := make(map[string]monitoring.AlarmSummary, len(alarms))
+		for _, alarm := range alarms {
+			definitions[*alarm.Id] = alarm
+		}
+
+		for _, status := range statuses {
+			alarmStatus := models.OCIAlarmStatus{
+				AlarmID:     stringValue(status.Id),
+				AlarmName:   stringValue(status.DisplayName),
+				Severity:    string(status.Severity),
+				State:       string(status.Status),
+				Suppression: formatSuppression(status.Suppression),
+				Region:      region,
+			}
+			if status.TimestampTriggered != nil {
+				alarmStatus.Time = status.TimestampTriggered.Time
+			}
+			if definition, ok := definitions[alarmStatus.AlarmID]; ok {
+				alarmStatus.Namespace = stringValue(definition.Namespace)
+				alarmStatus.Query = stringValue(definition.Query)
+			}
+			alarmsStatus = append(alarmsStatus, alarmStatus)
+		}
+	}
+
+	sort.SliceStable(alarmsStatus, func(i, j int) bool {
+		a, b := alarmsStatus[i], alarmsStatus[j]
+		if (a.State == string(monitoring.AlarmStatusSummaryStatusFiring)) != (b.State == string(monitoring.AlarmStatusSummaryStatusFiring)) {
+			return a.State == string(monitoring.AlarmStatusSummaryStatusFiring)
+		}
+		if severityRank(a.Severity) != severityRank(b.Severity) {
+			return severityRank(a.Severity) < severityRank(b.Severity)
+		}
+		return a.AlarmName < b.AlarmName
+	})
+
+	return alarmsStatus, regionErrors, nil
+}
+
+// severityRank orders the alarm severities from the most to the least severe.
+func severityRank(severity string) int {
+	switch severity {
+	case string(monitoring.AlarmStatusSummarySeverityCritical):
+		return 0
+	case string(monitoring.AlarmStatusSummarySeverityError):
+		return 1
+	case string(monitoring.AlarmStatusSummarySeverityWarning):
+		return 2
+	case string(monitoring.AlarmStatusSummarySeverityInfo):
+		return 3
+	}
+	return 4
+}
+
+// formatSuppression describes an alarm-wide suppression, empty when there is none.
+func formatSuppression(suppression *monitoring.Suppression) string {
+	if suppression == nil || suppression.TimeSuppressUntil == nil {
+		return ""
+	}
+	text := "until " + suppression.TimeSuppressUntil.Time.UTC().Format(time.RFC3339)
+	if suppression.Description != nil && *suppression.Description != "" {
+		text += ": " + *suppression.Description
+	}
+	return text
+}
+
+// alarmHistory returns the state transition history entries of an alarm between start and end.
+func alarmHistory(ctx context.Context, client monitoring.MonitoringClient, alarmOCID string, start time.Time, end time.Time) ([]monitoring.AlarmHistoryEntry, error) {
+	req := monitoring.GetAlarmHistoryRequest{
+		AlarmId:                       common.String(alarmOCID),
+		AlarmHistorytype:              monitoring.GetAlarmHistoryAlarmHistorytypeStateTransitionHistory,
+		TimestampGreaterThanOrEqualTo: &common.SDKTime{Time: start},
+		TimestampLessThan:             &common.SDKTime{Time: end},
+	}
+
+	var entries []monitoring.AlarmHistoryEntry
+	for {
+		res, err := client.GetAlarmHistory(ctx, req)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, res.Entries...)
+		if res.OpcNextPage == nil {
+			break
+		}
+		req.Page = res.OpcNextPage
+	}
+
+	return entries, nil
+}
+
+// alarmTransitionState returns the state an alarm transitioned to, read from the summary of the history entry
+// such as "State transitioned from OK to Firing". The summary is returned when it has no state.
+func alarmTransitionState(summary string) string {
+	idx := strings.LastIndex(summary, " to ")
+	if idx < 0 {
+		return summary
+	}
+	return strings.ToUpper(strings.TrimSpace(summary[idx+len(" to "):]))
+}
+
+// alarmQueryDimensions returns the dimensions filtered with an equality by an alarm MQL query,
+// for example {resourceId = "ocid1.instance..."} in CpuUtilization[1m]{resourceId = "ocid1.instance..."}.mean() > 80.
+func alarmQueryDimensions(query string) map[string]string {
+	dimensions := map[string]string{}
+
+	start := strings.Index(query, "{")
+	end := strings.Index(query, "}")
+	if start < 0 || end < start {
+		return dimensions
+	}
+	for _, clause := range strings.Split(query[start+1:end], ",") {
+		parts := strings.SplitN(clause, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		// != and =~ are not equalities
+		if key == "" || strings.HasSuffix(key, "!") || strings.HasPrefix(value, "~") {
+			continue
+		}
+		dimensions[key] = strings.Trim(value, "\"")
+	}
+
+	return dimensions
+}
+
+// formatDimensions returns the dimensions as a sorted key=value list.
+func formatDimensions(dimensions map[string]string) []string {
+	formatted := make([]string, 0, len(dimensions))
+	for key, value := range dimensions {
+		formatted = append(formatted, key+"="+value)
+	}
+	sort.Strings(formatted)
+	return formatted
+}
+
+// alarmHistoryQuery handles the alarm history query type: the state transitions of the alarms are returned
+// as an annotation frame, with the time, title, text, tags and color fields Grafana reads annotations from.
+//
+// Parameters:
+//   - ctx: The context for the query execution.
+//   - qm: The query model.
+//   - query: The data query, its time range limits the history.
+//
+// Returns:
+//   - backend.DataResponse: The response holding the annotation frame.
+func (ocidx *OCIDatasource) alarmHistoryQuery(ctx context.Context, qm *models.QueryModel, query backend.DataQuery) backend.DataResponse {
+	response := backend.DataResponse{}
+
+	transitions, regionErrors, err := ocidx.GetAlarmTransitions(ctx, qm, query.TimeRange.From.UTC(), query.TimeRange.To.UTC())
+	if err != nil {
+		response.Error = err
+		return response
+	}
+
+	times := make([]time.Time, 0, len(transitions))
+	titles := make([]string, 0, len(transitions))
+	texts := make([]string, 0, len(transitions))
+	tags := make([]string, 0, len(transitions))
+	colors := make([]string, 0, len(transitions))
+	alarms := make([]string, 0, len(transitions))
+	severities := make([]string, 0, len(transitions))
+	states := make([]string, 0, len(transitions))
+	regions := make([]string, 0, len(transitions))
+	dimensions := make([]string, 0, len(transitions))
+
+	for _, transition := range transitions {
+		formattedDimensions := formatDimensions(transition.Dimensions)
+
+		text := transition.Summary
+		if transition.AlarmSummary != "" {
+			text += "\n" + transition.AlarmSummary
+		}
+		if len(formattedDimensions) > 0 {
+			text += "\n" + strings.Join(formattedDimensions, ", ")
+		}
+
+		color := "green"
+		if transition.State == string(monitoring.AlarmStatusSummaryStatusFiring) {
+			color = "red"
+		}
+
+		times = append(times, transition.Time)
+		titles = append(titles, transition.AlarmName+": "+transition.State)
+		texts = append(texts, text)
+		tags = append(tags, strings.Join(append([]string{transition.Severity, transition.State, transition.Region}, formattedDimensions...), ","))
+		colors = append(colors, color)
+		alarms = append(alarms, transition.AlarmName)
+		severities = append(severities, transition.Severity)
+		states = append(states, transition.State)
+		regions = append(regions, transition.Region)
+		dimensions = append(dimensions, strings.Join(formattedDimensions, ", "))
+	}
+
+	frame := data.NewFrame("annotations",
+		data.NewField("time", nil, times),
+		data.NewField("title", nil, titles),
+		data.NewField("text", nil, texts),
+		data.NewField("tags", nil, tags),
+		data.NewField("color", nil, colors),
+		data.NewField("alarm", nil, alarms),
+		data.NewField("severity", nil, severities),
+		data.NewField("state", nil, states),
+		data.NewField("region", nil, regions),
+		data.NewField("dimensions", nil, dimensions),
+	).SetMeta(&data.FrameMeta{})
+	frame.Meta.Notices = append(frame.Meta.Notices, regionNoticesFor("alarm history", regionErrors)...)
+
+	response.Frames = append(response.Frames, frame)
+
+	return response
+}
+
+// alarmStatusQuery handles the alarm status query type: the current status of the alarms is returned as a table frame.
+//
+// Parameters:
+//   - ctx: The context for the query execution.
+//   - qm: The query model.
+//
+// Returns:
+//   - backend.DataResponse: The response holding the table frame.
+func (ocidx *OCIDatasource) alarmStatusQuery(ctx context.Context, qm *models.QueryModel) backend.DataResponse {
+	response := backend.DataResponse{}
+
+	alarmsStatus, regionErrors, err := ocidx.GetAlarmsStatus(ctx, qm)
+	if err != nil {
+		response.Error = err
+		return response
+	}
+
+	names := make([]string, 0, len(alarmsStatus))
+	severities := make([]string, 0, len(alarmsStatus))
+	namespaces := make([]string, 0, len(alarmsStatus))
+	queries := make([]string, 0, len(alarmsStatus))
+	states := make([]string, 0, len(alarmsStatus))
+	suppressions := make([]string, 0, len(alarmsStatus))
+	regions := make([]string, 0, len(alarmsStatus))
+	times := make([]*time.Time, 0, len(alarmsStatus))
+
+	for i := range alarmsStatus {
+		alarmStatus := alarmsStatus[i]
+		names = append(names, alarmStatus.AlarmName)
+		severities = append(severities, alarmStatus.Severity)
+		namespaces = append(namespaces, alarmStatus.Namespace)
+		queries = append(queries, alarmStatus.Query)
+		states = append(states, alarmStatus.State)
+		suppressions = append(suppressions, alarmStatus.Suppression)
+		regions = append(regions, alarmStatus.Region)
+		if alarmStatus.Time.IsZero() {
+			times = append(times, nil)
+		} else {
+			times = append(times, &alarmsStatus[i].Time)
+		}
+	}
+
+	frame := data.NewFrame("alarms",
+		data.NewField("alarm", nil, names),
+		data.NewField("severity", nil, severities),
+		data.NewField("namespace", nil, namespaces),
+		data.NewField("query", nil, queries),
+		data.NewField("state", nil, states),
+		data.NewField("suppression", nil, suppressions),
+		data.NewField("region", nil, regions),
+		data.NewField("timestamp", nil, times),
+	).SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeTable})
+	frame.Meta.Notices = append(frame.Meta.Notices, regionNoticesFor("alarms status", regionErrors)...)
+
+	response.Frames = append(response.Frames, frame)
+
+	return response
+}
@@ -0,0 +1,283 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+const alarmsPath = "/20180401/alarms"
+
+// alarmSummary is an alarm definition as listed by ListAlarms.
+func alarmSummary(id string, name string, severity string, query string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             id,
+		"displayName":    name,
+		"compartmentId":  testTenancyOCID,
+		"namespace":      "oci_computeagent",
+		"query":          query,
+		"severity":       severity,
+		"isEnabled":      true,
+		"lifecycleState": "ACTIVE",
+	}
+}
+
+// handlePages answers the requests of a pattern with the body of the requested page, at most two pages, the
+// opc-next-page header telling the page that follows.
+func handlePages(fake *fakeOCI, pattern string, pages []interface{}) {
+	fake.handle(pattern, func(w http.ResponseWriter, r *http.Request) {
+		page := 0
+		if r.URL.Query().Get("page") == "next" {
+			page = 1
+		}
+		if page+1 < len(pages) {
+			w.Header().Set("opc-next-page", "next")
+		}
+		writeJSON(w, http.StatusOK, pages[page])
+	})
+}
+
+// alarmQueryModel returns a query of the alarms of the whole test tenancy in a region.
+func alarmQueryModel(region string) *models.QueryModel {
+	return &models.QueryModel{TenancyOCID: "select tenancy", Region: region}
+}
+
+func TestAlarmTransitionState(t *testing.T) {
+	tests := []struct {
+		summary string
+		want    string
+	}{
+		{summary: "State transitioned from OK to Firing", want: "FIRING"},
+		{summary: "State transitioned from Firing to OK", want: "OK"},
+		{summary: "Alarm updated", want: "Alarm updated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.summary, func(t *testing.T) {
+			if got := alarmTransitionState(tt.summary); got != tt.want {
+				t.Errorf("alarmTransitionState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlarmQueryDimensions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string
+	}{
+		{name: "no dimension", query: "CpuUtilization[1m].mean() > 80", want: map[string]string{}},
+		{
+			name:  "equalities",
+			query: `CpuUtilization[1m]{resourceId = "ocid1.instance.oc1..a", availabilityDomain="AD-1"}.mean() > 80`,
+			want:  map[string]string{"resourceId": "ocid1.instance.oc1..a", "availabilityDomain": "AD-1"},
+		},
+		{
+			name:  "other operators left out",
+			query: `CpuUtilization[1m]{resourceId != "a", shape =~ "VM*", faultDomain = "FD-1"}.mean() > 80`,
+			want:  map[string]string{"faultDomain": "FD-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alarmQueryDimensions(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alarmQueryDimensions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAlarmTransitions(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) string {
+		return t0.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339)
+	}
+	regions := []string{"us-ashburn-1", "eu-frankfurt-1"}
+	o, servers := newRegionsDatasource(t, regions)
+
+	handlePages(servers[0], "GET "+alarmsPath, []interface{}{
+		[]map[string]interface{}{alarmSummary("ocid1.alarm.oc1..cpu", "cpu", "CRITICAL", `CpuUtilization[1m]{resourceId = "a"}.mean() > 80`)},
+		[]map[string]interface{}{alarmSummary("ocid1.alarm.oc1..memory", "memory", "WARNING", "MemoryUtilization[1m].mean() > 90")},
+	})
+	handlePages(servers[0], "GET "+alarmsPath+"/ocid1.alarm.oc1..cpu/history", []interface{}{
+		map[string]interface{}{"alarmId": "ocid1.alarm.oc1..cpu", "entries": []map[string]interface{}{
+			{"summary": "State transitioned from OK to Firing", "timestamp": at(12), "timestampTriggered": at(10), "alarmSummary": "CPU is high"},
+		}},
+		map[string]interface{}{"alarmId": "ocid1.alarm.oc1..cpu", "entries": []map[string]interface{}{
+			{"summary": "State transitioned from Firing to OK", "timestamp": at(30), "timestampTriggered": at(10)},
+		}},
+	})
+	servers[0].handleJSON("GET "+alarmsPath+"/ocid1.alarm.oc1..memory/history", http.StatusOK, map[string]interface{}{
+		"alarmId": "ocid1.alarm.oc1..memory",
+		"entries": []map[string]interface{}{{"summary": "State transitioned from OK to Firing", "timestamp": at(20)}},
+	})
+	servers[1].handleJSON("GET "+alarmsPath, http.StatusNotFound, ociError("NotAuthorizedOrNotFound", "alarms not found"))
+
+	qm := alarmQueryModel(constants.ALL_REGION)
+	transitions, regionErrors, err := o.GetAlarmTransitions(context.Background(), qm, t0, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetAlarmTransitions() error = %v", err)
+	}
+	want := []models.OCIAlarmTransition{
+		{
+			AlarmID: "ocid1.alarm.oc1..cpu", AlarmName: "cpu", Severity: "CRITICAL", State: "FIRING",
+			Summary: "State transitioned from OK to Firing", AlarmSummary: "CPU is high",
+			Dimensions: map[string]string{"resourceId": "a"}, Region: "us-ashburn-1", Time: t0.Add(10 * time.Minute),
+		},
+		{
+			AlarmID: "ocid1.alarm.oc1..memory", AlarmName: "memory", Severity: "WARNING", State: "FIRING",
+			Summary: "State transitioned from OK to Firing", Dimensions: map[string]string{}, Region: "us-ashburn-1", Time: t0.Add(20 * time.Minute),
+		},
+		{
+			AlarmID: "ocid1.alarm.oc1..cpu", AlarmName: "cpu", Severity: "CRITICAL", State: "OK",
+			Summary: "State transitioned from Firing to OK", Dimensions: map[string]string{"resourceId": "a"}, Region: "us-ashburn-1", Time: t0.Add(30 * time.Minute),
+		},
+	}
+	for i := range transitions {
+		transitions[i].Time = transitions[i].Time.UTC()
+	}
+	if !reflect.DeepEqual(transitions, want) {
+		t.Errorf("GetAlarmTransitions() = %+v, want %+v", transitions, want)
+	}
+	if len(regionErrors) != 1 || regionErrors["eu-frankfurt-1"] == nil {
+		t.Errorf("GetAlarmTransitions() region errors = %v, want eu-frankfurt-1", regionErrors)
+	}
+
+	// the alarms of the whole tenancy are listed
+	request := servers[0].requestsTo(alarmsPath)[0]
+	if request.query.Get("compartmentId") != testTenancyOCID || request.query.Get("compartmentIdInSubtree") != "true" {
+		t.Errorf("ListAlarms query = %v, want the tenancy with its subtree", request.query)
+	}
+	history := servers[0].requestsTo(alarmsPath + "/ocid1.alarm.oc1..memory/history")[0]
+	if history.query.Get("alarmHistorytype") != "STATE_TRANSITION_HISTORY" {
+		t.Errorf("GetAlarmHistory query = %v, want the state transition history", history.query)
+	}
+
+	t.Run("annotation frame", func(t *testing.T) {
+		response := o.alarmHistoryQuery(context.Background(), qm, backend.DataQuery{TimeRange: backend.TimeRange{From: t0, To: t0.Add(time.Hour)}})
+		if response.Error != nil {
+			t.Fatalf("alarmHistoryQuery() error = %v", response.Error)
+		}
+		frame := response.Frames[0]
+		if frame.Rows() != 3 {
+			t.Fatalf("alarmHistoryQuery() returned %d annotations, want 3", frame.Rows())
+		}
+		field := func(name string) interface{} {
+			f, _ := frame.FieldByName(name)
+			return f.At(0)
+		}
+		if field("title") != "cpu: FIRING" || field("color") != "red" || field("tags") != "CRITICAL,FIRING,us-ashburn-1,resourceId=a" ||
+			field("text") != "State transitioned from OK to Firing\nCPU is high\nresourceId=a" {
+			t.Errorf("first annotation = %v %v %v %v", field("title"), field("color"), field("tags"), field("text"))
+		}
+		if len(frame.Meta.Notices) != 1 {
+			t.Errorf("alarmHistoryQuery() notices = %v, want the failed region", frame.Meta.Notices)
+		}
+	})
+
+	t.Run("no region", func(t *testing.T) {
+		if _, _, err := o.GetAlarmTransitions(context.Background(), alarmQueryModel(""), t0, t0.Add(time.Hour)); err == nil {
+			t.Error("GetAlarmTransitions() error = nil, want the region to be mandatory")
+		}
+	})
+}
+
+func TestGetAlarmsStatus(t *testing.T) {
+	triggered := time.Date(2024, 1, 1, 0, 10, 0, 0, time.UTC)
+	status := func(id string, name string, severity string, state string) map[string]interface{} {
+		s := map[string]interface{}{"id": id, "displayName": name, "severity": severity, "status": state}
+		if state == "FIRING" {
+			s["timestampTriggered"] = triggered.Format(time.RFC3339)
+		}
+		return s
+	}
+	o, servers := newRegionsDatasource(t, []string{"us-ashburn-1"})
+	handlePages(servers[0], "GET "+alarmsPath+"/status", []interface{}{
+		[]map[string]interface{}{
+			status("ocid1.alarm.oc1..disk", "disk", "INFO", "OK"),
+			status("ocid1.alarm.oc1..memory", "memory", "WARNING", "FIRING"),
+		},
+		[]map[string]interface{}{
+			status("ocid1.alarm.oc1..cpu", "cpu", "CRITICAL", "FIRING"),
+			func() map[string]interface{} {
+				s := status("ocid1.alarm.oc1..load", "load", "CRITICAL", "SUSPENDED")
+				s["suppression"] = map[string]interface{}{"timeSuppressFrom": "2024-01-01T00:00:00Z", "timeSuppressUntil": "2024-01-02T00:00:00Z", "description": "maintenance"}
+				return s
+			}(),
+		},
+	})
+	servers[0].handleJSON("GET "+alarmsPath, http.StatusOK, []map[string]interface{}{
+		alarmSummary("ocid1.alarm.oc1..cpu", "cpu", "CRITICAL", "CpuUtilization[1m].mean() > 80"),
+	})
+
+	alarmsStatus, regionErrors, err := o.GetAlarmsStatus(context.Background(), alarmQueryModel("us-ashburn-1"))
+	if err != nil {
+		t.Fatalf("GetAlarmsStatus() error = %v", err)
+	}
+	if len(regionErrors) != 0 {
+		t.Errorf("GetAlarmsStatus() region errors = %v, want none", regionErrors)
+	}
+	for i := range alarmsStatus {
+		alarmsStatus[i].Time = alarmsStatus[i].Time.UTC()
+	}
+	want := []models.OCIAlarmStatus{
+		{AlarmID: "ocid1.alarm.oc1..cpu", AlarmName: "cpu", Severity: "CRITICAL", Namespace: "oci_computeagent", Query: "CpuUtilization[1m].mean() > 80", State: "FIRING", Region: "us-ashburn-1", Time: triggered},
+		{AlarmID: "ocid1.alarm.oc1..memory", AlarmName: "memory", Severity: "WARNING", State: "FIRING", Region: "us-ashburn-1", Time: triggered},
+		{AlarmID: "ocid1.alarm.oc1..load", AlarmName: "load", Severity: "CRITICAL", State: "SUSPENDED", Suppression: "until 2024-01-02T00:00:00Z: maintenance", Region: "us-ashburn-1"},
+		{AlarmID: "ocid1.alarm.oc1..disk", AlarmName: "disk", Severity: "INFO", State: "OK", Region: "us-ashburn-1"},
+	}
+	if !reflect.DeepEqual(alarmsStatus, want) {
+		t.Errorf("GetAlarmsStatus() = %+v, want %+v", alarmsStatus, want)
+	}
+
+	t.Run("table frame", func(t *testing.T) {
+		response := o.alarmStatusQuery(context.Background(), alarmQueryModel("us-ashburn-1"))
+		if response.Error != nil {
+			t.Fatalf("alarmStatusQuery() error = %v", response.Error)
+		}
+		frame := response.Frames[0]
+		if frame.Rows() != 4 || frame.Meta.PreferredVisualization != "table" {
+			t.Fatalf("alarmStatusQuery() = %d rows shown as %v, want 4 rows shown as a table", frame.Rows(), frame.Meta.PreferredVisualization)
+		}
+		timestamps, _ := frame.FieldByName("timestamp")
+		if first, last := timestamps.At(0).(*time.Time), timestamps.At(3).(*time.Time); first == nil || last != nil {
+			t.Errorf("timestamps = %v and %v, want the time of the firing alarm and null for the others", first, last)
+		}
+	})
+
+	t.Run("region failed", func(t *testing.T) {
+		o, servers := newRegionsDatasource(t, []string{"us-ashburn-1"})
+		servers[0].handleJSON("GET "+alarmsPath+"/status", http.StatusNotFound, ociError("NotAuthorizedOrNotFound", "alarms not found"))
+
+		response := o.alarmStatusQuery(context.Background(), alarmQueryModel("us-ashburn-1"))
+		if response.Error != nil || response.Frames[0].Rows() != 0 || len(response.Frames[0].Meta.Notices) != 1 {
+			t.Errorf("alarmStatusQuery() = %v, %v, want an empty table with a notice of the failed region", response.Frames, response.Error)
+		}
+	})
+}
+
+func TestSeverityRank(t *testing.T) {
+	severities := []string{"UNKNOWN", "INFO", "CRITICAL", "WARNING", "ERROR"}
+	ranked := append([]string{}, severities...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return severityRank(ranked[i]) < severityRank(ranked[j])
+	})
+	if want := []string{"CRITICAL", "ERROR", "WARNING", "INFO", "UNKNOWN"}; !reflect.DeepEqual(ranked, want) {
+		t.Errorf("severities ranked = %v, want %v", ranked, want)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+// Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+
+package plugin
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/oracle/oci-go-sdk/v65/audit"
+	"github.com/oracle/oci-go-sdk/v65/common"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+// GetAuditEvents returns the audit events of the compartment selected by the query between start and end.
+//
+// The events are listed for each region and filtered on the event type and the principal of the query,
+// both matched case-insensitively as substrings. The Audit service does not list the events of the
+// sub-compartments, so a query of the whole tenancy lists the events of each of its compartments, through a
+// pool of workers (constants.MAX_AUDIT_WORKERS). At most MaxPagesToFetch pages of events are read per
+// compartment and region, the truncated listings are reported as warnings. A failure in one region does not
+// stop the others.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - qm: The query model, its tenancy, compartment, region, event type and principal select the events.
+//   - start: The start of the time range.
+//   - end: The end of the time range.
+//
+// Returns:
+//   - []models.OCIAuditEvent: The events sorted by time.
+//   - map[string]error: The error, keyed by region, for the regions that failed.
+//   - []string: The warnings about incomplete results, such as listings truncated after MaxPagesToFetch pages.
+//   - error: An error if the query is not valid.
+func (o *OCIDatasource) GetAuditEvents(ctx context.Context, qm *models.QueryModel, start time.Time, end time.Time) ([]models.OCIAuditEvent, map[string]error, []string, error) {
+	ta, compartmentOCID, inSubtree, regions, err := o.queryScope(ctx, qm)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var warnings []string
+	compartments := []string{compartmentOCID}
+	if inSubtree {
+		tenancyCompartments := o.GetCompartments(ctx, qm.TenancyOCID)
+		if len(tenancyCompartments) == 0 {
+			warnings = append(warnings, "the compartments of the tenancy could not be listed, only the events of the root compartment are shown")
+		} else {
+			compartments = compartments[:0]
+			for _, compartment := range tenancyCompartments {
+				compartments = append(compartments, compartment.OCID)
+			}
+		}
+	}
+
+	type compartmentInRegion struct {
+		compartmentOCID string
+		region          string
+	}
+
+	var toList []compartmentInRegion
+	for _, region := range regions {
+		for _, compartment := range compartments {
+			toList = append(toList, compartmentInRegion{compartmentOCID: compartment, region: region})
+		}
+	}
+
+	eventType := strings.ToLower(qm.EventType)
+	principal := strings.ToLower(qm.Principal)
+
+	regionErrors := map[string]error{}
+	truncatedRegions := map[string]bool{}
+	var events []models.OCIAuditEvent
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	workers := constants.MAX_AUDIT_WORKERS
+	if len(toList) < workers {
+		workers = len(toList)
+	}
+
+	listCh := make(chan compartmentInRegion)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range listCh {
+				client, err := ta.AuditClientForRegion(item.region)
+				if err != nil {
+					mu.Lock()
+					regionErrors[item.region] = err
+					mu.Unlock()
+					continue
+				}
+
+				items, truncated, err := listAuditEvents(ctx, client, item.compartmentOCID, start, end)
+				mu.Lock()
+				if err != nil {
+					backend.Logger.Error("client", "GetAuditEvents", "region "+item.region+": "+err.Error())
+					if _, ok := regionErrors[item.region]; !ok {
+						regionErrors[item.region] = err
+					}
+				}
+				if truncated {
+					truncatedRegions[item.region] = true
+				}
+				for _, it := range items {
+					event := auditEvent(it, item.region)
+					if eventType != "" && !strings.Contains(strings.ToLower(event.EventType), eventType) &&
+						!strings.Contains(strings.ToLower(event.EventName), eventType) {
+						continue
+					}
+					if principal != "" && !strings.Contains(strings.ToLower(event.PrincipalName), principal) {
+						continue
+					}
+					events = append(events, event)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, item := range toList {
+		select {
+		case listCh <- item:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(listCh)
+	wg.Wait()
+
+	for _, region := range regions {
+		if truncatedRegions[region] {
+			warnings = append(warnings, "the audit events of region "+region+" are truncated after "+strconv.Itoa(MaxPagesToFetch)+
+				" pages per compartment, narrow the time range or filter on a compartment")
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	return events, regionErrors, warnings, nil
+}
+
+// listAuditEvents lists the audit events of a compartment, following the pagination up to MaxPagesToFetch pages.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - client: The audit client of the region.
+//   - compartmentOCID: The compartment of the events.
+//   - start: The start of the time range.
+//   - end: The end of the time range.
+//
+// Returns:
+//   - []audit.AuditEvent: The events listed, also when an error stopped the listing.
+//   - bool: True when more pages were left after MaxPagesToFetch pages.
+//   - error: The error of the listing, if any.
+func listAuditEvents(ctx context.Context, client audit.AuditClient, compartmentOCID string, start time.Time, end time.Time) ([]audit.AuditEvent, bool, error) {
+	req := audit.ListEventsRequest{
+		CompartmentId: common.String(compartmentOCID),
+		StartTime:     &common.SDKTime{Time: start},
+		EndTime:       &common.SDKTime{Time: end},
+	}
+
+	var items []audit.AuditEvent
+	for page := 0; page < MaxPagesToFetch; page++ {
+		res, err := client.ListEvents(ctx, req)
+		if err != nil {
+			return items, false, err
+		}
+		items = append(items, res.Items...)
+		if res.OpcNextPage == nil {
+			return items, false, nil
+		}
+		req.Page = res.OpcNextPage
+	}
+
+	return items, true, nil
+}
+
+// auditEvent converts an event of the Audit service.
+func auditEvent(item audit.AuditEvent, region string) models.OCIAuditEvent {
+	event := models.OCIAuditEvent{
+		EventID:   stringValue(item.EventId),
+		EventType: stringValue(item.EventType),
+		Source:    stringValue(item.Source),
+		Region:    region,
+	}
+	if item.EventTime != nil {
+		event.Time = item.EventTime.Time
+	}
+	if item.Data == nil {
+		return event
+	}
+
+	event.EventName = stringValue(item.Data.EventName)
+	event.CompartmentName = stringValue(item.Data.CompartmentName)
+	event.ResourceName = stringValue(item.Data.ResourceName)
+	event.ResourceID = stringValue(item.Data.ResourceId)
+	if item.Data.Identity != nil {
+		event.PrincipalName = stringValue(item.Data.Identity.PrincipalName)
+		event.IPAddress = stringValue(item.Data.Identity.IpAddress)
+	}
+	if item.Data.Request != nil {
+		event.Action = stringValue(item.Data.Request.Action)
+		event.Path = stringValue(item.Data.Request.Path)
+	}
+	if item.Data.Response != nil {
+		event.Status = stringValue(item.Data.Response.Status)
+		event.Message = stringValue(item.Data.Response.Message)
+	}
+
+	return event
+}
+
+// auditEventLevel returns the log level of an event from its response status.
+func auditEventLevel(status string) string {
+	code, err := strconv.Atoi(status)
+	switch {
+	case err != nil:
+		return "info"
+	case code >= 500:
+		return "error"
+	case code >= 400:
+		return "warning"
+	}
+	return "info"
+}
+
+// auditEventsQuery handles the audit events query type. The events are returned as a single frame that is
+// read both as annotations, through its time, title, text and tags fields, and as logs: the frame prefers
+// the logs visualization, its first string field being the log line and the level field its severity.
+//
+// Parameters:
+//   - ctx: The context for the query execution.
+//   - qm: The query model.
+//   - query: The data query, its time range limits the events.
+//
+// Returns:
+//   - backend.DataResponse: The response holding the events frame.
+func (ocidx *OCIDatasource) auditEventsQuery(ctx context.Context, qm *models.QueryModel, query backend.DataQuery) backend.DataResponse {
+	response := backend.DataResponse{}
+
+	events, regionErrors, warnings, err := ocidx.GetAuditEvents(ctx, qm, query.TimeRange.From.UTC(), query.TimeRange.To.UTC())
+	if err != nil {
+		response.Error = err
+		return response
+	}
+
+	times := make([]time.Time, 0, len(events))
+	texts := make([]string, 0, len(events))
+	titles := make([]string, 0, len(events))
+	tags := make([]string, 0, len(events))
+	levels := make([]string, 0, len(events))
+	ids := make([]string, 0, len(events))
+	eventTypes := make([]string, 0, len(events))
+	principals := make([]string, 0, len(events))
+	resources := make([]string, 0, len(events))
+	compartments := make([]string, 0, len(events))
+	regions := make([]string, 0, len(events))
+	statuses := make([]string, 0, len(events))
+
+	for _, event := range events {
+		resource := event.ResourceName
+		if resource == "" {
+			resource = event.ResourceID
+		}
+
+		text := event.EventName + " " + resource + " by " + event.PrincipalName
+		if event.Status != "" {
+			text += " (" + event.Action + " " + event.Status + ")"
+		}
+		if event.Message != "" {
+			text += ": " + event.Message
+		}
+
+		times = append(times, event.Time)
+		texts = append(texts, text)
+		titles = append(titles, event.EventName)
+		tags = append(tags, strings.Join([]string{event.Source, event.EventName, event.PrincipalName, event.Region}, ","))
+		levels = append(levels, auditEventLevel(event.Status))
+		ids = append(ids, event.EventID)
+		eventTypes = append(eventTypes, event.EventType)
+		principals = append(principals, event.PrincipalName)
+		resources = append(resources, resource)
+		compartments = append(compartments, event.CompartmentName)
+		regions = append(regions, event.Region)
+		statuses = append(statuses, event.Status)
+	}
+
+	frame := data.NewFrame("audit",
+		data.NewField("time", nil, times),
+		data.NewField("text", nil, texts),
+		data.NewField("title", nil, titles),
+		data.NewField("tags", nil, tags),
+		data.NewField("level", nil, levels),
+		data.NewField("id", nil, ids),
+		data.NewField("eventType", nil, eventTypes),
+		data.NewField("principal", nil, principals),
+		data.NewField("resource", nil, resources),
+		data.NewField("compartment", nil, compartments),
+		data.NewField("region", nil, regions),
+		data.NewField("status", nil, statuses),
+	).SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeLogs})
+	frame.Meta.Notices = append(frame.Meta.Notices, regionNoticesFor("audit events", regionErrors)...)
+	for _, warning := range warnings {
+		frame.Meta.Notices = append(frame.Meta.Notices, data.Notice{Severity: data.NoticeSeverityWarning, Text: warning})
+	}
+
+	response.Frames = append(response.Frames, frame)
+
+	return response
+}
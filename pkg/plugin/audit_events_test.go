@@ -0,0 +1,195 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+const auditEventsPath = "/20190901/auditEvents"
+
+// auditEventItem is an event of the Audit service in a compartment.
+func auditEventItem(id string, compartmentOCID string, at time.Time, eventName string, principal string, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"eventType":          "com.oraclecloud.computeApi." + eventName,
+		"cloudEventsVersion": "0.1",
+		"eventTypeVersion":   "2.0",
+		"source":             "ComputeApi",
+		"eventId":            id,
+		"eventTime":          at.Format(time.RFC3339),
+		"contentType":        "application/json",
+		"data": map[string]interface{}{
+			"eventName":       eventName,
+			"compartmentId":   compartmentOCID,
+			"compartmentName": "name of " + compartmentOCID,
+			"resourceName":    "instance-a",
+			"resourceId":      "ocid1.instance.oc1..a",
+			"identity":        map[string]string{"principalName": principal, "ipAddress": "192.0.2.1"},
+			"request":         map[string]string{"action": "POST", "path": "/20160918/instances"},
+			"response":        map[string]string{"status": status, "message": eventName + " " + status},
+		},
+	}
+}
+
+func TestGetAuditEvents(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	const (
+		child = "ocid1.compartment.oc1..child"
+		busy  = "ocid1.compartment.oc1..busy"
+	)
+	o, servers := newRegionsDatasource(t, []string{"us-ashburn-1", "eu-frankfurt-1"})
+	servers[0].handleJSON("GET /20160918/tenancies/{tenancyId}", http.StatusOK, map[string]string{"id": testTenancyOCID, "name": "test"})
+	servers[0].handleJSON("GET /20160918/compartments", http.StatusOK, []map[string]string{
+		{"id": child, "compartmentId": testTenancyOCID, "name": "child", "lifecycleState": "ACTIVE"},
+		{"id": busy, "compartmentId": testTenancyOCID, "name": "busy", "lifecycleState": "ACTIVE"},
+	})
+	servers[0].handle("GET "+auditEventsPath, func(w http.ResponseWriter, r *http.Request) {
+		switch compartment := r.URL.Query().Get("compartmentId"); compartment {
+		case testTenancyOCID:
+			writeJSON(w, http.StatusOK, []map[string]interface{}{
+				auditEventItem("root-1", compartment, t0.Add(30*time.Minute), "TerminateInstance", "alice", "204"),
+			})
+		case child:
+			writeJSON(w, http.StatusOK, []map[string]interface{}{
+				auditEventItem("child-1", compartment, t0.Add(10*time.Minute), "LaunchInstance", "alice", "200"),
+				auditEventItem("child-2", compartment, t0.Add(20*time.Minute), "LaunchInstance", "bob", "404"),
+			})
+		case busy:
+			// every page has another page after it
+			w.Header().Set("opc-next-page", "next")
+			writeJSON(w, http.StatusOK, []map[string]interface{}{})
+		}
+	})
+	servers[1].handleJSON("GET "+auditEventsPath, http.StatusNotFound, ociError("NotAuthorizedOrNotFound", "events not found"))
+
+	qm := &models.QueryModel{TenancyOCID: "select tenancy", Region: constants.ALL_REGION}
+	events, regionErrors, warnings, err := o.GetAuditEvents(context.Background(), qm, t0, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetAuditEvents() error = %v", err)
+	}
+
+	ids := []string{}
+	for _, event := range events {
+		ids = append(ids, event.EventID)
+	}
+	if want := []string{"child-1", "child-2", "root-1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetAuditEvents() events = %v, want %v sorted by time", ids, want)
+	}
+	if len(regionErrors) != 1 || regionErrors["eu-frankfurt-1"] == nil {
+		t.Errorf("GetAuditEvents() region errors = %v, want eu-frankfurt-1", regionErrors)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "region us-ashburn-1 are truncated") {
+		t.Errorf("GetAuditEvents() warnings = %v, want the truncated listing of us-ashburn-1", warnings)
+	}
+
+	// each compartment of the tenancy is listed, the busy one up to MaxPagesToFetch pages
+	pages := map[string]int{}
+	for _, request := range servers[0].requestsTo(auditEventsPath) {
+		pages[request.query.Get("compartmentId")]++
+	}
+	if want := map[string]int{testTenancyOCID: 1, child: 1, busy: MaxPagesToFetch}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("ListEvents requests per compartment = %v, want %v", pages, want)
+	}
+
+	want := models.OCIAuditEvent{
+		EventID: "child-2", EventType: "com.oraclecloud.computeApi.LaunchInstance", EventName: "LaunchInstance", Source: "ComputeApi",
+		CompartmentName: "name of " + child, ResourceName: "instance-a", ResourceID: "ocid1.instance.oc1..a", PrincipalName: "bob",
+		IPAddress: "192.0.2.1", Action: "POST", Path: "/20160918/instances", Status: "404", Message: "LaunchInstance 404",
+		Region: "us-ashburn-1", Time: t0.Add(20 * time.Minute),
+	}
+	events[1].Time = events[1].Time.UTC()
+	if !reflect.DeepEqual(events[1], want) {
+		t.Errorf("GetAuditEvents() event = %+v, want %+v", events[1], want)
+	}
+
+	t.Run("filters", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			eventType string
+			principal string
+			want      []string
+		}{
+			{name: "event name", eventType: "launch", want: []string{"child-1", "child-2"}},
+			{name: "event type", eventType: "computeApi.Terminate", want: []string{"root-1"}},
+			{name: "principal", principal: "ALICE", want: []string{"child-1", "root-1"}},
+			{name: "event type and principal", eventType: "launch", principal: "bob", want: []string{"child-2"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				filtered := &models.QueryModel{TenancyOCID: "select tenancy", Region: "us-ashburn-1", EventType: tt.eventType, Principal: tt.principal}
+				events, _, _, err := o.GetAuditEvents(context.Background(), filtered, t0, t0.Add(time.Hour))
+				if err != nil {
+					t.Fatalf("GetAuditEvents() error = %v", err)
+				}
+				ids := []string{}
+				for _, event := range events {
+					ids = append(ids, event.EventID)
+				}
+				sort.Strings(ids)
+				if !reflect.DeepEqual(ids, tt.want) {
+					t.Errorf("GetAuditEvents() events = %v, want %v", ids, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("selected compartment", func(t *testing.T) {
+		selected := &models.QueryModel{TenancyOCID: "select tenancy", Region: "us-ashburn-1", CompartmentOCID: child}
+		events, _, warnings, err := o.GetAuditEvents(context.Background(), selected, t0, t0.Add(time.Hour))
+		if err != nil || len(events) != 2 || len(warnings) != 0 {
+			t.Errorf("GetAuditEvents() = %d events, %v, %v, want the 2 events of the compartment", len(events), warnings, err)
+		}
+	})
+
+	t.Run("events frame", func(t *testing.T) {
+		response := o.auditEventsQuery(context.Background(), qm, backend.DataQuery{TimeRange: backend.TimeRange{From: t0, To: t0.Add(time.Hour)}})
+		if response.Error != nil {
+			t.Fatalf("auditEventsQuery() error = %v", response.Error)
+		}
+		frame := response.Frames[0]
+		if frame.Rows() != 3 {
+			t.Fatalf("auditEventsQuery() returned %d rows, want 3", frame.Rows())
+		}
+		levels, _ := frame.FieldByName("level")
+		if levels == nil || levels.At(0) != "info" || levels.At(1) != "warning" {
+			t.Errorf("levels = %v, want the level of each response status", levels)
+		}
+		if len(frame.Meta.Notices) != 2 {
+			t.Errorf("auditEventsQuery() notices = %v, want the failed region and the truncated listing", frame.Meta.Notices)
+		}
+	})
+}
+
+func TestAuditEventLevel(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{status: "200", want: "info"},
+		{status: "404", want: "warning"},
+		{status: "503", want: "error"},
+		{status: "", want: "info"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := auditEventLevel(tt.status); got != tt.want {
+				t.Errorf("auditEventLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+)
+
+// coalescedCall is an upstream call shared by the concurrent calls with the same key.
+type coalescedCall struct {
+	// ctx is the context of the upstream call, cancelled once no call waits for it anymore
+	ctx    context.Context
+	cancel context.CancelFunc
+	// waiters counts the calls waiting for the upstream call, guarded by OCIDatasource.inflightMu
+	waiters int
+}
+
+// coalesce runs fetch once for all the concurrent calls with the same key, the calls made while it runs wait for
+// it and share its result.
+//
+// fetch runs on a context that is not cancelled with the context of the call that started it, so that a cancelled
+// call does not fail the other calls sharing the result. Each call stops waiting when its own context is cancelled,
+// and fetch is cancelled when the last call waiting for it stops waiting.
+//
+// Parameters:
+//   - ctx: The context of the call.
+//   - o: The datasource, holding the calls in flight.
+//   - key: The key of the call, calls with the same key share the result.
+//   - fetch: The upstream call.
+//
+// Returns:
+//   - T: The result of fetch.
+//   - error: The error of fetch, or the error of ctx when it is cancelled first.
+func coalesce[T any](ctx context.Context, o *OCIDatasource, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	o.inflightMu.Lock()
+	call, ok := o.inflightCalls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &coalescedCall{ctx: callCtx, cancel: cancel}
+		o.inflightCalls[key] = call
+	}
+	call.waiters++
+	o.inflightMu.Unlock()
+
+	defer func() {
+		o.inflightMu.Lock()
+		defer o.inflightMu.Unlock()
+
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			if o.inflightCalls[key] == call {
+				// the next calls start a new upstream call instead of sharing the cancelled one
+				delete(o.inflightCalls, key)
+				o.inflight.Forget(key)
+			}
+		}
+	}()
+
+	resultCh := o.inflight.DoChan(key, func() (interface{}, error) {
+		defer func() {
+			o.inflightMu.Lock()
+			defer o.inflightMu.Unlock()
+			if o.inflightCalls[key] == call {
+				delete(o.inflightCalls, key)
+			}
+		}()
+		return fetch(call.ctx)
+	})
+
+	select {
+	case result := <-resultCh:
+		value, _ := result.Val.(T)
+		return value, result.Err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// coalesceCached is coalesce for the results fetch stores in the cache under key. The cache is looked up again
+// before fetching: a call missing the cache just before a previous call for the key stored its result does not
+// fetch it again.
+//
+// Parameters:
+//   - ctx: The context of the call.
+//   - o: The datasource, holding the calls in flight and the cache.
+//   - key: The cache key of the result.
+//   - fetch: The upstream call, it stores its result in the cache.
+//
+// Returns:
+//   - T: The result of fetch, or the cached result.
+//   - error: The error of fetch, or the error of ctx when it is cancelled first.
+func coalesceCached[T any](ctx context.Context, o *OCIDatasource, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	return coalesce(ctx, o, key, func(ctx context.Context) (T, error) {
+		if cached, found := o.cache.Get(key); found {
+			if value, ok := cached.(T); ok {
+				return value, nil
+			}
+		}
+		return fetch(ctx)
+	})
+}
@@ -0,0 +1,217 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForWaiters waits until the given number of calls wait for the call in flight of key.
+func waitForWaiters(t *testing.T, o *OCIDatasource, key string, waiters int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		o.inflightMu.Lock()
+		call, ok := o.inflightCalls[key]
+		got := 0
+		if ok {
+			got = call.waiters
+		}
+		o.inflightMu.Unlock()
+		if got == waiters {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("the calls waiting for %v never reached %d", key, waiters)
+}
+
+func TestCoalesceConcurrentCalls(t *testing.T) {
+	o := newTestDatasource(t, "singletenancy")
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	fetch := func(ctx context.Context) (string, error) {
+		fetches.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	const callers = 5
+	results := make(chan string, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := coalesce(context.Background(), o, "key", fetch)
+			if err != nil {
+				t.Errorf("coalesce() error = %v", err)
+			}
+			results <- value
+		}()
+	}
+	waitForWaiters(t, o, "key", callers)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for value := range results {
+		if value != "value" {
+			t.Errorf("coalesce() = %v, want value", value)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetch called %d times, want 1", got)
+	}
+
+	// a call made once the shared call is done fetches again
+	release = make(chan struct{})
+	close(release)
+	if _, err := coalesce(context.Background(), o, "key", fetch); err != nil {
+		t.Fatalf("coalesce() error = %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("fetch called %d times after the shared call, want 2", got)
+	}
+}
+
+func TestCoalesceCancellation(t *testing.T) {
+	o := newTestDatasource(t, "singletenancy")
+	release := make(chan struct{})
+	fetchCtxs := make(chan context.Context, 2)
+	fetch := func(ctx context.Context) (string, error) {
+		fetchCtxs <- ctx
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	// the call starting the fetch is cancelled, the other call still gets the result
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := coalesce(firstCtx, o, "key", fetch)
+		firstErr <- err
+	}()
+	fetchCtx := <-fetchCtxs
+
+	secondResult := make(chan string, 1)
+	go func() {
+		value, err := coalesce(context.Background(), o, "key", fetch)
+		if err != nil {
+			t.Errorf("coalesce() error = %v", err)
+		}
+		secondResult <- value
+	}()
+	waitForWaiters(t, o, "key", 2)
+
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("coalesce() of the cancelled call error = %v, want %v", err, context.Canceled)
+	}
+	if fetchCtx.Err() != nil {
+		t.Errorf("the fetch is cancelled while a call still waits for it")
+	}
+	close(release)
+	if value := <-secondResult; value != "value" {
+		t.Errorf("coalesce() = %v, want value", value)
+	}
+
+	// every call is cancelled, the fetch is cancelled as well
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := coalesce(ctx, o, "other", func(ctx context.Context) (string, error) {
+			fetchCtxs <- ctx
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		done <- err
+	}()
+	fetchCtx = <-fetchCtxs
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("coalesce() error = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-fetchCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the fetch is not cancelled once no call waits for it")
+	}
+
+	// the next call does not share the cancelled fetch
+	value, err := coalesce(context.Background(), o, "other", func(ctx context.Context) (string, error) {
+		return "new value", nil
+	})
+	if err != nil || value != "new value" {
+		t.Errorf("coalesce() after the cancelled fetch = %v, %v, want new value", value, err)
+	}
+}
+
+func TestCoalesceErrors(t *testing.T) {
+	o := newTestDatasource(t, "singletenancy")
+	fetchErr := errors.New("upstream failure")
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (string, error) {
+		<-release
+		return "", fetchErr
+	}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := coalesce(context.Background(), o, "key", fetch)
+			errs <- err
+		}()
+	}
+	waitForWaiters(t, o, "key", 2)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, fetchErr) {
+			t.Errorf("coalesce() error = %v, want %v", err, fetchErr)
+		}
+	}
+}
+
+func TestCoalesceCached(t *testing.T) {
+	o := newTestDatasource(t, "singletenancy")
+	fetches := 0
+	fetch := func(ctx context.Context) ([]string, error) {
+		fetches++
+		value := []string{"fetched"}
+		o.cache.Set("key", value, 1)
+		o.cache.Wait()
+		return value, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		value, err := coalesceCached(context.Background(), o, "key", fetch)
+		if err != nil || len(value) != 1 || value[0] != "fetched" {
+			t.Fatalf("coalesceCached() = %v, %v, want [fetched]", value, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("fetch called %d times, want 1 with the result cached", fetches)
+	}
+
+	// a cached value of another type is fetched again
+	o.cache.Set("mismatch", 1, 1)
+	o.cache.Wait()
+	if value, err := coalesceCached(context.Background(), o, "mismatch", func(ctx context.Context) (string, error) {
+		return "fetched", nil
+	}); err != nil || value != "fetched" {
+		t.Errorf("coalesceCached() = %v, %v, want fetched", value, err)
+	}
+}
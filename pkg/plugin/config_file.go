@@ -0,0 +1,358 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+// configFileWatch tracks the OCI CLI config file the tenancy accesses were loaded from, and the key files
+// its profiles reference.
+type configFileWatch struct {
+	mu          sync.Mutex
+	path        string
+	tenancymode string
+	modTimes    map[string]time.Time
+	lastCheck   time.Time
+}
+
+// configFileProfile is a profile of an OCI CLI config file with its settings.
+type configFileProfile struct {
+	name     string
+	settings map[string]string
+}
+
+// readConfigFile returns the profiles of an OCI CLI config file, in the order they are defined.
+// The file is read on every call: the OCI SDK keeps the config and key files it reads for the
+// life of the process, which would hide their changes.
+//
+// Parameters:
+//   - path: The path of the config file.
+//
+// Returns:
+//   - []configFileProfile: The profiles.
+//   - error: An error if the file cannot be read or has no profile.
+func readConfigFile(path string) ([]configFileProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var profiles []configFileProfile
+	index := map[string]int{}
+	current := -1
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" {
+				current = -1
+				continue
+			}
+			i, ok := index[name]
+			if !ok {
+				i = len(profiles)
+				index[name] = i
+				profiles = append(profiles, configFileProfile{name: name, settings: map[string]string{}})
+			}
+			current = i
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || current < 0 {
+			continue
+		}
+		profiles[current].settings[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, errors.New("no profile found in config file " + path)
+	}
+
+	return profiles, nil
+}
+
+// configFileReferencedFiles returns the key files and security token files referenced by the profiles of an
+// OCI CLI config file.
+//
+// Parameters:
+//   - profiles: The profiles of the config file.
+//
+// Returns:
+//   - []string: The paths of the files, with the home directory expanded.
+func configFileReferencedFiles(profiles []configFileProfile) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, profile := range profiles {
+		for _, setting := range []string{"key_file", "security_token_file"} {
+			file := expandHomeDir(profile.settings[setting])
+			if file != "" && !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+// expandHomeDir replaces the leading ~/ of a path with the home directory, as the OCI SDK does.
+func expandHomeDir(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// configFileModTimes returns the modification times of a config file and of the key files and security token
+// files it references. A missing file has the zero time, so that the profiles are reloaded once it is created.
+//
+// Parameters:
+//   - path: The path of the config file.
+//
+// Returns:
+//   - map[string]time.Time: The modification times keyed by path.
+//   - error: An error if the config file cannot be read.
+func configFileModTimes(path string) (map[string]time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	modTimes := map[string]time.Time{path: info.ModTime()}
+	for _, file := range configFileReferencedFiles(profiles) {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		} else {
+			modTimes[file] = time.Time{}
+		}
+	}
+	return modTimes, nil
+}
+
+// configFileProvider creates the configuration provider of a profile of an OCI CLI config file.
+// The key file and the security token file are read when the provider is created.
+//
+// Parameters:
+//   - profile: The profile.
+//
+// Returns:
+//   - common.ConfigurationProvider: The configuration provider.
+//   - error: An error if a setting is missing or a file cannot be read.
+func configFileProvider(profile configFileProfile) (common.ConfigurationProvider, error) {
+	settings := profile.settings
+	for _, setting := range []string{"tenancy", "region", "key_file"} {
+		if settings[setting] == "" {
+			return nil, errors.New(setting + " is missing")
+		}
+	}
+	privateKey, err := os.ReadFile(expandHomeDir(settings["key_file"]))
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading key file")
+	}
+	var passphrase *string
+	if value, ok := settings["pass_phrase"]; ok {
+		passphrase = &value
+	}
+	if err := validatePrivateKey(profile.name, string(privateKey), passphrase); err != nil {
+		return nil, err
+	}
+
+	if settings["security_token_file"] != "" {
+		token, err := os.ReadFile(expandHomeDir(settings["security_token_file"]))
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading security token file")
+		}
+		return NewSessionTokenConfigurationProvider(profile.name, settings["tenancy"], settings["region"], "", string(token), string(privateKey), passphrase)
+	}
+	for _, setting := range []string{"user", "fingerprint"} {
+		if settings[setting] == "" {
+			return nil, errors.New(setting + " is missing")
+		}
+	}
+	return common.NewRawConfigurationProvider(settings["tenancy"], settings["user"], settings["region"], settings["fingerprint"], string(privateKey), passphrase), nil
+}
+
+// loadConfigFile creates the tenancy accesses of the profiles of an OCI CLI config file.
+// Key files, passphrases and security token files are read from the settings of each profile.
+//
+// Parameters:
+//   - path: The path of the config file.
+//   - tenancymode: The tenancy mode, only the DEFAULT profile is loaded in single tenancy mode.
+//
+// Returns:
+//   - map[string]*TenancyAccess: The tenancy accesses keyed as in the local environment.
+//   - error: An error if the file cannot be read or a profile is not valid.
+func loadConfigFile(path string, tenancymode string) (map[string]*TenancyAccess, error) {
+	profiles, err := readConfigFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading config file")
+	}
+
+	tenancyAccess := make(map[string]*TenancyAccess)
+	for _, p := range profiles {
+		profile := p.name
+		if tenancymode != "multitenancy" && profile != "DEFAULT" {
+			backend.Logger.Debug("Single Tenancy mode detected, skipping additional profile", "profile", profile)
+			continue
+		}
+
+		configProvider, err := configFileProvider(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "error with profile "+profile)
+		}
+		tenancyocid, err := configProvider.TenancyOCID()
+		if err != nil {
+			return nil, errors.Wrap(err, "error with TenancyOCID of profile "+profile)
+		}
+
+		mrp := clientRetryPolicy()
+		monitoringClient, err := monitoring.NewMonitoringClientWithConfigurationProvider(configProvider)
+		if err != nil {
+			return nil, errors.Wrap(err, "error with client of profile "+profile)
+		}
+		monitoringClient.Configuration.RetryPolicy = &mrp
+
+		irp := clientRetryPolicy()
+		identityClient, err := identity.NewIdentityClientWithConfigurationProvider(configProvider)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error creating identity client of profile "+profile)
+		}
+		identityClient.Configuration.RetryPolicy = &irp
+
+		if tenancymode == "multitenancy" {
+			tenancyAccess[profile+"/"+tenancyocid] = NewTenancyAccess(monitoringClient, identityClient, configProvider, "")
+		} else {
+			tenancyAccess[SingleTenancyKey] = NewTenancyAccess(monitoringClient, identityClient, configProvider, "")
+		}
+	}
+	if len(tenancyAccess) == 0 {
+		return nil, errors.New("no usable profile in config file " + path + ", single tenancy mode requires a DEFAULT profile")
+	}
+
+	return tenancyAccess, nil
+}
+
+// setConfigFileTenancyAccess loads the profiles of the config file into the tenancy accesses
+// and starts tracking the file for changes.
+//
+// Parameters:
+//   - path: The path of the config file.
+//   - tenancymode: The tenancy mode.
+//
+// Returns:
+//   - error: An error if the config file cannot be loaded.
+func (o *OCIDatasource) setConfigFileTenancyAccess(path string, tenancymode string) error {
+	if path == "" {
+		path = constants.DEFAULT_CONFIG_FILE
+	}
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return errors.Wrap(err, "error expanding config file path")
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	modTimes, err := configFileModTimes(path)
+	if err != nil {
+		return errors.Wrap(err, "error reading config file")
+	}
+	tenancyAccess, err := loadConfigFile(path, tenancymode)
+	if err != nil {
+		return err
+	}
+
+	o.tenancyAccessMu.Lock()
+	o.tenancyAccess = tenancyAccess
+	o.tenancyAccessMu.Unlock()
+	o.configFile = &configFileWatch{
+		path:        path,
+		tenancymode: tenancymode,
+		modTimes:    modTimes,
+		lastCheck:   time.Now(),
+	}
+
+	return nil
+}
+
+// reloadConfigFileIfChanged reloads the tenancy accesses when the config file or one of the key files
+// it references was modified. The files are checked at most every constants.CONFIG_FILE_CHECK_INTERVAL. When the new content
+// cannot be loaded the previous tenancy accesses are kept.
+func (o *OCIDatasource) reloadConfigFileIfChanged() {
+	watch := o.configFile
+	if watch == nil {
+		return
+	}
+
+	watch.mu.Lock()
+	defer watch.mu.Unlock()
+
+	if time.Since(watch.lastCheck) < constants.CONFIG_FILE_CHECK_INTERVAL {
+		return
+	}
+	watch.lastCheck = time.Now()
+
+	modTimes, err := configFileModTimes(watch.path)
+	if err != nil {
+		backend.Logger.Error("reloadConfigFileIfChanged", "config file", watch.path, "error", err)
+		return
+	}
+	if sameModTimes(modTimes, watch.modTimes) {
+		return
+	}
+
+	tenancyAccess, err := loadConfigFile(watch.path, watch.tenancymode)
+	if err != nil {
+		backend.Logger.Error("reloadConfigFileIfChanged", "config file", watch.path, "keeping previous profiles, error", err)
+		return
+	}
+	watch.modTimes = modTimes
+
+	o.tenancyAccessMu.Lock()
+	o.tenancyAccess = tenancyAccess
+	o.tenancyAccessMu.Unlock()
+	backend.Logger.Info("reloadConfigFileIfChanged", "config file", watch.path, "profiles", len(tenancyAccess))
+}
+
+// sameModTimes tells whether two sets of modification times hold the same files modified at the same times.
+func sameModTimes(a map[string]time.Time, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, modTime := range a {
+		if other, ok := b[path]; !ok || !modTime.Equal(other) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,222 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+// writeConfigFile writes an OCI CLI config file with a profile per tenancy, keyed by profile name, every profile
+// using the given key file.
+func writeConfigFile(t *testing.T, path string, keyFile string, tenancies map[string]string) {
+	t.Helper()
+	profiles := make([]string, 0, len(tenancies))
+	for profile := range tenancies {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+
+	content := ""
+	for _, profile := range profiles {
+		content += "[" + profile + "]\n" +
+			"user=ocid1.user.oc1..test\n" +
+			"fingerprint=aa:bb:cc\n" +
+			"tenancy=" + tenancies[profile] + "\n" +
+			"region=us-ashburn-1\n" +
+			"key_file=" + keyFile + "\n\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// touch sets the modification time of a file to the given time.
+func touch(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tenancyKeys returns the sorted keys of the configured tenancy accesses.
+func tenancyKeys(o *OCIDatasource) []string {
+	keys := []string{}
+	for key := range o.tenancyAccessSnapshot() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// forceConfigFileCheck makes the next access check the config file without waiting for the check interval.
+func forceConfigFileCheck(o *OCIDatasource) {
+	o.configFile.mu.Lock()
+	defer o.configFile.mu.Unlock()
+	o.configFile.lastCheck = time.Now().Add(-constants.CONFIG_FILE_CHECK_INTERVAL)
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyFile, []byte(testPrivateKey(t)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config")
+	writeConfigFile(t, path, keyFile, map[string]string{"DEFAULT": "ocid1.tenancy.oc1..a", "other": "ocid1.tenancy.oc1..b"})
+
+	tests := []struct {
+		name        string
+		tenancymode string
+		want        []string
+	}{
+		{name: "single tenancy", tenancymode: "singletenancy", want: []string{SingleTenancyKey}},
+		{name: "multi tenancy", tenancymode: "multitenancy", want: []string{"DEFAULT/ocid1.tenancy.oc1..a", "other/ocid1.tenancy.oc1..b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenancyAccess, err := loadConfigFile(path, tt.tenancymode)
+			if err != nil {
+				t.Fatalf("loadConfigFile() error = %v", err)
+			}
+			got := []string{}
+			for key := range tenancyAccess {
+				got = append(got, key)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadConfigFile() keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid key file", func(t *testing.T) {
+		invalidKey := filepath.Join(dir, "invalid.pem")
+		if err := os.WriteFile(invalidKey, []byte("not a key"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		invalid := filepath.Join(dir, "invalid")
+		writeConfigFile(t, invalid, invalidKey, map[string]string{"DEFAULT": "ocid1.tenancy.oc1..a"})
+		if _, err := loadConfigFile(invalid, "singletenancy"); err == nil {
+			t.Error("loadConfigFile() error = nil, want an error")
+		}
+	})
+
+	t.Run("single tenancy without DEFAULT profile", func(t *testing.T) {
+		noDefault := filepath.Join(dir, "no-default")
+		writeConfigFile(t, noDefault, keyFile, map[string]string{"other": "ocid1.tenancy.oc1..b"})
+		if _, err := loadConfigFile(noDefault, "singletenancy"); err == nil {
+			t.Error("loadConfigFile() error = nil, want an error")
+		}
+	})
+}
+
+func TestReadConfigFile(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	path := filepath.Join(t.TempDir(), "config")
+	content := "# comment\n[DEFAULT]\nkey_file = ~/.oci/key.pem\npass_phrase=\n\n[other]\nkey_file=/keys/other.pem\n" +
+		"\n[again]\nkey_file=/keys/other.pem\nsecurity_token_file=/keys/token\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile() error = %v", err)
+	}
+	want := []configFileProfile{
+		{name: "DEFAULT", settings: map[string]string{"key_file": "~/.oci/key.pem", "pass_phrase": ""}},
+		{name: "other", settings: map[string]string{"key_file": "/keys/other.pem"}},
+		{name: "again", settings: map[string]string{"key_file": "/keys/other.pem", "security_token_file": "/keys/token"}},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("readConfigFile() = %v, want %v", profiles, want)
+	}
+
+	files := configFileReferencedFiles(profiles)
+	if want := []string{filepath.Join(home, ".oci/key.pem"), "/keys/other.pem", "/keys/token"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("configFileReferencedFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestReloadConfigFileIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyFile, []byte(testPrivateKey(t)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config")
+	writeConfigFile(t, path, keyFile, map[string]string{"DEFAULT": "ocid1.tenancy.oc1..a"})
+	modTime := time.Now().Add(-time.Hour)
+	touch(t, path, modTime)
+	touch(t, keyFile, modTime)
+
+	o := newTestDatasource(t, "multitenancy")
+	if err := o.setConfigFileTenancyAccess(path, "multitenancy"); err != nil {
+		t.Fatalf("setConfigFileTenancyAccess() error = %v", err)
+	}
+	first := o.getTenancyAccess("DEFAULT/ocid1.tenancy.oc1..a")
+	if first == nil {
+		t.Fatalf("tenancy accesses = %v, want the DEFAULT profile", tenancyKeys(o))
+	}
+
+	// the files are not checked again before the check interval
+	writeConfigFile(t, path, keyFile, map[string]string{"DEFAULT": "ocid1.tenancy.oc1..a", "other": "ocid1.tenancy.oc1..b"})
+	if got := tenancyKeys(o); len(got) != 1 {
+		t.Errorf("tenancy accesses = %v before the check interval, want the DEFAULT profile only", got)
+	}
+
+	// an added profile is loaded
+	forceConfigFileCheck(o)
+	if got, want := tenancyKeys(o), []string{"DEFAULT/ocid1.tenancy.oc1..a", "other/ocid1.tenancy.oc1..b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tenancy accesses = %v, want %v", got, want)
+	}
+	second := o.getTenancyAccess("DEFAULT/ocid1.tenancy.oc1..a")
+
+	// unchanged files are not reloaded
+	forceConfigFileCheck(o)
+	if o.getTenancyAccess("DEFAULT/ocid1.tenancy.oc1..a") != second {
+		t.Error("the profiles are reloaded while no file changed")
+	}
+
+	// a rotated key file reloads the profiles
+	if err := os.WriteFile(keyFile, []byte(newPrivateKey(t)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, keyFile, time.Now())
+	forceConfigFileCheck(o)
+	third := o.getTenancyAccess("DEFAULT/ocid1.tenancy.oc1..a")
+	if third == second {
+		t.Fatal("the profiles are not reloaded after the key file changed")
+	}
+	previousKey, _ := second.config.PrivateRSAKey()
+	rotatedKey, _ := third.config.PrivateRSAKey()
+	if previousKey == nil || rotatedKey == nil || previousKey.Equal(rotatedKey) {
+		t.Error("the reloaded profile does not sign with the rotated key")
+	}
+
+	// a config file that cannot be loaded keeps the previous profiles
+	if err := os.WriteFile(path, []byte("no profile\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path, time.Now().Add(time.Minute))
+	forceConfigFileCheck(o)
+	if got := tenancyKeys(o); len(got) != 2 {
+		t.Errorf("tenancy accesses = %v after an invalid change, want the previous profiles", got)
+	}
+	if _, err := o.tenancyAccessFor("removed/ocid1.tenancy.oc1..c"); err == nil {
+		t.Error("tenancyAccessFor() of a profile not configured error = nil, want an error")
+	}
+}
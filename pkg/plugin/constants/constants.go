@@ -25,8 +25,18 @@ const (
 	QUERYTYPE_COMPARTMENTS              = "compartments"
 	QUERYTYPE_NAMESPACES_WITH_METRICS   = "namespaces_with_metrics"
 	QUERYTYPE_METRICS_SUMMARY           = "metrics_summary"
+	QUERYTYPE_METRICS                   = "metrics"
+	QUERYTYPE_ALARM_HISTORY             = "alarm_history"
+	QUERYTYPE_ALARM_STATUS              = "alarm_status"
+	QUERYTYPE_AUDIT_EVENTS              = "audit_events"
 	CACHE_KEY_RESOURCE_TAGS             = "resourceTags"
 	CACHE_KEY_RESOURCE_IDS_PER_TAG      = "resourceIDsPerTag"
+	CACHE_KEY_RESOURCE_INFO             = "resourceInfo"
+	CACHE_KEY_METRIC_METADATA           = "metricMetadata"
+	CACHE_KEY_METRIC_DATA               = "metricData"
+	RESOURCE_SEARCH_BATCH_SIZE          = 50
+	RESOURCE_INFO_CACHE_TTL             = 15 * time.Minute
+	METRIC_METADATA_CACHE_TTL           = 24 * time.Hour
 	ALL_REGION                          = "all-subscribed-region"
 	ALL_COMPARTMENT                     = "all-compartment"
 	FETCH_FOR_NAMESPACE                 = "namespace"
@@ -34,6 +44,41 @@ const (
 	FETCH_FOR_DIMENSION                 = "dimension"
 	FETCH_FOR_LABELDIMENSION            = "labeldimension"
 	TIME_IN_MINUTES                     = 5 * time.Minute
+	MAX_REGION_WORKERS                  = 5
+	REGION_QUERY_TIMEOUT                = 60 * time.Second
+	AUTH_TYPE_API_KEY                   = "api_key"
+	AUTH_TYPE_SECURITY_TOKEN            = "security_token"
+	SESSION_TOKEN_REFRESH_WINDOW        = 5 * time.Minute
+	SESSION_TOKEN_REFRESH_RETRY         = 1 * time.Minute
+	DEFAULT_CONFIG_FILE                 = "~/.oci/config"
+	CONFIG_FILE_CHECK_INTERVAL          = 30 * time.Second
+	VAULT_SECRET_REFRESH_INTERVAL       = 15 * time.Minute
+	STREAM_PATH_PREFIX                  = "metrics/"
+	STREAM_MIN_POLL_INTERVAL            = 1 * time.Minute
+	STREAM_INITIAL_POINTS               = 10
+	MAX_ALARM_WORKERS                   = 5
+	MAX_AUDIT_WORKERS                   = 5
+	MAX_EXPANDED_QUERIES                = 25
+	FILL_MODE_NULL                      = "null"
+	FILL_MODE_ZERO                      = "zero"
+	FILL_MODE_PREVIOUS                  = "previous"
+	FRAME_FORMAT_MULTI                  = "multi"
+	FRAME_FORMAT_WIDE                   = "wide"
+	METRIC_WINDOW_LIMIT_MINUTELY        = 7 * 24 * time.Hour
+	METRIC_WINDOW_LIMIT_FIVE_MINUTES    = 30 * 24 * time.Hour
+	METRIC_WINDOW_LIMIT_HOURLY          = 90 * 24 * time.Hour
+	METRIC_WINDOW_MAX_DATAPOINTS        = 1440
+	METRIC_CACHE_BUCKET_POINTS          = 60
+	METRIC_CACHE_SETTLE_DELAY           = 10 * time.Minute
+	METRIC_CACHE_TTL                    = 1 * time.Hour
+	METRIC_CACHE_SERIES_COST            = 512
+	METRIC_CACHE_DATAPOINT_COST         = 64
+	AUTO_INTERVAL                       = "auto"
+	DEFAULT_INTERVAL_PLACEHOLDER        = "select interval"
+	DEFAULT_METRIC_PLACEHOLDER          = "select metric"
+	DEFAULT_STATISTIC_PLACEHOLDER       = "select aggregation"
+	DEFAULT_GROUPBY_PLACEHOLDER         = "select option (optional)"
+	DEFAULT_STATISTIC                   = "avg()"
 	OCI_TARGET_COMPUTE                  = "compute"
 	OCI_TARGET_VCN                      = "vcn"
 	OCI_TARGET_LBAAS                    = "lbaas"
@@ -59,4 +104,17 @@ var (
 		"oracle_external_database":          OCI_TARGET_DATABASE,
 		"oracle_apm_synthetics":             OCI_TARGET_APM,
 	}
+
+	// OCI_INTERVALS are the intervals the auto interval is chosen from, finest first
+	OCI_INTERVALS = []string{"1m", "5m", "1h", "1d"}
+
+	// OCI_RESOURCE_TYPES are the Resource Search types of the resources reporting the metrics of each target
+	OCI_RESOURCE_TYPES = map[string][]string{
+		OCI_TARGET_COMPUTE:     {"instance"},
+		OCI_TARGET_VCN:         {"vnic"},
+		OCI_TARGET_LBAAS:       {"loadbalancer"},
+		OCI_TARGET_HEALTHCHECK: {"httpmonitor", "pingmonitor"},
+		OCI_TARGET_DATABASE:    {"database", "autonomousdatabase", "pluggabledatabase", "externalpluggabledatabase"},
+		OCI_TARGET_APM:         {"apmdomain"},
+	}
 )
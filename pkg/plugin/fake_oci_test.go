@@ -0,0 +1,192 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/oracle/oci-go-sdk/v65/audit"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+const testTenancyOCID = "ocid1.tenancy.oc1..test"
+
+var (
+	testKeyOnce sync.Once
+	testKeyPEM  string
+)
+
+// testPrivateKey returns a PEM encoded RSA key, generated once for the tests of the package.
+func testPrivateKey(t *testing.T) string {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		testKeyPEM = newPrivateKey(t)
+	})
+	return testKeyPEM
+}
+
+// newPrivateKey returns a new PEM encoded RSA key.
+func newPrivateKey(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+// testConfigProvider returns a configuration provider of the test tenancy in the given region.
+func testConfigProvider(t *testing.T, region string) common.ConfigurationProvider {
+	t.Helper()
+	return common.NewRawConfigurationProvider(testTenancyOCID, "ocid1.user.oc1..test", region, "aa:bb:cc", testPrivateKey(t), nil)
+}
+
+// recordedRequest is a request received by a fakeOCI server.
+type recordedRequest struct {
+	method string
+	path   string
+	query  url.Values
+	body   []byte
+}
+
+// fakeOCI is an HTTP server standing in for the OCI services of a region, it records the requests it receives.
+// The requests without handler are answered with 404.
+type fakeOCI struct {
+	server *httptest.Server
+	mux    *http.ServeMux
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+// newFakeOCI starts a fakeOCI server, stopped at the end of the test.
+func newFakeOCI(t *testing.T) *fakeOCI {
+	t.Helper()
+	f := &fakeOCI{mux: http.NewServeMux()}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		f.mu.Lock()
+		f.requests = append(f.requests, recordedRequest{method: r.Method, path: r.URL.Path, query: r.URL.Query(), body: body})
+		f.mu.Unlock()
+
+		f.mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// handle registers the handler of a pattern, see http.ServeMux.
+func (f *fakeOCI) handle(pattern string, handler http.HandlerFunc) {
+	f.mux.HandleFunc(pattern, handler)
+}
+
+// handleJSON registers a handler answering every request of a pattern with the given status and JSON body.
+func (f *fakeOCI) handleJSON(pattern string, status int, body interface{}) {
+	f.handle(pattern, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, status, body)
+	})
+}
+
+// requestsTo returns the requests received for the given path.
+func (f *fakeOCI) requestsTo(path string) []recordedRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var requests []recordedRequest
+	for _, r := range f.requests {
+		if r.path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// writeJSON writes a JSON response, errors are written as the OCI services do.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("opc-request-id", "test")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// ociError is the body of the errors of the OCI services.
+func ociError(code string, message string) map[string]string {
+	return map[string]string{"code": code, "message": message}
+}
+
+// newFakeTenancyAccess returns the access to the test tenancy, configured in the first region given, whose
+// clients send the requests of each region to the fake server of the region.
+func newFakeTenancyAccess(t *testing.T, regions []string, servers []*fakeOCI) *TenancyAccess {
+	t.Helper()
+	config := testConfigProvider(t, regions[0])
+
+	mc, err := monitoring.NewMonitoringClientWithConfigurationProvider(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic, err := identity.NewIdentityClientWithConfigurationProvider(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.Host = servers[0].server.URL
+	ic.Host = servers[0].server.URL
+
+	ta := NewTenancyAccess(mc, ic, config, "")
+	for i, region := range regions {
+		regionMC := mc
+		regionMC.Host = servers[i].server.URL
+		ta.regionClients[region] = regionMC
+
+		ac, err := audit.NewAuditClientWithConfigurationProvider(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ac.Host = servers[i].server.URL
+		ta.auditClients[region] = ac
+
+		sc, err := resourcesearch.NewResourceSearchClientWithConfigurationProvider(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc.Host = servers[i].server.URL
+		ta.searchClients[region] = sc
+	}
+	return ta
+}
+
+// newTestDatasource returns a datasource of the given tenancy mode with a cache and no tenancy access.
+func newTestDatasource(t *testing.T, tenancyMode string) *OCIDatasource {
+	t.Helper()
+	cache, err := ristretto.NewCache(&ristretto.Config{NumCounters: 1e4, MaxCost: 1 << 20, BufferItems: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cache.Close)
+
+	o := NewOCIDatasourceConstructor()
+	o.settings = &models.OCIDatasourceSettings{TenancyMode: tenancyMode}
+	o.cache = cache
+	return o
+}
@@ -0,0 +1,268 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/mql"
+)
+
+// templateVariable matches the template variables left in a query by clients which do not interpolate them,
+// such as alert rules and reports: $name, ${name} and [[name]].
+var templateVariable = regexp.MustCompile(`\$\{[^}]*\}|\$[A-Za-z_]\w*|\[\[[^\]]*\]\]`)
+
+// multiValue matches a multi-value template variable interpolated in the default format of Grafana, {value1,value2}.
+var multiValue = regexp.MustCompile(`^\{([^{}]*)\}$`)
+
+// multiValueString matches the quoted dimension values of a MQL query which hold a multi-value template variable.
+var multiValueString = regexp.MustCompile(`"\{[^{}"]*,[^{}"]*\}"`)
+
+// splitMultiValue returns the values of a multi-value template variable, or the value itself.
+func splitMultiValue(value string) []string {
+	match := multiValue.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil || !strings.Contains(match[1], ",") {
+		return []string{value}
+	}
+
+	return splitArgs(match[1])
+}
+
+// normalizeQuery prepares a query sent by a client which may not have interpolated it, such as Grafana alerting
+// or image rendering: the placeholders of the query editor are resolved, multi-value template variables are
+// expanded and the template variables left unresolved are reported as errors rather than returning no data.
+//
+// A multi-value tenancy, region or compartment expands into one query for each combination of their values,
+// the frames of these queries being returned together. A multi-value dimension is matched with =~ and the
+// values separated by |, a multi-value tag selects any of its values.
+//
+// Parameters:
+//   - qm: The query model, as sent by the client.
+//   - queryType: The type of the query, the placeholders are resolved as the query type expects.
+//
+// Returns:
+//   - []*models.QueryModel: The queries to run.
+//   - error: An error if a placeholder cannot be resolved or a template variable is left in the query.
+func (o *OCIDatasource) normalizeQuery(qm *models.QueryModel, queryType string) ([]*models.QueryModel, error) {
+	if err := normalizeFilters(qm); err != nil {
+		return nil, err
+	}
+
+	queries := []*models.QueryModel{}
+	for _, tenancy := range splitMultiValue(qm.TenancyOCID) {
+		for _, region := range splitMultiValue(qm.Region) {
+			for _, compartment := range splitMultiValue(qm.CompartmentOCID) {
+				expanded := *qm
+				expanded.TenancyOCID = tenancy
+				expanded.Region = region
+				expanded.CompartmentOCID = compartment
+				queries = append(queries, &expanded)
+			}
+		}
+	}
+	if len(queries) > constants.MAX_EXPANDED_QUERIES {
+		return nil, errors.New("the template variables expand into " + strconv.Itoa(len(queries)) +
+			" queries, at most " + strconv.Itoa(constants.MAX_EXPANDED_QUERIES) + " are allowed, select fewer tenancies, regions or compartments")
+	}
+
+	for _, q := range queries {
+		if err := o.resolvePlaceholders(q, queryType); err != nil {
+			return nil, err
+		}
+		if err := checkUnresolvedVariables(q, queryType); err != nil {
+			return nil, err
+		}
+	}
+
+	return queries, nil
+}
+
+// normalizeFilters rewrites the multi-value template variables of the dimensions, tags and MQL of a query.
+func normalizeFilters(qm *models.QueryModel) error {
+	dimensionValues := make([]string, 0, len(qm.DimensionValues))
+	for _, dimensionValue := range qm.DimensionValues {
+		dimension, err := parseDimension(dimensionValue)
+		if err != nil {
+			return err
+		}
+		if values := splitMultiValue(dimension.Value); len(values) > 1 {
+			dimension = multiValueDimension(dimension, values)
+			dimensionValue = dimension.Name + dimension.Operator + mql.QuoteString(dimension.Value)
+		}
+		dimensionValues = append(dimensionValues, dimensionValue)
+	}
+	qm.DimensionValues = dimensionValues
+
+	// the tags of the same key are alternatives, a multi-value tag selects any of its values
+	tagsValues := make([]string, 0, len(qm.TagsValues))
+	for _, tagValue := range qm.TagsValues {
+		key, value, found := strings.Cut(tagValue, "=")
+		if !found {
+			tagsValues = append(tagsValues, tagValue)
+			continue
+		}
+		for _, v := range splitMultiValue(value) {
+			tagsValues = append(tagsValues, key+"="+v)
+		}
+	}
+	qm.TagsValues = tagsValues
+
+	if !qm.RawQuery && multiValueString.MatchString(qm.QueryText) {
+		queryText, err := expandQueryText(qm.QueryText)
+		if err != nil {
+			return err
+		}
+		qm.QueryText = queryText
+	}
+
+	return nil
+}
+
+// multiValueDimension matches a dimension on any of the values of a multi-value template variable: = and =~
+// become =~ and != and !~ become !~, the values being separated by |.
+func multiValueDimension(dimension mql.DimensionFilter, values []string) mql.DimensionFilter {
+	if strings.HasPrefix(dimension.Operator, "!") {
+		dimension.Operator = "!~"
+	} else {
+		dimension.Operator = "=~"
+	}
+	dimension.Value = strings.Join(values, "|")
+
+	return dimension
+}
+
+// expandQueryText rewrites the dimension filters of a MQL query holding a multi-value template variable.
+//
+// Parameters:
+//   - queryText: The MQL query.
+//
+// Returns:
+//   - string: The MQL query, the multi-value dimensions being matched with =~ or !~.
+//   - error: An error if the query cannot be parsed.
+func expandQueryText(queryText string) (string, error) {
+	node, err := mql.Parse(queryText)
+	if err != nil {
+		return "", errors.Wrap(err, "the multi-value template variables of the query cannot be expanded")
+	}
+
+	mql.Walk(node, func(n mql.Node) bool {
+		if q, ok := n.(*mql.MetricQuery); ok {
+			for i, dimension := range q.Dimensions {
+				if values := splitMultiValue(dimension.Value); len(values) > 1 {
+					q.Dimensions[i] = multiValueDimension(dimension, values)
+				}
+			}
+		}
+		return true
+	})
+
+	return node.String(), nil
+}
+
+// resolvePlaceholders replaces the placeholders the query editor shows before a value is selected.
+//
+// Parameters:
+//   - qm: The query model, updated in place.
+//   - queryType: The type of the query.
+//
+// Returns:
+//   - error: An error if a placeholder has no default value.
+func (o *OCIDatasource) resolvePlaceholders(qm *models.QueryModel, queryType string) error {
+	// a single tenancy datasource has only one tenancy to query
+	if qm.TenancyOCID == "" || qm.TenancyOCID == "select tenancy" {
+		if o.settings.TenancyMode == "multitenancy" {
+			return errors.New("no tenancy is selected, select a tenancy")
+		}
+		qm.TenancyOCID = constants.DEFAULT_PROFILE
+	}
+
+	// the region defaults to the region the tenancy is configured with
+	if qm.Region == "" || qm.Region == "select region" {
+		takey := o.GetTenancyAccessKey(qm.TenancyOCID)
+		if takey == "" {
+			return errors.New("Datasource not configured (invalid takey)")
+		}
+		ta, err := o.tenancyAccessFor(takey)
+		if err != nil {
+			return err
+		}
+		region, err := ta.config.Region()
+		if err != nil || region == "" {
+			return errors.New("no region is selected and the tenancy has no default region, select a region")
+		}
+		qm.Region = region
+	}
+
+	// no compartment is the whole tenancy, sub-compartments included
+	if qm.CompartmentOCID == constants.DEFAULT_COMPARTMENT_PLACEHOLDER {
+		qm.CompartmentOCID = ""
+	}
+
+	switch queryType {
+	case constants.QUERYTYPE_ALARM_HISTORY, constants.QUERYTYPE_ALARM_STATUS, constants.QUERYTYPE_AUDIT_EVENTS:
+		return nil
+	}
+
+	if qm.Namespace == "" || qm.Namespace == "select namespace" {
+		return errors.New("no namespace is selected, select a namespace")
+	}
+	if qm.RawQuery && !isBuilderQuery(qm) && strings.TrimSpace(qm.QueryText) == "" {
+		return errors.New("no metric is selected, select a metric")
+	}
+	if !qm.RawQuery && strings.TrimSpace(qm.QueryText) == "" {
+		return errors.New("the query is empty, enter a MQL query")
+	}
+
+	return nil
+}
+
+// checkUnresolvedVariables reports the first template variable left in the fields of a query.
+//
+// Parameters:
+//   - qm: The query model.
+//   - queryType: The type of the query, only the fields it uses are checked.
+//
+// Returns:
+//   - error: An error naming the field and the variable.
+func checkUnresolvedVariables(qm *models.QueryModel, queryType string) error {
+	fields := [][2]string{
+		{"tenancy", qm.TenancyOCID},
+		{"region", qm.Region},
+		{"compartment", qm.CompartmentOCID},
+	}
+	switch queryType {
+	case constants.QUERYTYPE_ALARM_HISTORY, constants.QUERYTYPE_ALARM_STATUS, constants.QUERYTYPE_AUDIT_EVENTS:
+		fields = append(fields, [2]string{"alarm name", qm.AlarmName}, [2]string{"event type", qm.EventType}, [2]string{"principal", qm.Principal})
+	default:
+		fields = append(fields, [2]string{"namespace", qm.Namespace}, [2]string{"resource group", qm.ResourceGroup}, [2]string{"interval", qm.Interval})
+		if isBuilderQuery(qm) {
+			fields = append(fields, [2]string{"metric", qm.Metric})
+		} else {
+			fields = append(fields, [2]string{"query", qm.QueryText})
+		}
+		for _, dimensionValue := range qm.DimensionValues {
+			fields = append(fields, [2]string{"dimension", dimensionValue})
+		}
+		for _, tagValue := range qm.TagsValues {
+			fields = append(fields, [2]string{"tag", tagValue})
+		}
+	}
+
+	for _, field := range fields {
+		if variable := templateVariable.FindString(field[1]); variable != "" {
+			return errors.New("the template variable " + variable + " of the " + field[0] +
+				" is not resolved, template variables are not available to alert rules and reports, select a value instead")
+		}
+	}
+
+	return nil
+}
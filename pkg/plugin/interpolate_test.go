@@ -0,0 +1,75 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+func TestNormalizeFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		qm      models.QueryModel
+		want    models.QueryModel
+		wantErr bool
+	}{
+		{
+			name: "single values unchanged",
+			qm:   models.QueryModel{RawQuery: true, DimensionValues: []string{`shape="VM"`, `ad = "{AD-1}"`}, TagsValues: []string{"env=prod"}},
+			want: models.QueryModel{RawQuery: true, DimensionValues: []string{`shape="VM"`, `ad = "{AD-1}"`}, TagsValues: []string{"env=prod"}},
+		},
+		{
+			name: "multi-value dimensions",
+			qm:   models.QueryModel{RawQuery: true, DimensionValues: []string{`shape="{VM,BM}"`, `ad!={AD-1, AD-2}`, `name=~"{a\b,c}"`}},
+			want: models.QueryModel{RawQuery: true, DimensionValues: []string{`shape=~"VM|BM"`, `ad!~"AD-1|AD-2"`, `name=~"a\\b|c"`}, TagsValues: []string{}},
+		},
+		{
+			name: "multi-value tags",
+			qm:   models.QueryModel{RawQuery: true, TagsValues: []string{"env={prod,dev}", "team=ops", "invalid"}},
+			want: models.QueryModel{RawQuery: true, DimensionValues: []string{}, TagsValues: []string{"env=prod", "env=dev", "team=ops", "invalid"}},
+		},
+		{
+			name: "multi-value MQL dimension",
+			qm:   models.QueryModel{QueryText: `CpuUtilization[1m]{shape = "{VM,BM}", ad != "{AD-1,AD-2}"}.mean() > 80`},
+			want: models.QueryModel{
+				QueryText:       `CpuUtilization[1m]{shape =~ "VM|BM", ad !~ "AD-1|AD-2"}.mean() > 80`,
+				DimensionValues: []string{},
+				TagsValues:      []string{},
+			},
+		},
+		{
+			name: "MQL of builder queries unchanged",
+			qm:   models.QueryModel{RawQuery: true, QueryText: `CpuUtilization[1m]{shape = "{VM,BM}"}.mean()`},
+			want: models.QueryModel{RawQuery: true, QueryText: `CpuUtilization[1m]{shape = "{VM,BM}"}.mean()`, DimensionValues: []string{}, TagsValues: []string{}},
+		},
+		{
+			name:    "invalid dimension",
+			qm:      models.QueryModel{RawQuery: true, DimensionValues: []string{"shape"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid MQL",
+			qm:      models.QueryModel{QueryText: `CpuUtilization[1m]{shape = "{VM,BM}"}.mean(`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qm := tt.qm
+			err := normalizeFilters(&qm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(qm, tt.want) {
+				t.Errorf("normalizeFilters() = %+v, want %+v", qm, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+// metricCacheBucket returns the duration of the cache buckets of an interval, constants.METRIC_CACHE_BUCKET_POINTS
+// datapoints per series. The second value is false when the interval is not valid and the data is not cached.
+func metricCacheBucket(interval string) (time.Duration, bool) {
+	duration, ok := parseInterval(interval)
+	if !ok {
+		return 0, false
+	}
+
+	return duration * constants.METRIC_CACHE_BUCKET_POINTS, true
+}
+
+// metricRequestKey returns the key of a SummarizeMetricsData request: the tenancy, region, compartment, namespace,
+// resource group, query and interval of the request.
+func metricRequestKey(takey string, region string, req monitoring.SummarizeMetricsDataRequest, interval string) string {
+	resourceGroup := ""
+	if req.SummarizeMetricsDataDetails.ResourceGroup != nil {
+		resourceGroup = *req.SummarizeMetricsDataDetails.ResourceGroup
+	}
+	inSubtree := req.CompartmentIdInSubtree != nil && *req.CompartmentIdInSubtree
+
+	return strings.Join([]string{
+		constants.CACHE_KEY_METRIC_DATA,
+		takey,
+		region,
+		stringValue(req.CompartmentId),
+		strconv.FormatBool(inSubtree),
+		stringValue(req.SummarizeMetricsDataDetails.Namespace),
+		resourceGroup,
+		stringValue(req.SummarizeMetricsDataDetails.Query),
+		interval,
+	}, "/")
+}
+
+// metricCacheKey returns the cache key of the datapoints of a bucket, the key of the request followed by the
+// start of the bucket.
+func metricCacheKey(takey string, region string, req monitoring.SummarizeMetricsDataRequest, interval string, bucketStart time.Time) string {
+	return metricRequestKey(takey, region, req, interval) + "/" + strconv.FormatInt(bucketStart.Unix(), 10)
+}
+
+// metricWindowKey returns the key of the request of a window of the time range, the key of the request followed
+// by the start and the end of the window.
+func metricWindowKey(takey string, region string, req monitoring.SummarizeMetricsDataRequest, interval string, window timeWindow) string {
+	return metricRequestKey(takey, region, req, interval) + "/" + strconv.FormatInt(window.start.Unix(), 10) + "-" + strconv.FormatInt(window.end.Unix(), 10)
+}
+
+// isCompleteBucket tells whether a bucket is within the time range of a request and old enough for its datapoints
+// not to change anymore, OCI Monitoring accepting datapoints up to constants.METRIC_CACHE_SETTLE_DELAY late.
+func isCompleteBucket(bucketStart time.Time, bucket time.Duration, window timeWindow, now time.Time) bool {
+	bucketEnd := bucketStart.Add(bucket)
+	return !bucketStart.Before(window.start) && !bucketEnd.After(window.end) &&
+		!bucketEnd.After(now.Add(-constants.METRIC_CACHE_SETTLE_DELAY))
+}
+
+// cachedMetricData splits the time range of a request between the buckets found in the cache and the time
+// ranges left to fetch from OCI Monitoring. The buckets are aligned on their duration, so that the requests of
+// different users and refreshes share them, and only the complete buckets are cached: the recent tail of the
+// time range and the partial buckets at its edges are always fetched.
+//
+// Parameters:
+//   - takey: The tenancy access key of the request.
+//   - region: The region of the request.
+//   - req: The SummarizeMetricsData request.
+//   - interval: The interval of the query.
+//
+// Returns:
+//   - [][]monitoring.MetricData: The series of each cached bucket.
+//   - []timeWindow: The time ranges to fetch, in chronological order.
+func (o *OCIDatasource) cachedMetricData(takey string, region string, req monitoring.SummarizeMetricsDataRequest, interval string) ([][]monitoring.MetricData, []timeWindow) {
+	window := timeWindow{start: req.StartTime.Time, end: req.EndTime.Time}
+	bucket, ok := metricCacheBucket(interval)
+	if !ok {
+		return nil, []timeWindow{window}
+	}
+
+	now := time.Now()
+	cached := [][]monitoring.MetricData{}
+	toFetch := []timeWindow{}
+	for bucketStart := window.start.Truncate(bucket); bucketStart.Before(window.end); bucketStart = bucketStart.Add(bucket) {
+		if isCompleteBucket(bucketStart, bucket, window, now) {
+			if items, found := o.cache.Get(metricCacheKey(takey, region, req, interval, bucketStart)); found {
+				if data, ok := items.([]monitoring.MetricData); ok {
+					cached = append(cached, data)
+					continue
+				}
+			}
+		}
+
+		// extending the last time range to fetch when it ends where the bucket starts
+		fetchStart, fetchEnd := maxTime(bucketStart, window.start), minTime(bucketStart.Add(bucket), window.end)
+		if last := len(toFetch) - 1; last >= 0 && toFetch[last].end.Equal(fetchStart) {
+			toFetch[last].end = fetchEnd
+		} else {
+			toFetch = append(toFetch, timeWindow{start: fetchStart, end: fetchEnd})
+		}
+	}
+
+	return cached, toFetch
+}
+
+// storeMetricData caches the datapoints of the complete buckets of the time ranges fetched for a request.
+//
+// Parameters:
+//   - takey: The tenancy access key of the request.
+//   - region: The region of the request.
+//   - req: The SummarizeMetricsData request.
+//   - interval: The interval of the query.
+//   - fetched: The time ranges fetched.
+//   - items: The series fetched for these time ranges.
+func (o *OCIDatasource) storeMetricData(takey string, region string, req monitoring.SummarizeMetricsDataRequest, interval string, fetched []timeWindow, items []monitoring.MetricData) {
+	bucket, ok := metricCacheBucket(interval)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	for _, window := range fetched {
+		for bucketStart := window.start.Truncate(bucket); bucketStart.Before(window.end); bucketStart = bucketStart.Add(bucket) {
+			if !isCompleteBucket(bucketStart, bucket, window, now) {
+				continue
+			}
+
+			bucketItems, cost := metricDataBetween(items, bucketStart, bucketStart.Add(bucket))
+			o.cache.SetWithTTL(metricCacheKey(takey, region, req, interval, bucketStart), bucketItems, cost, constants.METRIC_CACHE_TTL)
+		}
+	}
+}
+
+// metricDataBetween returns the series holding the datapoints of a time range, start included and end excluded,
+// together with their cache cost, an estimate of their size in bytes. The series without datapoint in the time
+// range are left out.
+func metricDataBetween(items []monitoring.MetricData, start time.Time, end time.Time) ([]monitoring.MetricData, int64) {
+	between := []monitoring.MetricData{}
+	cost := int64(1)
+	for _, item := range items {
+		datapoints := []monitoring.AggregatedDatapoint{}
+		for _, datapoint := range item.AggregatedDatapoints {
+			if datapoint.Timestamp != nil && !datapoint.Timestamp.Time.Before(start) && datapoint.Timestamp.Time.Before(end) {
+				datapoints = append(datapoints, datapoint)
+			}
+		}
+		if len(datapoints) == 0 {
+			continue
+		}
+
+		item.AggregatedDatapoints = datapoints
+		between = append(between, item)
+		cost += constants.METRIC_CACHE_SERIES_COST + int64(len(datapoints))*constants.METRIC_CACHE_DATAPOINT_COST
+	}
+
+	return between, cost
+}
+
+// minTime returns the earliest of two times.
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// maxTime returns the latest of two times.
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
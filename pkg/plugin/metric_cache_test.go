@@ -0,0 +1,245 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+func TestIsCompleteBucket(t *testing.T) {
+	window := timeWindow{start: mustTime(t, "2024-01-01T00:00:00Z"), end: mustTime(t, "2024-01-01T12:00:00Z")}
+	now := mustTime(t, "2024-01-01T12:00:00Z")
+
+	tests := []struct {
+		name        string
+		bucketStart string
+		now         time.Time
+		want        bool
+	}{
+		{name: "within the window", bucketStart: "2024-01-01T03:00:00Z", now: now, want: true},
+		{name: "at the start of the window", bucketStart: "2024-01-01T00:00:00Z", now: now, want: true},
+		{name: "before the start of the window", bucketStart: "2023-12-31T23:30:00Z", now: now, want: false},
+		{name: "past the end of the window", bucketStart: "2024-01-01T11:30:00Z", now: now.Add(time.Hour), want: false},
+		{name: "at the end of the window", bucketStart: "2024-01-01T11:00:00Z", now: now.Add(time.Hour), want: true},
+		{name: "not settled", bucketStart: "2024-01-01T11:00:00Z", now: now.Add(constants.METRIC_CACHE_SETTLE_DELAY - time.Second), want: false},
+		{name: "just settled", bucketStart: "2024-01-01T11:00:00Z", now: now.Add(constants.METRIC_CACHE_SETTLE_DELAY), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCompleteBucket(mustTime(t, tt.bucketStart), time.Hour, window, tt.now); got != tt.want {
+				t.Errorf("isCompleteBucket() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricDataBetween(t *testing.T) {
+	t0 := mustTime(t, "2024-01-01T00:00:00Z")
+	datapoint := func(minutes int) monitoring.AggregatedDatapoint {
+		return monitoring.AggregatedDatapoint{
+			Timestamp: &common.SDKTime{Time: t0.Add(time.Duration(minutes) * time.Minute)},
+			Value:     common.Float64(float64(minutes)),
+		}
+	}
+	series := func(name string, datapoints ...monitoring.AggregatedDatapoint) monitoring.MetricData {
+		return monitoring.MetricData{Name: common.String(name), AggregatedDatapoints: datapoints}
+	}
+	items := []monitoring.MetricData{
+		series("a", datapoint(0), datapoint(1), datapoint(2), datapoint(3)),
+		series("b", datapoint(5)),
+		series("c", monitoring.AggregatedDatapoint{Value: common.Float64(1)}, datapoint(2)),
+	}
+
+	tests := []struct {
+		name     string
+		start    int
+		end      int
+		want     []monitoring.MetricData
+		wantCost int64
+	}{
+		{
+			name:     "start included and end excluded",
+			start:    1,
+			end:      3,
+			want:     []monitoring.MetricData{series("a", datapoint(1), datapoint(2)), series("c", datapoint(2))},
+			wantCost: 1 + 2*constants.METRIC_CACHE_SERIES_COST + 3*constants.METRIC_CACHE_DATAPOINT_COST,
+		},
+		{
+			name:     "series without datapoint left out",
+			start:    4,
+			end:      10,
+			want:     []monitoring.MetricData{series("b", datapoint(5))},
+			wantCost: 1 + constants.METRIC_CACHE_SERIES_COST + constants.METRIC_CACHE_DATAPOINT_COST,
+		},
+		{
+			name:     "no datapoint",
+			start:    10,
+			end:      20,
+			want:     []monitoring.MetricData{},
+			wantCost: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := t0.Add(time.Duration(tt.start) * time.Minute)
+			end := t0.Add(time.Duration(tt.end) * time.Minute)
+			got, cost := metricDataBetween(items, start, end)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metricDataBetween() = %v, want %v", got, tt.want)
+			}
+			if cost != tt.wantCost {
+				t.Errorf("metricDataBetween() cost = %v, want %v", cost, tt.wantCost)
+			}
+		})
+	}
+
+	// the series passed in are left unchanged
+	if len(items[0].AggregatedDatapoints) != 4 {
+		t.Errorf("metricDataBetween() changed the datapoints of the series passed in")
+	}
+}
+
+// cacheRequest returns a SummarizeMetricsData request of the CpuUtilization metric over the given time range.
+func cacheRequest(start time.Time, end time.Time) monitoring.SummarizeMetricsDataRequest {
+	return monitoring.SummarizeMetricsDataRequest{
+		CompartmentId: common.String("ocid1.compartment.oc1..a"),
+		SummarizeMetricsDataDetails: monitoring.SummarizeMetricsDataDetails{
+			Namespace: common.String("oci_computeagent"),
+			Query:     common.String("CpuUtilization[1m].mean()"),
+			StartTime: &common.SDKTime{Time: start},
+			EndTime:   &common.SDKTime{Time: end},
+		},
+	}
+}
+
+// minuteSeries returns a series with a datapoint per minute over the given time range.
+func minuteSeries(start time.Time, end time.Time) monitoring.MetricData {
+	item := monitoring.MetricData{Name: common.String("CpuUtilization"), Dimensions: map[string]string{"resourceId": "a"}}
+	for at := start; at.Before(end); at = at.Add(time.Minute) {
+		item.AggregatedDatapoints = append(item.AggregatedDatapoints, monitoring.AggregatedDatapoint{
+			Timestamp: &common.SDKTime{Time: at},
+			Value:     common.Float64(float64(at.Unix())),
+		})
+	}
+	return item
+}
+
+func TestCachedMetricData(t *testing.T) {
+	t0 := mustTime(t, "2024-01-01T00:00:00Z")
+	hour := func(hours float64) time.Time {
+		return t0.Add(time.Duration(hours * float64(time.Hour)))
+	}
+	o := newTestDatasource(t, "singletenancy")
+
+	// the buckets of the first three hours are cached
+	stored := timeWindow{start: hour(0), end: hour(3)}
+	o.storeMetricData(SingleTenancyKey, "us-ashburn-1", cacheRequest(stored.start, stored.end), "1m", []timeWindow{stored},
+		[]monitoring.MetricData{minuteSeries(stored.start, stored.end)})
+	o.cache.Wait()
+
+	tests := []struct {
+		name        string
+		start       time.Time
+		end         time.Time
+		interval    string
+		region      string
+		wantCached  []monitoring.MetricData
+		wantToFetch []timeWindow
+	}{
+		{
+			name:       "every bucket cached",
+			start:      hour(0),
+			end:        hour(3),
+			interval:   "1m",
+			region:     "us-ashburn-1",
+			wantCached: []monitoring.MetricData{minuteSeries(hour(0), hour(1)), minuteSeries(hour(1), hour(2)), minuteSeries(hour(2), hour(3))},
+		},
+		{
+			name:        "partial buckets at the edges fetched",
+			start:       hour(0.5),
+			end:         hour(2.5),
+			interval:    "1m",
+			region:      "us-ashburn-1",
+			wantCached:  []monitoring.MetricData{minuteSeries(hour(1), hour(2))},
+			wantToFetch: []timeWindow{{start: hour(0.5), end: hour(1)}, {start: hour(2), end: hour(2.5)}},
+		},
+		{
+			name:        "buckets not cached fetched together",
+			start:       hour(2),
+			end:         hour(5),
+			interval:    "1m",
+			region:      "us-ashburn-1",
+			wantCached:  []monitoring.MetricData{minuteSeries(hour(2), hour(3))},
+			wantToFetch: []timeWindow{{start: hour(3), end: hour(5)}},
+		},
+		{
+			name:        "other region",
+			start:       hour(0),
+			end:         hour(1),
+			interval:    "1m",
+			region:      "eu-frankfurt-1",
+			wantToFetch: []timeWindow{{start: hour(0), end: hour(1)}},
+		},
+		{
+			name:        "interval not valid",
+			start:       hour(0),
+			end:         hour(1),
+			interval:    "auto",
+			region:      "us-ashburn-1",
+			wantToFetch: []timeWindow{{start: hour(0), end: hour(1)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached, toFetch := o.cachedMetricData(SingleTenancyKey, tt.region, cacheRequest(tt.start, tt.end), tt.interval)
+
+			var got []monitoring.MetricData
+			for _, items := range cached {
+				got = append(got, items...)
+			}
+			if !reflect.DeepEqual(got, tt.wantCached) {
+				t.Errorf("cachedMetricData() cached %d series, want %d", len(got), len(tt.wantCached))
+			}
+			if len(toFetch) != len(tt.wantToFetch) || (len(toFetch) > 0 && !reflect.DeepEqual(toFetch, tt.wantToFetch)) {
+				t.Errorf("cachedMetricData() to fetch = %v, want %v", toFetch, tt.wantToFetch)
+			}
+		})
+	}
+}
+
+func TestStoreMetricDataNotSettled(t *testing.T) {
+	o := newTestDatasource(t, "singletenancy")
+	// the last bucket is the current hour
+	end := time.Now().Truncate(time.Hour).Add(time.Hour)
+	start := end.Add(-3 * time.Hour)
+	req := cacheRequest(start, end)
+
+	o.storeMetricData(SingleTenancyKey, "us-ashburn-1", req, "1m", []timeWindow{{start: start, end: end}},
+		[]monitoring.MetricData{minuteSeries(start, end)})
+	o.cache.Wait()
+
+	// the last bucket does not end more than the settle delay ago, its datapoints may still change
+	cached, toFetch := o.cachedMetricData(SingleTenancyKey, "us-ashburn-1", req, "1m")
+	if len(cached) != 2 {
+		t.Errorf("cachedMetricData() cached %d buckets, want the 2 settled buckets", len(cached))
+	}
+	if want := []timeWindow{{start: end.Add(-time.Hour), end: end}}; !reflect.DeepEqual(toFetch, want) {
+		t.Errorf("cachedMetricData() to fetch = %v, want the bucket not settled %v", toFetch, want)
+	}
+	if _, found := o.cache.Get(metricCacheKey(SingleTenancyKey, "us-ashburn-1", req, "1m", end.Add(-time.Hour))); found {
+		t.Error("the bucket not settled is cached")
+	}
+}
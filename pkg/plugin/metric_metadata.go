@@ -0,0 +1,225 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/mql"
+)
+
+// metricMetadata is the display metadata of a metric.
+type metricMetadata struct {
+	// unit is the Grafana unit of the metric, such as percent, bytes or ms.
+	unit        string
+	displayName string
+	description string
+}
+
+// knownMetricMetadata is the metadata of the service metrics of the well-known namespaces, keyed by namespace and
+// metric. It is used when OCI Monitoring returns no metadata with the metric data: ListMetrics returns the names and
+// dimensions of the metrics only, without unit or description.
+var knownMetricMetadata = map[string]map[string]metricMetadata{
+	"oci_computeagent": {
+		"CpuUtilization":         {"percent", "CPU Utilization", "Activity level from CPU."},
+		"MemoryUtilization":      {"percent", "Memory Utilization", "Space currently in use."},
+		"DiskBytesRead":          {"bytes", "Disk Read Bytes", "Read throughput, bytes read per interval."},
+		"DiskBytesWritten":       {"bytes", "Disk Write Bytes", "Write throughput, bytes written per interval."},
+		"DiskIopsRead":           {"short", "Disk Read I/O", "Activity level from I/O reads, reads per interval."},
+		"DiskIopsWritten":        {"short", "Disk Write I/O", "Activity level from I/O writes, writes per interval."},
+		"NetworksBytesIn":        {"bytes", "Network Receive Bytes", "Network receipt throughput, bytes received per interval."},
+		"NetworksBytesOut":       {"bytes", "Network Transmit Bytes", "Network transmission throughput, bytes sent per interval."},
+		"LoadAverage":            {"short", "Load Average", "Average system load over 1 minute."},
+		"MemoryAllocationStalls": {"short", "Memory Allocation Stalls", "Number of times page reclaim was called directly."},
+	},
+	"oci_lbaas": {
+		"AcceptedConnections":     {"short", "Accepted Connections", "Number of connections accepted by the load balancer."},
+		"ActiveConnections":       {"short", "Active Connections", "Number of active connections between the clients and the load balancer."},
+		"BackendTimeouts":         {"short", "Backend Timeouts", "Number of timeouts across all backend servers."},
+		"BytesReceived":           {"bytes", "Bytes Received", "Number of bytes received by the load balancer."},
+		"BytesSent":               {"bytes", "Bytes Sent", "Number of bytes sent by the load balancer."},
+		"HttpRequests":            {"short", "Inbound Requests", "Number of incoming client requests to the load balancer."},
+		"HttpResponses":           {"short", "HTTP Responses", "Number of HTTP responses across all backend sets."},
+		"PeakBandwidth":           {"Mbits", "Peak Bandwidth", "Peak bandwidth utilization of the load balancer during the interval, in megabits per second."},
+		"ResponseTimeFirstByte":   {"ms", "Response Time First Byte", "Time to receive the first byte of the response from the backend servers."},
+		"ResponseTimeHttpHeader":  {"ms", "Response Time HTTP Header", "Time to receive the HTTP headers of the response from the backend servers."},
+		"UnHealthyBackendServers": {"short", "Unhealthy Backend Servers", "Number of unhealthy backend servers in a backend set."},
+	},
+	"oci_autonomous_database": {
+		"CpuUtilization":     {"percent", "CPU Utilization", "CPU utilization expressed as a percentage, aggregated across all consumer groups."},
+		"CurrentLogons":      {"short", "Current Logons", "Number of successful logons during the interval."},
+		"ExecuteCount":       {"short", "Execute Count", "Number of user and recursive calls that executed SQL statements during the interval."},
+		"QueryLatency":       {"ms", "Query Latency", "Average time the processing of user queries takes."},
+		"Sessions":           {"short", "Sessions", "Number of sessions in the database."},
+		"StorageUsed":        {"decgbytes", "Storage Space Used", "Amount of storage space used by the database, in gigabytes."},
+		"StorageUtilization": {"percent", "Storage Utilization", "Percentage of the provisioned storage capacity currently in use."},
+		"TransactionCount":   {"short", "Transaction Count", "Combined number of user commits and user rollbacks during the interval."},
+	},
+	"oci_vcn": {
+		"VnicFromNetworkBytes":         {"bytes", "Bytes from Network", "Number of bytes received from the network by the VNIC."},
+		"VnicToNetworkBytes":           {"bytes", "Bytes to Network", "Number of bytes sent from the VNIC to the network."},
+		"VnicFromNetworkPackets":       {"short", "Packets from Network", "Number of packets received from the network by the VNIC."},
+		"VnicToNetworkPackets":         {"short", "Packets to Network", "Number of packets sent from the VNIC to the network."},
+		"VnicIngressDropsSecurityList": {"short", "Ingress Packets Dropped by Security List", "Number of ingress packets dropped by the security lists."},
+		"VnicEgressDropsSecurityList":  {"short", "Egress Packets Dropped by Security List", "Number of egress packets dropped by the security lists."},
+	},
+	"oci_blockstore": {
+		"VolumeReadThroughput":  {"bytes", "Volume Read Throughput", "Read throughput, bytes read per interval."},
+		"VolumeWriteThroughput": {"bytes", "Volume Write Throughput", "Write throughput, bytes written per interval."},
+		"VolumeReadOps":         {"short", "Volume Read Operations", "Activity level from I/O reads, reads per interval."},
+		"VolumeWriteOps":        {"short", "Volume Write Operations", "Activity level from I/O writes, writes per interval."},
+		"VolumeThrottledIOs":    {"short", "Volume Throttled I/Os", "Number of I/O operations throttled on the volume."},
+	},
+	"oci_objectstorage": {
+		"AllRequests":         {"short", "All Requests", "Number of HTTP requests made to the bucket."},
+		"FirstByteLatency":    {"ms", "First Byte Latency", "Time from the receipt of the request to the first byte of the response."},
+		"ObjectCount":         {"short", "Object Count", "Number of objects in the bucket."},
+		"StoredBytes":         {"bytes", "Bucket Size", "Size of the bucket, in bytes."},
+		"TotalRequestLatency": {"ms", "Total Request Latency", "Time to process the request and to send the response."},
+	},
+}
+
+// grafanaUnits maps the units published with the OCI metrics to the units of Grafana.
+var grafanaUnits = map[string]string{
+	"percent":               "percent",
+	"percentage":            "percent",
+	"bytes":                 "bytes",
+	"kilobytes":             "kbytes",
+	"megabytes":             "mbytes",
+	"gigabytes":             "gbytes",
+	"terabytes":             "tbytes",
+	"bytes per second":      "Bps",
+	"bytes/second":          "Bps",
+	"bits per second":       "bps",
+	"megabits":              "Mbits",
+	"microseconds":          "µs",
+	"milliseconds":          "ms",
+	"ms":                    "ms",
+	"seconds":               "s",
+	"minutes":               "m",
+	"hours":                 "h",
+	"count":                 "short",
+	"requests":              "short",
+	"operations":            "short",
+	"packets":               "short",
+	"connections":           "short",
+	"requests per second":   "reqps",
+	"operations per second": "ops",
+}
+
+// unitStatistics are the statistics keeping the unit of the metric, the others such as count() and rate() change it.
+var unitStatistics = map[string]bool{
+	"avg": true, "mean": true, "max": true, "min": true, "first": true, "last": true,
+	"percentile": true, "sum": true, "increment": true,
+}
+
+// grafanaUnit returns the Grafana unit of a unit published with an OCI metric, or an empty string when it is not known.
+func grafanaUnit(ociUnit string) string {
+	return grafanaUnits[strings.ToLower(strings.TrimSpace(ociUnit))]
+}
+
+// getMetricMetadata returns the display metadata of a metric. The metadata published with the metric data by
+// OCI Monitoring is preferred, it is cached so that it also applies when a later response does not carry it,
+// the metadata of the well-known namespaces being used otherwise.
+//
+// Parameters:
+//   - namespace: The namespace of the metric.
+//   - metric: The name of the metric.
+//   - published: The metadata returned with the metric data, unit and displayName.
+//
+// Returns:
+//   - metricMetadata: The metadata of the metric, empty when it is not known.
+func (o *OCIDatasource) getMetricMetadata(namespace string, metric string, published map[string]string) metricMetadata {
+	metadata := knownMetricMetadata[namespace][metric]
+
+	cacheKey := strings.Join([]string{constants.CACHE_KEY_METRIC_METADATA, namespace, metric}, "/")
+	if unit, displayName := grafanaUnit(published["unit"]), published["displayName"]; unit != "" || displayName != "" {
+		learned := metricMetadata{unit: unit, displayName: displayName, description: metadata.description}
+		o.cache.SetWithTTL(cacheKey, learned, 1, constants.METRIC_METADATA_CACHE_TTL)
+		return mergeMetricMetadata(learned, metadata)
+	}
+
+	if cached, found := o.cache.Get(cacheKey); found {
+		if learned, ok := cached.(metricMetadata); ok {
+			return mergeMetricMetadata(learned, metadata)
+		}
+	}
+
+	return metadata
+}
+
+// mergeMetricMetadata completes the metadata with the fields of the fallback it does not have.
+func mergeMetricMetadata(metadata metricMetadata, fallback metricMetadata) metricMetadata {
+	if metadata.unit == "" {
+		metadata.unit = fallback.unit
+	}
+	if metadata.displayName == "" {
+		metadata.displayName = fallback.displayName
+	}
+	if metadata.description == "" {
+		metadata.description = fallback.description
+	}
+
+	return metadata
+}
+
+// keepsMetricUnit tells whether the values of a MQL query are in the unit of its metric: the query selects a single
+// metric, without arithmetic or condition, aggregated with a statistic that keeps the unit.
+func keepsMetricUnit(queryText string) bool {
+	node, err := mql.Parse(queryText)
+	if err != nil {
+		return false
+	}
+	for {
+		paren, ok := node.(*mql.ParenExpr)
+		if !ok {
+			break
+		}
+		node = paren.Expr
+	}
+
+	query, ok := node.(*mql.MetricQuery)
+	if !ok {
+		return false
+	}
+	for _, f := range query.Functions {
+		if unitStatistics[f.Name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metricFieldConfig returns the configuration of the value field of a series: its display name, unit and description.
+//
+// Parameters:
+//   - name: The display name of the series, the display name of the metric is used when it is empty.
+//   - metadata: The metadata of the metric.
+//   - withUnit: Whether the values are in the unit of the metric.
+//
+// Returns:
+//   - *data.FieldConfig: The field configuration, nil when there is nothing to configure.
+func metricFieldConfig(name string, metadata metricMetadata, withUnit bool) *data.FieldConfig {
+	config := &data.FieldConfig{
+		DisplayNameFromDS: name,
+		Description:       metadata.description,
+	}
+	if config.DisplayNameFromDS == "" {
+		config.DisplayNameFromDS = metadata.displayName
+	}
+	if withUnit {
+		config.Unit = metadata.unit
+	}
+
+	if config.DisplayNameFromDS == "" && config.Description == "" && config.Unit == "" {
+		return nil
+	}
+	return config
+}
@@ -0,0 +1,164 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+func TestGrafanaUnit(t *testing.T) {
+	tests := []struct {
+		ociUnit string
+		want    string
+	}{
+		{ociUnit: "Percent", want: "percent"},
+		{ociUnit: " bytes ", want: "bytes"},
+		{ociUnit: "Bytes per second", want: "Bps"},
+		{ociUnit: "milliseconds", want: "ms"},
+		{ociUnit: "count", want: "short"},
+		{ociUnit: "furlongs", want: ""},
+		{ociUnit: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ociUnit, func(t *testing.T) {
+			if got := grafanaUnit(tt.ociUnit); got != tt.want {
+				t.Errorf("grafanaUnit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMetricMetadata(t *testing.T) {
+	o := newTestDatasource(t, "singletenancy")
+	cpu := knownMetricMetadata["oci_computeagent"]["CpuUtilization"]
+
+	tests := []struct {
+		name      string
+		namespace string
+		metric    string
+		published map[string]string
+		want      metricMetadata
+	}{
+		{name: "well-known metric", namespace: "oci_computeagent", metric: "CpuUtilization", want: cpu},
+		{name: "unknown metric", namespace: "custom", metric: "QueueDepth", want: metricMetadata{}},
+		{
+			name: "published metadata preferred", namespace: "oci_computeagent", metric: "MemoryUtilization",
+			published: map[string]string{"unit": "bytes", "displayName": "Memory Used"},
+			want:      metricMetadata{unit: "bytes", displayName: "Memory Used", description: "Space currently in use."},
+		},
+		{
+			// the metadata published with a previous response is remembered
+			name: "published metadata cached", namespace: "oci_computeagent", metric: "MemoryUtilization",
+			want: metricMetadata{unit: "bytes", displayName: "Memory Used", description: "Space currently in use."},
+		},
+		{
+			name: "published unit of a custom metric", namespace: "custom", metric: "Latency",
+			published: map[string]string{"unit": "Milliseconds"},
+			want:      metricMetadata{unit: "ms"},
+		},
+		{
+			name: "unknown published unit", namespace: "oci_computeagent", metric: "LoadAverage",
+			published: map[string]string{"unit": "furlongs"},
+			want:      knownMetricMetadata["oci_computeagent"]["LoadAverage"],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := o.getMetricMetadata(tt.namespace, tt.metric, tt.published)
+			o.cache.Wait()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMetricMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepsMetricUnit(t *testing.T) {
+	tests := []struct {
+		queryText string
+		want      bool
+	}{
+		{queryText: "CpuUtilization[1m].mean()", want: true},
+		{queryText: `CpuUtilization[5m]{resourceId = "a"}.max()`, want: true},
+		{queryText: "CpuUtilization[1m].grouping().percentile(0.9)", want: true},
+		{queryText: "(CpuUtilization[1m].mean())", want: true},
+		{queryText: "CpuUtilization[1m].count()", want: false},
+		{queryText: "CpuUtilization[1m].rate()", want: false},
+		{queryText: "CpuUtilization[1m].mean() > 80", want: false},
+		{queryText: "CpuUtilization[1m].mean() / MemoryUtilization[1m].mean()", want: false},
+		{queryText: "not a query(", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.queryText, func(t *testing.T) {
+			if got := keepsMetricUnit(tt.queryText); got != tt.want {
+				t.Errorf("keepsMetricUnit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricFieldConfig(t *testing.T) {
+	cpu := metricMetadata{unit: "percent", displayName: "CPU Utilization", description: "Activity level from CPU."}
+
+	tests := []struct {
+		name     string
+		series   string
+		metadata metricMetadata
+		withUnit bool
+		want     *data.FieldConfig
+	}{
+		{
+			name: "series name and unit", series: "instance-a", metadata: cpu, withUnit: true,
+			want: &data.FieldConfig{DisplayNameFromDS: "instance-a", Unit: "percent", Description: "Activity level from CPU."},
+		},
+		{
+			name: "metric display name", metadata: cpu, withUnit: true,
+			want: &data.FieldConfig{DisplayNameFromDS: "CPU Utilization", Unit: "percent", Description: "Activity level from CPU."},
+		},
+		{
+			name: "statistic changing the unit", series: "instance-a", metadata: cpu,
+			want: &data.FieldConfig{DisplayNameFromDS: "instance-a", Description: "Activity level from CPU."},
+		},
+		{name: "series name only", series: "instance-a", want: &data.FieldConfig{DisplayNameFromDS: "instance-a"}},
+		{name: "nothing to configure", withUnit: true, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metricFieldConfig(tt.series, tt.metadata, tt.withUnit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metricFieldConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMetricDataPointsMetadata(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	o, servers := newRegionsDatasource(t, []string{"us-ashburn-1"})
+	items := metricDataResponse(t0, map[string][]float64{"a": {1, 2}})
+	items[0]["metadata"] = map[string]string{"unit": "percent", "displayName": "CPU Utilization"}
+	servers[0].handleJSON("POST "+summarizeMetricsDataPath, http.StatusOK, items)
+
+	_, dataPoints, _, err := o.GetMetricDataPoints(context.Background(), metricsDataRequest(t0, "us-ashburn-1"), constants.DEFAULT_PROFILE)
+	if err != nil {
+		t.Fatalf("GetMetricDataPoints() error = %v", err)
+	}
+	want := map[string]string{"unit": "percent", "displayName": "CPU Utilization"}
+	if len(dataPoints) != 1 || !reflect.DeepEqual(dataPoints[0].Metadata, want) {
+		t.Errorf("GetMetricDataPoints() = %+v, want the metadata returned with the metric data", dataPoints)
+	}
+}
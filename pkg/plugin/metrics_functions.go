@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -29,8 +30,8 @@ type metricDataBank struct {
 // and compartment levels.
 //
 // The function iterates through each configured tenancy access key. For each key, it performs the following steps:
-// 1. Fetches the tenancy OCID using the `FetchTenancyOCID` method.
-// 2. Retrieves the configured region using `o.tenancyAccess[key].config.Region()`.
+// 1. Fetches the tenancy OCID using the `FetchTenancyOCID` method, and checks the principal token for principal environments.
+// 2. Retrieves the configured region using `ta.config.Region()`.
 // 3. Attempts to list metrics at the tenancy level.
 // 4. If listing metrics at the tenancy level fails, it attempts to list metrics at each compartment level.
 // 5. The function checks for various error conditions and returns appropriate error messages.
@@ -48,12 +49,13 @@ func (o *OCIDatasource) TestConnectivity(ctx context.Context) error {
 	var testResult bool
 
 	// Check if the tenancy access configurations are empty
-	if len(o.tenancyAccess) == 0 {
+	tenancyAccess := o.tenancyAccessSnapshot()
+	if len(tenancyAccess) == 0 {
 		return fmt.Errorf("TestConnectivity failed: cannot read o.tenancyAccess")
 	}
 
 	// Iterate over the tenancy access configurations
-	for key := range o.tenancyAccess {
+	for key, ta := range tenancyAccess {
 		testResult = false
 
 		// Fetch the tenancy OCID
@@ -62,8 +64,22 @@ func (o *OCIDatasource) TestConnectivity(ctx context.Context) error {
 			return errors.Wrap(tenancyErr, "error fetching TenancyOCID")
 		}
 
+		// Report expired security tokens instead of an opaque 401
+		if stErr := o.checkSessionToken(key); stErr != nil {
+			backend.Logger.Error("TestConnectivity", "Config Key", key, "error", stErr)
+			return fmt.Errorf("TestConnectivity failed: %v", stErr)
+		}
+
+		// Check that the principal token can be obtained before calling the services
+		if isPrincipalEnvironment(o.settings.Environment) {
+			if _, keyErr := ta.config.KeyID(); keyErr != nil {
+				backend.Logger.Error("TestConnectivity", "Config Key", key, "error", keyErr)
+				return fmt.Errorf("TestConnectivity failed: cannot obtain %v token: %v", o.settings.Environment, keyErr)
+			}
+		}
+
 		// Get the region from the tenancy access configuration
-		regio, regErr := o.tenancyAccess[key].config.Region()
+		regio, regErr := ta.config.Region()
 		if regErr != nil {
 			return errors.Wrap(regErr, "error fetching Region")
 		} else {
@@ -78,7 +94,7 @@ func (o *OCIDatasource) TestConnectivity(ctx context.Context) error {
 		}
 
 		var status int
-		res, err := o.tenancyAccess[key].monitoringClient.ListMetrics(ctx, listMetrics)
+		res, err := ta.monitoringClient.ListMetrics(ctx, listMetrics)
 		if res.RawResponse == nil || res.RawResponse.ContentLength == 0 {
 			backend.Logger.Error("TestConnectivity", "Config Key", key, "error", err)
 			return fmt.Errorf("TestConnectivity failed: result is empty %v: %v", key, err)
@@ -118,7 +134,7 @@ func (o *OCIDatasource) TestConnectivity(ctx context.Context) error {
 					Limit:         common.Int(25),
 				}
 
-				res, err := o.tenancyAccess[key].monitoringClient.ListMetrics(ctx, listMetrics)
+				res, err := ta.monitoringClient.ListMetrics(ctx, listMetrics)
 				if err != nil {
 					backend.Logger.Error("TestConnectivity", "Config Key", key, "SKIPPED", err)
 				}
@@ -145,7 +161,9 @@ func (o *OCIDatasource) TestConnectivity(ctx context.Context) error {
 /*
 FetchTenancyOCID retrieves the tenancy OCID based on the provided tenancy access key (takey).
 
-This function handles different tenancy modes (single vs. multi-tenancy) and environments (local vs. OCI Instance).
+This function handles different tenancy modes (single vs. multi-tenancy) and environments (local vs. OCI Instance,
+OCI Resource Principal and OCI Workload Identity). In multi-tenancy mode the tenancy OCID is part of the key, for
+principals it is the target tenancy of the cross tenancy policies.
 It fetches the tenancy OCID from the appropriate configuration provider.
 
 Parameters:
@@ -153,19 +171,20 @@ Parameters:
 
 Returns:
   - string: The tenancy OCID.
-  - error: An error if the tenancy OCID cannot be fetched.
+  - error: An error if the tenancy is not configured or its OCID cannot be fetched.
 */
 func (o *OCIDatasource) FetchTenancyOCID(takey string) (string, error) {
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		return "", err
+	}
+
 	tenv := o.settings.Environment
 	tenancymode := o.settings.TenancyMode
 	xtenancy := o.settings.Xtenancy_0
 	var tenancyocid string
 	var tenancyErr error
 
-	if tenancymode == "multitenancy" && tenv == "OCI Instance" {
-		return "", errors.New("Multitenancy mode using instance principals is not implemented yet.")
-	}
-
 	if tenancymode == "multitenancy" {
 		if len(takey) <= 0 || takey == NoTenancy {
 			o.logger.Error("Unable to get Multi-tenancy OCID")
@@ -175,14 +194,14 @@ func (o *OCIDatasource) FetchTenancyOCID(takey string) (string, error) {
 			tenancyocid = res[1]
 		}
 	} else {
-		if xtenancy != "" && tenv == "OCI Instance" {
-			o.logger.Debug("Cross Tenancy Instance Principal detected")
-			tocid, _ := o.tenancyAccess[takey].config.TenancyOCID()
+		if xtenancy != "" && isPrincipalEnvironment(tenv) {
+			o.logger.Debug("Cross Tenancy Principal detected", "environment", tenv)
+			tocid, _ := ta.config.TenancyOCID()
 			o.logger.Debug("Source Tenancy OCID: " + tocid)
 			o.logger.Debug("Target Tenancy OCID: " + o.settings.Xtenancy_0)
 			tenancyocid = xtenancy
 		} else {
-			tenancyocid, tenancyErr = o.tenancyAccess[takey].config.TenancyOCID()
+			tenancyocid, tenancyErr = ta.config.TenancyOCID()
 			if tenancyErr != nil {
 				return "", errors.Wrap(tenancyErr, "error fetching TenancyOCID")
 			}
@@ -209,7 +228,7 @@ func (o *OCIDatasource) GetTenancies(ctx context.Context) []models.OCIResource {
 	backend.Logger.Error("client", "GetTenancies", "fetching the tenancies")
 
 	tenancyList := []models.OCIResource{}
-	for key := range o.tenancyAccess {
+	for key := range o.tenancyAccessSnapshot() {
 		// frame.AppendRow(*(common.String(key)))
 
 		tenancyList = append(tenancyList, models.OCIResource{
@@ -249,6 +268,11 @@ func (o *OCIDatasource) GetSubscribedRegions(ctx context.Context, tenancyOCID st
 		backend.Logger.Warn("client", "GetSubscribedRegions", "invalid takey")
 		return nil
 	}
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		backend.Logger.Warn("client", "GetSubscribedRegions", err)
+		return nil
+	}
 
 	tenancyocid, tenancyErr := o.FetchTenancyOCID(takey)
 	if tenancyErr != nil {
@@ -260,7 +284,7 @@ func (o *OCIDatasource) GetSubscribedRegions(ctx context.Context, tenancyOCID st
 
 	req := identity.ListRegionSubscriptionsRequest{TenancyId: common.String(tenancyocid)}
 
-	resp, err := o.tenancyAccess[takey].identityClient.ListRegionSubscriptions(ctx, req)
+	resp, err := ta.identityClient.ListRegionSubscriptions(ctx, req)
 	if err != nil {
 		backend.Logger.Warn("client", "GetSubscribedRegions", err)
 		return nil
@@ -268,7 +292,7 @@ func (o *OCIDatasource) GetSubscribedRegions(ctx context.Context, tenancyOCID st
 
 	// if err != nil {
 	// 	backend.Logger.Warn("client", "GetSubscribedRegions", err)
-	// 	subscribedRegions = append(subscribedRegions, o.tenancyAccess[takey].region)
+	// 	subscribedRegions = append(subscribedRegions, ta.region)
 	// 	return subscribedRegions
 	// }
 	if resp.RawResponse.StatusCode != 200 {
@@ -315,6 +339,11 @@ func (o *OCIDatasource) GetCompartments(ctx context.Context, tenancyOCID string,
 	backend.Logger.Error("client", "GetCompartments", "fetching the sub-compartments for tenancy: "+tenancyOCID)
 
 	takey := o.GetTenancyAccessKey(tenancyOCID)
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		backend.Logger.Warn("client", "GetCompartments", err)
+		return nil
+	}
 
 	tenancyocid, tenancyErr := o.FetchTenancyOCID(takey)
 	if tenancyErr != nil {
@@ -329,10 +358,20 @@ func (o *OCIDatasource) GetCompartments(ctx context.Context, tenancyOCID string,
 		return cachedCompartments.([]models.OCIResource)
 	}
 
+	// concurrent calls for the same compartments share a single upstream call
+	compartmentList, _ := coalesceCached(ctx, o, cacheKey, func(ctx context.Context) ([]models.OCIResource, error) {
+		return o.listCompartments(ctx, ta, tenancyocid, cacheKey, includeAccessibleOnly...), nil
+	})
+
+	return compartmentList
+}
+
+// listCompartments lists the compartments of a tenancy and caches them, see GetCompartments.
+func (o *OCIDatasource) listCompartments(ctx context.Context, ta *TenancyAccess, tenancyocid string, cacheKey string, includeAccessibleOnly ...bool) []models.OCIResource {
 	req := identity.GetTenancyRequest{TenancyId: common.String(tenancyocid)}
 
 	// Send the request using the service client
-	resp, err := o.tenancyAccess[takey].identityClient.GetTenancy(context.Background(), req)
+	resp, err := ta.identityClient.GetTenancy(context.Background(), req)
 	if err != nil {
 		backend.Logger.Error("client", "GetCompartments", "error in GetTenancy")
 		return nil
@@ -355,7 +394,7 @@ func (o *OCIDatasource) GetCompartments(ctx context.Context, tenancyOCID string,
 	var pageHeader string
 
 	for {
-		res, err := o.tenancyAccess[takey].identityClient.ListCompartments(ctx,
+		res, err := ta.identityClient.ListCompartments(ctx,
 			identity.ListCompartmentsRequest{
 				CompartmentId:          common.String(tenancyocid),
 				Page:                   &pageHeader,
@@ -461,6 +500,11 @@ func (o *OCIDatasource) GetNamespaceWithMetricNames(
 	backend.Logger.Error("client", "GetNamespaceWithMetricNames", "fetching the metric names along with namespaces under compartment: "+compartmentOCID)
 
 	takey := o.GetTenancyAccessKey(tenancyOCID)
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		backend.Logger.Warn("client", "GetNamespaceWithMetricNames", err)
+		return nil
+	}
 	// fetching from cache, if present
 	cacheKey := strings.Join([]string{tenancyOCID, compartmentOCID, region, "nss"}, "-")
 	if cachedMetricNamesWithNamespaces, found := o.cache.Get(cacheKey); found {
@@ -473,6 +517,16 @@ func (o *OCIDatasource) GetNamespaceWithMetricNames(
 		}
 	}
 
+	// concurrent calls for the same namespaces share a single upstream call
+	namespaceWithMetricNamesList, _ := coalesceCached(ctx, o, cacheKey, func(ctx context.Context) ([]models.OCIMetricNamesWithNamespace, error) {
+		return o.listNamespaceWithMetricNames(ctx, ta, tenancyOCID, compartmentOCID, region, cacheKey), nil
+	})
+
+	return namespaceWithMetricNamesList
+}
+
+// listNamespaceWithMetricNames lists the namespaces and their metric names and caches them, see GetNamespaceWithMetricNames.
+func (o *OCIDatasource) listNamespaceWithMetricNames(ctx context.Context, ta *TenancyAccess, tenancyOCID string, compartmentOCID string, region string, cacheKey string) []models.OCIMetricNamesWithNamespace {
 	// calling the api if not present in cache
 	var namespaceWithMetricNames map[string][]string
 	namespaceWithMetricNamesList := []models.OCIMetricNamesWithNamespace{}
@@ -500,7 +554,7 @@ func (o *OCIDatasource) GetNamespaceWithMetricNames(
 			o.cache,
 			cacheKey,
 			constants.FETCH_FOR_NAMESPACE,
-			o.tenancyAccess[takey].monitoringClient,
+			ta,
 			monitoringRequest,
 			o.GetSubscribedRegions(ctx, tenancyOCID),
 		)
@@ -510,7 +564,7 @@ func (o *OCIDatasource) GetNamespaceWithMetricNames(
 			o.cache,
 			cacheKey,
 			constants.FETCH_FOR_NAMESPACE,
-			o.tenancyAccess[takey].monitoringClient,
+			ta.MonitoringClientForRegion(region),
 			monitoringRequest,
 		)
 	}
@@ -549,7 +603,9 @@ func (o *OCIDatasource) GetNamespaceWithMetricNames(
 // Returns:
 //   - []time.Time: A slice of time.Time representing the timestamps for the retrieved data points.
 //   - []models.OCIMetricDataPoints: A slice of OCIMetricDataPoints, each containing the data points, labels, and other metadata for a metric.
-//   - error: An error if any operation fails during the process.
+//   - map[string]error: The errors of the regions that could not be fetched, keyed by region, when other regions succeeded.
+//   - error: An error if any operation fails during the process, or the errors of every region, sorted by region,
+//     if no region could be fetched.
 //
 // API Operation:
 //   - SummarizeMetricsData: https://docs.oracle.com/en-us/iaas/api/#/en/monitoring/20180401/MetricData/SummarizeMetricsData
@@ -559,9 +615,12 @@ func (o *OCIDatasource) GetNamespaceWithMetricNames(
 //   - METRIC_READ: Required to read metric data.
 //
 // Data Handling:
-//   - Handles fetching data for all regions in parallel when specified.
-//   - Adjusts data when different resource datapoints have a mismatched number of values.
-//   - Supports filtering by resource group, dimensions, and tags.
+//   - Handles fetching data for all regions in parallel when specified, using a bounded pool of workers.
+//   - Returns partial results when some of the regions fail, the failures are reported per region.
+//   - Aligns the series on the timestamps of all the series, the missing datapoints being null, zero or the
+//     previous value of the series as requested by the fill mode.
+//   - Supports filtering by resource group, dimensions, and tags. With selected tags, the series without a resource id
+//     are skipped, and the regions whose tagged resources cannot be searched are reported as failed.
 //   - Adds labels based on selected dimensions and tags.
 //   - Sorts the time slice for proper representation in Grafana.
 //
@@ -569,12 +628,12 @@ func (o *OCIDatasource) GetNamespaceWithMetricNames(
 //   - Returns an error if an invalid 'takey' (tenancy access key) is detected.
 //   - Returns any errors encountered during API calls.
 //   - Logs errors encountered during the data retrieval process.
-func (o *OCIDatasource) GetMetricDataPoints(ctx context.Context, requestParams models.MetricsDataRequest, tenancyOCID string) ([]time.Time, []models.OCIMetricDataPoints, error) {
+func (o *OCIDatasource) GetMetricDataPoints(ctx context.Context, requestParams models.MetricsDataRequest, tenancyOCID string) ([]time.Time, []models.OCIMetricDataPoints, map[string]error, error) {
 	backend.Logger.Error("client", "GetMetricDataPoints", "fetching the metrics datapoints under compartment '"+requestParams.CompartmentOCID+"' for query '"+requestParams.QueryText+"'")
 
 	times := []time.Time{}
-	dataValuesWithTime := map[common.SDKTime][]float64{}
-	dataPointsWithResourceSerialNo := map[int]models.OCIMetricDataPoints{}
+	timesSeen := map[time.Time]struct{}{}
+	seriesValues := []map[time.Time]float64{}
 	dataPoints := []models.OCIMetricDataPoints{}
 	resourceIDsPerTag := map[string]map[string]struct{}{}
 	var takey string
@@ -589,7 +648,15 @@ func (o *OCIDatasource) GetMetricDataPoints(ctx context.Context, requestParams m
 
 	if len(takey) == 0 {
 		backend.Logger.Warn("client", "GetMetricDataPoints", "invalid takey")
-		return nil, nil, errors.New("Datasource not configured (invalid takey)")
+		return nil, nil, nil, errors.New("Datasource not configured (invalid takey)")
+	}
+
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if stErr := o.checkSessionToken(takey); stErr != nil {
+		return nil, nil, nil, stErr
 	}
 
 	metricsDataRequest := monitoring.SummarizeMetricsDataRequest{
@@ -603,9 +670,13 @@ func (o *OCIDatasource) GetMetricDataPoints(ctx context.Context, requestParams m
 		},
 	}
 
-	// to search for all compartments
+	// to search for all compartments, the root compartment and its subtree as for the alarm queries, see queryScope
 	if len(requestParams.CompartmentOCID) == 0 {
-		metricsDataRequest.CompartmentId = common.String(requestParams.TenancyOCID)
+		tenancyocid, err := o.FetchTenancyOCID(takey)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		metricsDataRequest.CompartmentId = common.String(tenancyocid)
 		metricsDataRequest.CompartmentIdInSubtree = common.Bool(true)
 	}
 
@@ -616,7 +687,6 @@ func (o *OCIDatasource) GetMetricDataPoints(ctx context.Context, requestParams m
 		}
 	}
 
-	var allRegionsMetricsDataPoint sync.Map
 	subscribedRegions := []string{}
 
 	if requestParams.Region == constants.ALL_REGION {
@@ -627,133 +697,68 @@ func (o *OCIDatasource) GetMetricDataPoints(ctx context.Context, requestParams m
 		}
 	}
 
-	// fetching the metrics data for specified region in parallel
-	var wg sync.WaitGroup
-	errCh := make(chan error)
-	for _, subscribedRegion := range subscribedRegions {
-		if subscribedRegion != constants.ALL_REGION {
-			wg.Add(1)
-			go func(mc monitoring.MonitoringClient, sRegion string, errCh chan error) {
-				defer wg.Done()
-				resp, err := mc.SummarizeMetricsData(ctx, metricsDataRequest)
-				if err != nil {
-					backend.Logger.Error("client", "GetMetricDataPoints", err)
-					errCh <- err
-				}
-
-				if len(resp.Items) > 0 {
-					// fetching the resource labels
-					var rl map[string]map[string]string
-
-					// Tags will be used in future releases
-					// cachedResourceLabels := o.fetchFromCache(
-					// 	ctx,
-					// 	requestParams.TenancyOCID,
-					// 	requestParams.CompartmentOCID,
-					// 	requestParams.CompartmentName,
-					// 	sRegion,
-					// 	requestParams.Namespace,
-					// 	"resource_labels",
-					// )
-
-					// rl = cachedResourceLabels.(map[string]map[string]string)
-
-					// storing the data to calculate later
-					allRegionsMetricsDataPoint.Store(sRegion, metricDataBank{
-						dataPoints:     resp.Items,
-						resourceLabels: rl,
-					})
-				}
-				errCh <- nil
-			}(o.tenancyAccess[takey].monitoringClient, subscribedRegion, errCh)
-			// Receive on the error channel
-			err := <-errCh
-			if err != nil {
-				backend.Logger.Error("client", "GetMetricDataPoints", err)
-				return nil, nil, err
-			}
-		}
+	// fetching the metrics data for specified regions in parallel
+	allRegionsMetricsDataPoint, regionErrors := o.fetchMetricDataFromRegions(ctx, takey, ta, metricsDataRequest, subscribedRegions, requestParams.Interval)
+	if len(regionErrors) > 0 && len(allRegionsMetricsDataPoint) == 0 {
+		// nothing to show, every region failed
+		return nil, nil, nil, regionsError("metric data could not be fetched from any region", regionErrors)
 	}
-	wg.Wait()
-
-	resourcesFetched := 0
-
-	allRegionsMetricsDataPoint.Range(func(key, value interface{}) bool {
-		regionInUse := key.(string)
 
+	if regionErrors == nil {
+		regionErrors = map[string]error{}
+	}
+	tagLookupErrors := map[string]error{}
+	for regionInUse, metricData := range allRegionsMetricsDataPoint {
 		backend.Logger.Debug("client", "GetMetricDataPoints", "Metric datapoints got for region-"+regionInUse)
 
-		// Tags will be used in future releases
-		// get the selected tags
-		// if len(selectedTags) != 0 {
-		// 	cachedResourceNamesPerTag := o.fetchFromCache(
-		// 		ctx,
-		// 		requestParams.TenancyOCID,
-		// 		requestParams.CompartmentOCID,
-		// 		requestParams.CompartmentName,
-		// 		regionInUse,
-		// 		requestParams.Namespace,
-		// 		constants.CACHE_KEY_RESOURCE_IDS_PER_TAG,
-		// 	)
-
-		// 	resourceIDsPerTag = cachedResourceNamesPerTag.(map[string]map[string]struct{})
-		// }
-
-		metricData := value.(metricDataBank)
+		// resolving the name, compartment and tags of the resources with the resource search
+		metricData.resourceLabels = o.getResourceLabels(ctx, tenancyOCID, regionInUse, requestParams, metricData.dataPoints)
+
+		// get the resources carrying the selected tags
+		if len(selectedTags) != 0 {
+			cachedResourceNamesPerTag := o.fetchFromCache(
+				ctx,
+				requestParams.TenancyOCID,
+				requestParams.CompartmentOCID,
+				requestParams.CompartmentName,
+				regionInUse,
+				requestParams.Namespace,
+				constants.CACHE_KEY_RESOURCE_IDS_PER_TAG,
+			)
+
+			var found bool
+			resourceIDsPerTag, found = cachedResourceNamesPerTag.(map[string]map[string]struct{})
+			if !found {
+				// without the tagged resources the series cannot be filtered, the region is reported instead of
+				// showing every series or none
+				tagLookupErrors[regionInUse] = errors.New("the resources carrying the selected tags could not be searched")
+				regionErrors[regionInUse] = tagLookupErrors[regionInUse]
+				continue
+			}
+		}
 
 		for _, metricDataItem := range metricData.dataPoints {
-			found := false
-
 			uniqueDataID, dimensionKey, resourceDisplayName, extraUniqueID, rIDPresent := getUniqueIdsForLabels(requestParams.Namespace, metricDataItem.Dimensions, requestParams.QueryText)
 
-			if rIDPresent {
-				for _, selectedTag := range selectedTags {
-					if _, ok := resourceIDsPerTag[selectedTag][uniqueDataID]; ok {
-						found = true
-						break
-					}
-				}
+			// series of resources without the selected tags, or without a resource id to match them, are skipped
+			if len(selectedTags) != 0 && (!rIDPresent || !matchesSelectedTags(resourceIDsPerTag, selectedTags, uniqueDataID)) {
+				continue
+			}
 
-				if len(selectedTags) != 0 && !found {
+			// the series are aligned on the timestamps of all the series once they are all fetched
+			values := make(map[time.Time]float64, len(metricDataItem.AggregatedDatapoints))
+			for _, eachMetricDataPoint := range metricDataItem.AggregatedDatapoints {
+				if eachMetricDataPoint.Timestamp == nil || eachMetricDataPoint.Value == nil {
 					continue
 				}
-			}
-
-			metricDatapoints := metricDataItem.AggregatedDatapoints
-
-			// sorting the data by increasing time
-			sort.SliceStable(metricDatapoints, func(i, j int) bool {
-				return metricDatapoints[i].Timestamp.Time.Before(metricDatapoints[j].Timestamp.Time)
-			})
-
-			// sometimes 2 different resource datapoint have mismatched no of values
-			// to make it equal fill the extra point with previous value
-			resourcesFetched += 1
-			previousValue := 0.0
-			for _, eachMetricDataPoint := range metricDatapoints {
-				t := *eachMetricDataPoint.Timestamp
-				v := *eachMetricDataPoint.Value
-
-				if _, ok := dataValuesWithTime[t]; ok {
-					dataValuesWithTime[t] = append(dataValuesWithTime[t], v)
-					previousValue = v
-				} else {
-					if resourcesFetched == 1 {
-						dataValuesWithTime[t] = []float64{v}
-						previousValue = v
-						continue
-					}
-
-					// adjustment for previous non-existance values with the immediate previous value
-					// when the time comes in later data points
-					dataValuesWithTime[t] = []float64{previousValue}
-					for i := 2; i < resourcesFetched; i++ {
-						dataValuesWithTime[t] = append(dataValuesWithTime[t], previousValue)
-					}
-					dataValuesWithTime[t] = append(dataValuesWithTime[t], v)
-					previousValue = v
+				t := eachMetricDataPoint.Timestamp.Time.UTC()
+				if _, ok := timesSeen[t]; !ok {
+					timesSeen[t] = struct{}{}
+					times = append(times, t)
 				}
+				values[t] = *eachMetricDataPoint.Value
 			}
+			seriesValues = append(seriesValues, values)
 
 			// for base tenancy
 			splits := strings.Split(tenancyOCID, "/")
@@ -762,23 +767,31 @@ func (o *OCIDatasource) GetMetricDataPoints(ctx context.Context, requestParams m
 			// to get the resource labels
 			labelKey := uniqueDataID + extraUniqueID
 			if strings.Contains(resourceDisplayName, "ocid") {
-				resourceDisplayName = metricData.resourceLabels[labelKey]["resource_name"]
+				if name, ok := metricData.resourceLabels[labelKey]["resource_name"]; ok {
+					resourceDisplayName = name
+				}
 			}
 
-			var labelsToAdd map[string]string
+			// copying the resource labels, they are shared by the series of the same resource
+			labelsToAdd := map[string]string{}
+			for k, v := range metricData.resourceLabels[labelKey] {
+				labelsToAdd[k] = v
+			}
 			if requestParams.RawQuery {
 				// adding the selected dimensions as labels if dropdowns are selected
-				labelsToAdd = addSelectedValuesLabels(metricData.resourceLabels[labelKey], selectedDimensions)
+				labelsToAdd = addSelectedValuesLabels(labelsToAdd, selectedDimensions, metricDataItem.Dimensions)
 			} else {
 				// adding the all returned dimensions as labels if raw query is selected are selected
-				labelsToAdd = metricDataItem.Dimensions
+				for k, v := range metricDataItem.Dimensions {
+					labelsToAdd[k] = v
+				}
 			}
 
 			// adding the selected tags as labels
-			labelsToAdd = addSelectedValuesLabels(labelsToAdd, selectedTags)
+			labelsToAdd = addSelectedValuesLabels(labelsToAdd, selectedTags, nil)
 
 			// preparing the metric data to display
-			dataPointsWithResourceSerialNo[resourcesFetched-1] = models.OCIMetricDataPoints{
+			dataPoints = append(dataPoints, models.OCIMetricDataPoints{
 				TenancyName:  tenancyName,
 				Region:       regionInUse,
 				MetricName:   *metricDataItem.Name,
@@ -786,59 +799,203 @@ func (o *OCIDatasource) GetMetricDataPoints(ctx context.Context, requestParams m
 				UniqueDataID: uniqueDataID,
 				DimensionKey: dimensionKey,
 				Labels:       labelsToAdd,
-			}
+				Metadata:     metricDataItem.Metadata,
+			})
 		}
+	}
 
-		return true
+	// the tag filter could not be applied in any region
+	if len(tagLookupErrors) > 0 && len(tagLookupErrors) == len(allRegionsMetricsDataPoint) {
+		return nil, nil, nil, regionsError("the resources carrying the selected tags could not be searched in any region", tagLookupErrors)
+	}
+
+	// sorting the time slice, for grafana
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
 	})
 
-	timesToFetch := []common.SDKTime{}
-	// adjustment for later non-existance values with last value
-	for t, dvs := range dataValuesWithTime {
-		times = append(times, t.Time)
-		timesToFetch = append(timesToFetch, t)
+	// aligning every series on the times of all the series, the missing datapoints are filled as requested
+	for i := range dataPoints {
+		dataPoints[i].DataPoints = alignDataPoints(times, seriesValues[i], requestParams.FillMode)
+	}
+
+	return times, dataPoints, regionErrors, nil
+}
+
+// getResourceLabels returns the labels of the resources of the metric data of a region, keyed as the
+// resource labels of metricDataBank: unique data id followed by the extra unique id.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - tenancyOCID: The tenancy of the query.
+//   - region: The region of the metric data.
+//   - requestParams: The metrics data request.
+//   - dataPoints: The metric data of the region.
+//
+// Returns:
+//   - map[string]map[string]string: The labels of each resource found.
+func (o *OCIDatasource) getResourceLabels(ctx context.Context, tenancyOCID string, region string, requestParams models.MetricsDataRequest, dataPoints []monitoring.MetricData) map[string]map[string]string {
+	labelKeys := map[string]string{}
+	resourceIDs := []string{}
+	for _, metricDataItem := range dataPoints {
+		uniqueDataID, _, _, extraUniqueID, _ := getUniqueIdsForLabels(requestParams.Namespace, metricDataItem.Dimensions, requestParams.QueryText)
+		labelKeys[uniqueDataID+extraUniqueID] = uniqueDataID
+		resourceIDs = append(resourceIDs, uniqueDataID)
+	}
+
+	resourcesInfo := o.GetResourcesInfo(ctx, tenancyOCID, region, resourceIDs)
+
+	resourceLabels := map[string]map[string]string{}
+	for labelKey, resourceID := range labelKeys {
+		if info, ok := resourcesInfo[resourceID]; ok {
+			resourceLabels[labelKey] = resourceInfoLabels(info)
+		}
+	}
+
+	return resourceLabels
+}
+
+// fetchMetricDataFromRegions calls SummarizeMetricsData for each of the given regions in parallel.
+//
+// Long time ranges are split into the windows returned by metricQueryWindows, each region and window being
+// requested separately and the windows of a region stitched back per series. The requests are distributed
+// over a bounded pool of workers (constants.MAX_REGION_WORKERS), every call runs with its own timeout
+// (constants.REGION_QUERY_TIMEOUT) and uses the monitoring client of its region. When the context is cancelled
+// no further requests are dispatched and the regions left behind are reported with the context error.
+// A region fails when any of its windows fails, a failure in one region does not stop the others.
+//
+// The datapoints of the past are cached by bucket, see cachedMetricData: only the time ranges of a region
+// missing from the cache are requested, the complete buckets fetched being cached for the next requests.
+// Concurrent queries requesting the same window of the same region share a single call, see coalesce.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - takey: The tenancy access key, part of the cache keys.
+//   - ta: The TenancyAccess used to build the per-region monitoring clients.
+//   - req: The SummarizeMetricsData request to send to every region, its time range is split by window.
+//   - regions: The regions to fetch. constants.ALL_REGION is skipped.
+//   - interval: The interval of the query, used to split its time range.
+//
+// Returns:
+//   - map[string]metricDataBank: The data fetched, keyed by region, for the regions that succeeded.
+//   - map[string]error: The error, keyed by region, for the regions that failed.
+func (o *OCIDatasource) fetchMetricDataFromRegions(ctx context.Context, takey string, ta *TenancyAccess, req monitoring.SummarizeMetricsDataRequest, regions []string, interval string) (map[string]metricDataBank, map[string]error) {
+	type windowRequest struct {
+		region string
+		window int
+	}
+
+	regionErrors := map[string]error{}
+	regionCachedData := map[string][][]monitoring.MetricData{}
+	regionFetched := map[string][]timeWindow{}
+	regionWindows := map[string][]timeWindow{}
+	regionWindowsData := map[string][][]monitoring.MetricData{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 
-		if len(dvs) == resourcesFetched {
+	requests := []windowRequest{}
+	for _, region := range regions {
+		if region == constants.ALL_REGION {
 			continue
 		}
 
-		lastValue := dataValuesWithTime[t][len(dataValuesWithTime[t])-1]
-		for i := 0; i < resourcesFetched-len(dvs); i++ {
-			dataValuesWithTime[t] = append(dataValuesWithTime[t], lastValue)
+		// only the time ranges missing from the cache are requested
+		cached, toFetch := o.cachedMetricData(takey, region, req, interval)
+		windows := []timeWindow{}
+		for _, fetch := range toFetch {
+			windows = append(windows, metricQueryWindows(fetch.start, fetch.end, interval)...)
+		}
+		backend.Logger.Debug("client", "fetchMetricDataFromRegions", "region "+region+": "+strconv.Itoa(len(cached))+" cached buckets, "+strconv.Itoa(len(windows))+" windows to fetch")
+
+		regionCachedData[region] = cached
+		regionFetched[region] = toFetch
+		regionWindows[region] = windows
+		regionWindowsData[region] = make([][]monitoring.MetricData, len(windows))
+		for i := range windows {
+			requests = append(requests, windowRequest{region: region, window: i})
 		}
 	}
 
-	// sorting the time slice, for grafana
-	sort.SliceStable(times, func(i, j int) bool {
-		return times[i].Before(times[j])
-	})
-	// sorting the time slice, for internal fetch
-	sort.SliceStable(timesToFetch, func(i, j int) bool {
-		return timesToFetch[i].Time.Before(timesToFetch[j].Time)
-	})
+	workers := constants.MAX_REGION_WORKERS
+	if len(requests) < workers {
+		workers = len(requests)
+	}
+
+	requestCh := make(chan windowRequest)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for wr := range requestCh {
+				mu.Lock()
+				_, failed := regionErrors[wr.region]
+				mu.Unlock()
+				if failed {
+					continue
+				}
+
+				window := regionWindows[wr.region][wr.window]
+				windowReq := req
+				windowReq.StartTime = &common.SDKTime{Time: window.start}
+				windowReq.EndTime = &common.SDKTime{Time: window.end}
+
+				// concurrent queries requesting the same window share a single upstream call, the windows are cached
+				// per bucket by storeMetricData once all are fetched
+				items, err := coalesce(ctx, o, metricWindowKey(takey, wr.region, req, interval, window), func(ctx context.Context) ([]monitoring.MetricData, error) {
+					regionCtx, cancel := context.WithTimeout(ctx, constants.REGION_QUERY_TIMEOUT)
+					defer cancel()
+					resp, err := ta.MonitoringClientForRegion(wr.region).SummarizeMetricsData(regionCtx, windowReq)
+					return resp.Items, err
+				})
+
+				mu.Lock()
+				if err != nil {
+					backend.Logger.Error("client", "fetchMetricDataFromRegions", "region "+wr.region+": "+err.Error())
+					regionErrors[wr.region] = err
+				} else {
+					regionWindowsData[wr.region][wr.window] = items
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for _, wr := range requests {
+		select {
+		case requestCh <- wr:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(requestCh)
+	wg.Wait()
 
-	dataValuesWithResourceSerialNo := map[int][]float64{}
-	// final preparation
-	for _, t := range timesToFetch {
-		dvIndex := 0
-		for i := 0; i < resourcesFetched; i++ {
-			dataValuesWithResourceSerialNo[i] = append(dataValuesWithResourceSerialNo[i], dataValuesWithTime[t][dvIndex])
-			dvIndex += 1
+	// requests never dispatched because the request was cancelled
+	for _, wr := range requests[dispatched:] {
+		if _, failed := regionErrors[wr.region]; !failed {
+			regionErrors[wr.region] = ctx.Err()
 		}
 	}
 
-	// extracting for grafana
-	for i, dps := range dataValuesWithResourceSerialNo {
-		dp := dataPointsWithResourceSerialNo[i]
-		dp.DataPoints = dps
+	regionsData := map[string]metricDataBank{}
+	for region, windowsData := range regionWindowsData {
+		if _, failed := regionErrors[region]; failed {
+			continue
+		}
+		fetched := mergeMetricData(windowsData)
+		o.storeMetricData(takey, region, req, interval, regionFetched[region], fetched)
 
-		dataPoints = append(dataPoints, dp)
+		regionsData[region] = metricDataBank{
+			dataPoints: mergeMetricData(append(regionCachedData[region], fetched)),
+		}
 	}
 
-	return times, dataPoints, nil
+	return regionsData, regionErrors
 }
 
-// ****** WARNING This function is not implemented yet ******
 // fetchFromCache retrieves data from the cache based on the provided parameters.
 // If the data is not found in the cache, it fetches the tags and updates the cache.
 //
@@ -867,13 +1024,27 @@ func (o *OCIDatasource) fetchFromCache(ctx context.Context, tenancyOCID string,
 	return cachedResource
 }
 
-// ****** WARNING This function is not implemented yet ******
 // GetTags Returns all the defined as well as freeform tags attached with resources for a namespace under a compartment
-// fetching the resources based on which type resources we want
-// API Operation: ListInstances, ListVcns
-// Permission Required:
+// fetching the resources with the Resource Search service, restricted to the resource types of the namespace
+// API Operation: SearchResources
+// Permission Required: inspect on the resources
 // Links:
-// https://docs.oracle.com/en-us/iaas/api/#/en/iaas/20160918/Instance/ListInstances
+// https://docs.oracle.com/en-us/iaas/api/#/en/search/20180409/ResourceSummary/SearchResources
+//
+// Freeform tags are returned with their key, defined tags with <namespace>.<key>. The tags and the resource
+// ids carrying each key=value pair are cached per region, the resource ids are used by GetMetricDataPoints
+// to filter the series on the selected tags.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - tenancyOCID: The OCID of the tenancy.
+//   - compartmentOCID: The OCID of the compartment, its sub-compartments included, the whole tenancy when empty.
+//   - compartmentName: The name of the compartment.
+//   - region: The region to query. If set to constants.ALL_REGION, it queries all subscribed regions.
+//   - namespace: The metric namespace.
+//
+// Returns:
+//   - []models.OCIResourceTags: The tag keys with their values, sorted by key.
 func (o *OCIDatasource) GetTags(
 	ctx context.Context,
 	tenancyOCID string,
@@ -884,7 +1055,24 @@ func (o *OCIDatasource) GetTags(
 	backend.Logger.Error("client", "GetTags", "fetching the tags for namespace '"+namespace+"'")
 
 	resourceTagsList := []models.OCIResourceTags{}
-	allResourceTags := map[string][]string{}
+	allResourceTags := map[string]map[string]struct{}{}
+
+	takey := o.GetTenancyAccessKey(tenancyOCID)
+	if len(takey) == 0 {
+		backend.Logger.Warn("client", "GetTags", "invalid takey")
+		return resourceTagsList
+	}
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		backend.Logger.Warn("client", "GetTags", err)
+		return resourceTagsList
+	}
+
+	// the resources of a compartment are searched in its sub-compartments as well, the whole tenancy without compartment
+	var compartmentOCIDs []string
+	if compartmentOCID != "" && compartmentOCID != constants.DEFAULT_COMPARTMENT_PLACEHOLDER {
+		compartmentOCIDs = o.compartmentSubtree(ctx, ta, takey, compartmentOCID)
+	}
 
 	// building the regions list
 	subscribedRegions := []string{}
@@ -896,41 +1084,6 @@ func (o *OCIDatasource) GetTags(
 		}
 	}
 
-	// compartments := []models.OCIResource{}
-	// if len(compartmentOCID) == 0 {
-	// 	compartments = append(compartments, o.GetCompartments(ctx, tenancyOCID)...)
-	// } else {
-	// 	compartments = append(compartments, models.OCIResource{
-	// 		Name: compartmentName,
-	// 		OCID: compartmentOCID,
-	// 	})
-	// }
-
-	// var ccc core.ComputeClient
-	// //var vcc core.VirtualNetworkClient
-	// var lbc loadbalancer.LoadBalancerClient
-	// var hcc healthchecks.HealthChecksClient
-	// var dbc database.DatabaseClient
-	// var adc apmcontrolplane.ApmDomainClient
-	// var asc apmsynthetics.ApmSyntheticClient
-	var cErr error
-
-	// switch constants.OCI_NAMESPACES[namespace] {
-	// case constants.OCI_TARGET_COMPUTE, constants.OCI_TARGET_VCN:
-	// 	ccc, cErr = client.GetComputeClient()
-	// // case constants.OCI_TARGET_VCN:
-	// // 	ccc, cErr = client.GetComputeClient()
-	// // 	vcc, cErr = client.GetVCNClient()
-	// case constants.OCI_TARGET_LBAAS:
-	// 	lbc, cErr = client.GetLBaaSClient()
-	// case constants.OCI_TARGET_HEALTHCHECK:
-	// 	hcc, cErr = client.GetHealthChecksClient()
-	// case constants.OCI_TARGET_DATABASE:
-	// 	dbc, cErr = client.GetDatabaseClient()
-	// case constants.OCI_TARGET_APM:
-	// 	adc, asc, cErr = client.GetApmClients()
-	// }
-
 	var allRegionsResourceTags sync.Map
 	var wg sync.WaitGroup
 	for _, subscribedRegion := range subscribedRegions {
@@ -964,76 +1117,30 @@ func (o *OCIDatasource) GetTags(
 					}
 				}
 
-				// when creating client has some error
-				if cErr != nil {
+				client, err := ta.SearchClientForRegion(sRegion)
+				if err != nil {
+					backend.Logger.Error("client", "GetTags", err)
+					return
+				}
+				resourcesInfo, err := searchTaggedResources(ctx, client, namespace, compartmentOCIDs)
+				if err != nil {
+					backend.Logger.Error("client", "GetTags", "region "+sRegion+": "+err.Error())
 					return
 				}
-
-				labelCacheKey := strings.Join([]string{tenancyOCID, compartmentOCID, sRegion, namespace, "resource_labels"}, "-")
 
 				resourceTags := map[string][]string{}
 				resourceIDsPerTag := map[string]map[string]struct{}{}
-				resourceLabels := map[string]map[string]string{}
-
-				// Tags to be implemented in future release
-				// switch constants.OCI_NAMESPACES[namespace] {
-				// case constants.OCI_TARGET_COMPUTE:
-				// 	ccc.SetRegion(sRegion)
-				// 	ocic := OCICore{
-				// 		ctx:           ctx,
-				// 		computeClient: ccc,
-				// 	}
-				// 	resourceTags, resourceIDsPerTag, resourceLabels = ocic.GetComputeResourceTagsPerRegion(compartments)
-				// case constants.OCI_TARGET_VCN:
-				// 	//vcc.SetRegion(sRegion)
-				// 	ccc.SetRegion(sRegion)
-				// 	ocic := OCICore{
-				// 		ctx:           ctx,
-				// 		computeClient: ccc,
-				// 	}
-				// 	resourceTags, resourceIDsPerTag, resourceLabels = ocic.GetVNicResourceTagsPerRegion(compartments)
-				// case constants.OCI_TARGET_LBAAS:
-				// 	lbc.SetRegion(sRegion)
-				// 	ocilb := OCILoadBalancer{
-				// 		ctx:    ctx,
-				// 		client: lbc,
-				// 	}
-				// 	resourceTags, resourceIDsPerTag, resourceLabels = ocilb.GetLBaaSResourceTagsPerRegion(compartments)
-				// case constants.OCI_TARGET_HEALTHCHECK:
-				// 	hcc.SetRegion(sRegion)
-				// 	ocihc := OCIHealthChecks{
-				// 		ctx:               ctx,
-				// 		healthCheckClient: hcc,
-				// 	}
-				// 	resourceTags, resourceIDsPerTag, resourceLabels = ocihc.GetHealthChecksTagsPerRegion(compartments)
-				// case constants.OCI_TARGET_DATABASE:
-				// 	dbc.SetRegion(sRegion)
-				// 	db := OCIDatabase{
-				// 		ctx:    ctx,
-				// 		client: dbc,
-				// 	}
-
-				// 	switch namespace {
-				// 	case constants.OCI_NS_DB_ORACLE:
-				// 		resourceTags, resourceIDsPerTag, resourceLabels = db.GetOracleDatabaseTagsPerRegion(compartments)
-				// 	case constants.OCI_NS_DB_EXTERNAL:
-				// 		resourceTags, resourceIDsPerTag, resourceLabels = db.GetExternalPluggableDatabaseTagsPerRegion(compartments)
-				// 	case constants.OCI_NS_DB_AUTONOMOUS:
-				// 		resourceTags, resourceIDsPerTag, resourceLabels = db.GetAutonomousDatabaseTagsPerRegion(compartments)
-				// 	}
-				// case constants.OCI_TARGET_APM:
-				// 	adc.SetRegion(sRegion)
-				// 	asc.SetRegion(sRegion)
-				// 	apm := OCIApm{
-				// 		ctx:             ctx,
-				// 		domainClient:    adc,
-				// 		syntheticClient: asc,
-				// 	}
-				// 	resourceTags, resourceIDsPerTag, resourceLabels = apm.GetApmTagsPerRegion(compartments)
-				// }
-
-				// storing the labels in cache to use along with metric data
-				o.cache.SetWithTTL(labelCacheKey, resourceLabels, 1, 15*time.Minute)
+				for _, info := range resourcesInfo {
+					for key, value := range resourceInfoTags(info) {
+						tag := key + "=" + value
+						if _, ok := resourceIDsPerTag[tag]; !ok {
+							resourceIDsPerTag[tag] = map[string]struct{}{}
+							resourceTags[key] = append(resourceTags[key], value)
+						}
+						resourceIDsPerTag[tag][info.ResourceID] = struct{}{}
+					}
+				}
+
 				// saving in cache - previous was 30
 				o.cache.SetWithTTL(rTagsCacheKey, resourceTags, 1, 15*time.Minute)
 				o.cache.SetWithTTL(rIDsPerTagCacheKey, resourceIDsPerTag, 1, 15*time.Minute)
@@ -1046,44 +1153,17 @@ func (o *OCIDatasource) GetTags(
 	}
 	wg.Wait()
 
-	// // clearing up
-	// ccc = core.ComputeClient{}
-	// //vcc = core.VirtualNetworkClient{}
-	// lbc = loadbalancer.LoadBalancerClient{}
-	// hcc = healthchecks.HealthChecksClient{}
-	// dbc = database.DatabaseClient{}
-
 	allRegionsResourceTags.Range(func(key, value interface{}) bool {
 		backend.Logger.Info("client", "getResourceTags", "Resource tags got for region-"+key.(string))
 
-		resourceTagsGot := value.(map[string][]string)
-
-		// for first entry
-		if len(allResourceTags) == 0 {
-			allResourceTags = resourceTagsGot
-			return true
-		}
-
 		// k will be tag key
 		// values will be tag values
-		for k, values := range resourceTagsGot {
+		for k, values := range value.(map[string][]string) {
 			if _, ok := allResourceTags[k]; !ok {
-				// when key not present
-				allResourceTags[k] = values
-				continue
+				allResourceTags[k] = map[string]struct{}{}
 			}
-
-			// when key is already present
-			for _, mn := range values {
-				findIndex := sort.SearchStrings(allResourceTags[k], mn)
-				if findIndex < len(allResourceTags[k]) && allResourceTags[k][findIndex] != mn {
-					// not found, and insert in between
-					allResourceTags[k] = append(allResourceTags[k][:findIndex+1], allResourceTags[k][findIndex:]...)
-					allResourceTags[k][findIndex] = mn
-				} else if findIndex == len(allResourceTags[k]) {
-					// not found and insert at last
-					allResourceTags[k] = append(allResourceTags[k], mn)
-				}
+			for _, v := range values {
+				allResourceTags[k][v] = struct{}{}
 			}
 		}
 
@@ -1091,15 +1171,107 @@ func (o *OCIDatasource) GetTags(
 	})
 
 	for k, v := range allResourceTags {
+		values := make([]string, 0, len(v))
+		for value := range v {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+
 		resourceTagsList = append(resourceTagsList, models.OCIResourceTags{
 			Key:    k,
-			Values: v,
+			Values: values,
 		})
 	}
+	sort.SliceStable(resourceTagsList, func(i, j int) bool {
+		return resourceTagsList[i].Key < resourceTagsList[j].Key
+	})
 
 	return resourceTagsList
 }
 
+// compartmentSubtree returns a compartment followed by the active compartments of its subtree, from the
+// compartments of the tenancy. Only the compartment itself is returned when the compartments cannot be listed.
+// API Operation: ListCompartments
+// Permission Required: COMPARTMENT_INSPECT
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - ta: The access to the tenancy.
+//   - takey: The tenancy access key.
+//   - compartmentOCID: The OCID of the compartment.
+//
+// Returns:
+//   - []string: The OCIDs of the compartment and of its sub-compartments.
+func (o *OCIDatasource) compartmentSubtree(ctx context.Context, ta *TenancyAccess, takey string, compartmentOCID string) []string {
+	subtree := []string{compartmentOCID}
+
+	tenancyocid, err := o.FetchTenancyOCID(takey)
+	if err != nil {
+		backend.Logger.Warn("client", "compartmentSubtree", err)
+		return subtree
+	}
+
+	children := map[string][]string{}
+	req := identity.ListCompartmentsRequest{
+		CompartmentId:          common.String(tenancyocid),
+		AccessLevel:            identity.ListCompartmentsAccessLevelAny,
+		LifecycleState:         identity.CompartmentLifecycleStateActive,
+		CompartmentIdInSubtree: common.Bool(true),
+	}
+	for page := 0; page < MaxPagesToFetch; page++ {
+		res, err := ta.identityClient.ListCompartments(ctx, req)
+		if err != nil {
+			backend.Logger.Warn("client", "compartmentSubtree", "searching compartment "+compartmentOCID+" only: "+err.Error())
+			return subtree
+		}
+		for _, item := range res.Items {
+			if item.Id != nil && item.CompartmentId != nil {
+				children[*item.CompartmentId] = append(children[*item.CompartmentId], *item.Id)
+			}
+		}
+		if res.OpcNextPage == nil {
+			break
+		}
+		req.Page = res.OpcNextPage
+	}
+
+	for i := 0; i < len(subtree); i++ {
+		subtree = append(subtree, children[subtree[i]]...)
+	}
+
+	return subtree
+}
+
+// matchesSelectedTags tells whether a resource carries the selected tags: the resource needs one of the
+// selected values for each selected tag key.
+//
+// Parameters:
+//   - resourceIDsPerTag: The resource ids carrying each key=value pair.
+//   - selectedTags: The selected key=value pairs.
+//   - resourceID: The id of the resource.
+//
+// Returns:
+//   - bool: True when the resource matches, or when no tag is selected.
+func matchesSelectedTags(resourceIDsPerTag map[string]map[string]struct{}, selectedTags []string, resourceID string) bool {
+	matchedKeys := map[string]bool{}
+	for _, selectedTag := range selectedTags {
+		key := strings.SplitN(selectedTag, "=", 2)[0]
+		if _, ok := matchedKeys[key]; !ok {
+			matchedKeys[key] = false
+		}
+		if _, ok := resourceIDsPerTag[selectedTag][resourceID]; ok {
+			matchedKeys[key] = true
+		}
+	}
+	for _, matched := range matchedKeys {
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
 // GetResourceGroups Returns all the resource groups associated with mentioned namespace under the compartment of mentioned tenancy
 // API Operation: ListMetrics
 // Permission Required: METRIC_INSPECT
@@ -1138,9 +1310,23 @@ func (o *OCIDatasource) GetResourceGroups(
 		}
 	}
 
+	// concurrent calls for the same resource groups share a single upstream call
+	metricResourceGroupsList, _ := coalesceCached(ctx, o, cacheKey, func(ctx context.Context) ([]models.OCIMetricNamesWithResourceGroup, error) {
+		return o.listResourceGroups(ctx, tenancyOCID, compartmentOCID, region, namespace, cacheKey), nil
+	})
+
+	return metricResourceGroupsList
+}
+
+// listResourceGroups lists the resource groups of a namespace and caches them, see GetResourceGroups.
+func (o *OCIDatasource) listResourceGroups(ctx context.Context, tenancyOCID string, compartmentOCID string, region string, namespace string, cacheKey string) []models.OCIMetricNamesWithResourceGroup {
 	var metricResourceGroups map[string][]string
 	metricResourceGroupsList := []models.OCIMetricNamesWithResourceGroup{}
-	takey := o.GetTenancyAccessKey(tenancyOCID)
+	ta, err := o.tenancyAccessFor(o.GetTenancyAccessKey(tenancyOCID))
+	if err != nil {
+		backend.Logger.Warn("client", "GetResourceGroups", err)
+		return nil
+	}
 
 	monitoringRequest := monitoring.ListMetricsRequest{
 		CompartmentId:          common.String(compartmentOCID),
@@ -1162,7 +1348,7 @@ func (o *OCIDatasource) GetResourceGroups(
 			o.cache,
 			cacheKey,
 			constants.FETCH_FOR_RESOURCE_GROUP,
-			o.tenancyAccess[takey].monitoringClient,
+			ta,
 			monitoringRequest,
 			o.GetSubscribedRegions(ctx, tenancyOCID),
 		)
@@ -1172,7 +1358,7 @@ func (o *OCIDatasource) GetResourceGroups(
 			o.cache,
 			cacheKey,
 			constants.FETCH_FOR_RESOURCE_GROUP,
-			o.tenancyAccess[takey].monitoringClient,
+			ta.MonitoringClientForRegion(region),
 			monitoringRequest,
 		)
 	}
@@ -1250,6 +1436,16 @@ func (o *OCIDatasource) GetDimensions(
 		}
 	}
 
+	// concurrent calls for the same dimensions share a single upstream call
+	metricDimensionsList, _ := coalesceCached(ctx, o, cacheKey, func(ctx context.Context) ([]models.OCIMetricDimensions, error) {
+		return o.listDimensions(ctx, tenancyOCID, compartmentOCID, region, namespace, metricName, DimensionUse, cacheKey), nil
+	})
+
+	return metricDimensionsList
+}
+
+// listDimensions lists the dimensions of a metric and caches them, see GetDimensions.
+func (o *OCIDatasource) listDimensions(ctx context.Context, tenancyOCID string, compartmentOCID string, region string, namespace string, metricName string, DimensionUse string, cacheKey string) []models.OCIMetricDimensions {
 	var metricDimensions map[string][]string
 	metricDimensionsList := []models.OCIMetricDimensions{}
 	takey := o.GetTenancyAccessKey(tenancyOCID)
@@ -1258,6 +1454,11 @@ func (o *OCIDatasource) GetDimensions(
 		backend.Logger.Warn("client", "GetDimensions", "invalid takey")
 		return nil
 	}
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		backend.Logger.Warn("client", "GetDimensions", err)
+		return nil
+	}
 
 	monitoringRequest := monitoring.ListMetricsRequest{
 		CompartmentId:          common.String(compartmentOCID),
@@ -1279,7 +1480,7 @@ func (o *OCIDatasource) GetDimensions(
 			o.cache,
 			cacheKey,
 			DimensionUse,
-			o.tenancyAccess[takey].monitoringClient,
+			ta,
 			monitoringRequest,
 			o.GetSubscribedRegions(ctx, tenancyOCID),
 		)
@@ -1289,7 +1490,7 @@ func (o *OCIDatasource) GetDimensions(
 			o.cache,
 			cacheKey,
 			DimensionUse,
-			o.tenancyAccess[takey].monitoringClient,
+			ta.MonitoringClientForRegion(region),
 			monitoringRequest,
 		)
 	}
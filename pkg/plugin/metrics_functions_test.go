@@ -0,0 +1,182 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+// newRegionsDatasource returns a single tenancy datasource subscribed to the given regions, the requests of each
+// region being sent to its fake server. The first region is the home region the tenancy is configured with.
+func newRegionsDatasource(t *testing.T, regions []string) (*OCIDatasource, []*fakeOCI) {
+	t.Helper()
+	servers := make([]*fakeOCI, 0, len(regions))
+	subscriptions := []map[string]interface{}{}
+	for i, region := range regions {
+		servers = append(servers, newFakeOCI(t))
+		subscriptions = append(subscriptions, map[string]interface{}{
+			"regionKey":    strings.ToUpper(region[:3]),
+			"regionName":   region,
+			"status":       "READY",
+			"isHomeRegion": i == 0,
+		})
+	}
+	servers[0].handleJSON("GET /20160918/tenancies/{tenancyId}/regionSubscriptions", http.StatusOK, subscriptions)
+
+	o := newTestDatasource(t, "singletenancy")
+	o.setTenancyAccess(SingleTenancyKey, newFakeTenancyAccess(t, regions, servers))
+	return o, servers
+}
+
+// metricsDataRequest returns a request of the CpuUtilization metric of the test tenancy over the hour from t0.
+func metricsDataRequest(t0 time.Time, region string) models.MetricsDataRequest {
+	return models.MetricsDataRequest{
+		TenancyOCID: constants.DEFAULT_PROFILE,
+		Region:      region,
+		Namespace:   "oci_computeagent",
+		QueryText:   "CpuUtilization[1m].mean()",
+		Interval:    "1m",
+		RawQuery:    true,
+		StartTime:   t0,
+		EndTime:     t0.Add(time.Hour),
+	}
+}
+
+func TestGetMetricDataPointsRegionErrors(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	regions := []string{"us-ashburn-1", "eu-frankfurt-1", "ap-tokyo-1"}
+	failure := ociError("NotAuthorizedOrNotFound", "metrics not found")
+
+	t.Run("some regions failed", func(t *testing.T) {
+		o, servers := newRegionsDatasource(t, regions)
+		servers[0].handleJSON("POST "+summarizeMetricsDataPath, http.StatusNotFound, failure)
+		servers[1].handleJSON("POST "+summarizeMetricsDataPath, http.StatusOK, metricDataResponse(t0, map[string][]float64{"instance-a": {1}}))
+		servers[2].handleJSON("POST "+summarizeMetricsDataPath, http.StatusNotFound, failure)
+
+		_, dataPoints, regionErrors, err := o.GetMetricDataPoints(context.Background(), metricsDataRequest(t0, constants.ALL_REGION), constants.DEFAULT_PROFILE)
+		if err != nil {
+			t.Fatalf("GetMetricDataPoints() error = %v", err)
+		}
+		if len(dataPoints) != 1 || dataPoints[0].Region != "eu-frankfurt-1" {
+			t.Errorf("GetMetricDataPoints() = %v, want the series of eu-frankfurt-1", dataPoints)
+		}
+		if len(regionErrors) != 2 || regionErrors["us-ashburn-1"] == nil || regionErrors["ap-tokyo-1"] == nil {
+			t.Errorf("GetMetricDataPoints() region errors = %v, want us-ashburn-1 and ap-tokyo-1", regionErrors)
+		}
+	})
+
+	t.Run("every region failed", func(t *testing.T) {
+		o, servers := newRegionsDatasource(t, regions)
+		for _, server := range servers {
+			server.handleJSON("POST "+summarizeMetricsDataPath, http.StatusNotFound, failure)
+		}
+
+		_, _, _, err := o.GetMetricDataPoints(context.Background(), metricsDataRequest(t0, constants.ALL_REGION), constants.DEFAULT_PROFILE)
+		if err == nil {
+			t.Fatal("GetMetricDataPoints() error = nil, want the errors of every region")
+		}
+		message := err.Error()
+		positions := []int{}
+		for _, region := range []string{"ap-tokyo-1", "eu-frankfurt-1", "us-ashburn-1"} {
+			positions = append(positions, strings.Index(message, region+": "))
+		}
+		if !strings.HasPrefix(message, "metric data could not be fetched from any region") ||
+			positions[0] < 0 || positions[0] > positions[1] || positions[1] > positions[2] {
+			t.Errorf("GetMetricDataPoints() error = %v, want the error of every region sorted by region", message)
+		}
+	})
+
+	t.Run("single region failed", func(t *testing.T) {
+		o, servers := newRegionsDatasource(t, regions)
+		servers[1].handleJSON("POST "+summarizeMetricsDataPath, http.StatusNotFound, failure)
+
+		_, _, _, err := o.GetMetricDataPoints(context.Background(), metricsDataRequest(t0, "eu-frankfurt-1"), constants.DEFAULT_PROFILE)
+		if err == nil || strings.Contains(err.Error(), "any region") || !strings.Contains(err.Error(), "NotAuthorizedOrNotFound") {
+			t.Errorf("GetMetricDataPoints() error = %v, want the error of the region", err)
+		}
+	})
+}
+
+func TestGetMetricDataPointsTags(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	const (
+		parent = "ocid1.compartment.oc1..parent"
+		child  = "ocid1.compartment.oc1..child"
+	)
+	series := map[string][]float64{
+		"ocid1.instance.oc1..a": {1},
+		"ocid1.instance.oc1..b": {2},
+		"ocid1.instance.oc1..c": {3},
+	}
+	resources := map[string]map[string]string{
+		"ocid1.instance.oc1..a": {"env": "prod"},
+		"ocid1.instance.oc1..b": {"env": "dev"},
+		"ocid1.instance.oc1..c": {"env": "prod"},
+	}
+	compartments := map[string]string{"ocid1.instance.oc1..a": parent, "ocid1.instance.oc1..b": parent, "ocid1.instance.oc1..c": child}
+
+	t.Run("series of the tagged resources of the compartment subtree", func(t *testing.T) {
+		o, servers := newRegionsDatasource(t, []string{"us-ashburn-1"})
+		servers[0].handleJSON("POST "+summarizeMetricsDataPath, http.StatusOK, metricDataResponse(t0, series))
+		servers[0].handleJSON("POST "+searchResourcesPath, http.StatusOK, searchItems(resources, compartments))
+		servers[0].handleJSON("GET /20160918/compartments", http.StatusOK, []map[string]string{
+			{"id": parent, "compartmentId": testTenancyOCID, "name": "parent", "lifecycleState": "ACTIVE"},
+			{"id": child, "compartmentId": parent, "name": "child", "lifecycleState": "ACTIVE"},
+			{"id": "ocid1.compartment.oc1..other", "compartmentId": testTenancyOCID, "name": "other", "lifecycleState": "ACTIVE"},
+		})
+
+		request := metricsDataRequest(t0, "us-ashburn-1")
+		request.CompartmentOCID = parent
+		request.TagsValues = []string{"env=prod"}
+		_, dataPoints, _, err := o.GetMetricDataPoints(context.Background(), request, constants.DEFAULT_PROFILE)
+		if err != nil {
+			t.Fatalf("GetMetricDataPoints() error = %v", err)
+		}
+		got := map[string]bool{}
+		for _, dataPoint := range dataPoints {
+			got[dataPoint.UniqueDataID] = true
+		}
+		if want := map[string]bool{"ocid1.instance.oc1..a": true, "ocid1.instance.oc1..c": true}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetMetricDataPoints() series = %v, want %v", got, want)
+		}
+
+		wantQuery := "query instance resources where compartmentId = '" + parent + "' || compartmentId = '" + child + "'"
+		found := false
+		for _, query := range searchQueries(t, servers[0]) {
+			found = found || query == wantQuery
+		}
+		if !found {
+			t.Errorf("search queries = %v, want %v", searchQueries(t, servers[0]), wantQuery)
+		}
+	})
+
+	t.Run("tagged resources searched in no region", func(t *testing.T) {
+		regions := []string{"us-ashburn-1", "eu-frankfurt-1"}
+		o, servers := newRegionsDatasource(t, regions)
+		for _, server := range servers {
+			server.handleJSON("POST "+summarizeMetricsDataPath, http.StatusOK, metricDataResponse(t0, series))
+			server.handleJSON("POST "+searchResourcesPath, http.StatusNotFound, ociError("NotAuthorizedOrNotFound", "not authorized"))
+		}
+
+		request := metricsDataRequest(t0, constants.ALL_REGION)
+		request.TagsValues = []string{"env=prod"}
+		_, _, _, err := o.GetMetricDataPoints(context.Background(), request, constants.DEFAULT_PROFILE)
+		want := "the resources carrying the selected tags could not be searched in any region, " +
+			"eu-frankfurt-1: the resources carrying the selected tags could not be searched; " +
+			"us-ashburn-1: the resources carrying the selected tags could not be searched"
+		if err == nil || err.Error() != want {
+			t.Errorf("GetMetricDataPoints() error = %v, want %v", err, want)
+		}
+	})
+}
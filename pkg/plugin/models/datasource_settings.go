@@ -7,6 +7,9 @@ package models
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	jsoniter "github.com/json-iterator/go"
@@ -22,38 +25,104 @@ type OCIDatasourceSettings struct {
 	TenancyName   string `json:"tenancyName,omitempty"`
 	Environment   string `json:"environment"`
 
-	Profile_0 string `json:"profile0,omitempty"`
-	Region_0  string `json:"region0,omitempty"`
+	Xtenancy_0 string `json:"xtenancy0,omitempty"`
 
-	Profile_1 string `json:"profile1,omitempty"`
-	Region_1  string `json:"region1,omitempty"`
+	// Xtenancies lists the target tenancies of the principal in multitenancy mode.
+	Xtenancies []OCIXTenancySettings `json:"xtenancies,omitempty"`
 
-	Profile_2 string `json:"profile2,omitempty"`
-	Region_2  string `json:"region2,omitempty"`
+	// ConfigFilePath is the OCI CLI config file of the OCI Config File environment.
+	ConfigFilePath string `json:"configfilepath,omitempty"`
 
-	Profile_3 string `json:"profile3,omitempty"`
-	Region_3  string `json:"region3,omitempty"`
+	// Profiles lists the tenancy profiles of the User Principals environment.
+	Profiles []OCIProfileSettings `json:"profiles,omitempty"`
+}
 
-	Profile_4 string `json:"profile4,omitempty"`
-	Region_4  string `json:"region4,omitempty"`
+// OCIProfileSettings holds the non secured settings of a tenancy profile.
+// The secured settings of the profile are stored in the secure json data with an underscore and the
+// profile name as suffix: tenancy_<name>, user_<name>, fingerprint_<name>, privkey_<name>,
+// privkeypass_<name>, customdomain_<name> and token_<name>. The profiles migrated from the numbered
+// settings of previous versions keep their index as suffix instead, such as privkey0.
+type OCIProfileSettings struct {
+	// Index is the block of the numbered settings the profile was migrated from.
+	Index        *int   `json:"index,omitempty"`
+	Name         string `json:"name"`
+	Region       string `json:"region,omitempty"`
+	CustomRegion string `json:"customregion,omitempty"`
+	AuthType     string `json:"authtype,omitempty"`
+	// PrivkeySecret is the OCID of an OCI Vault secret holding the private key, used instead of privkey<index>.
+	PrivkeySecret string `json:"privkeysecret,omitempty"`
+}
 
-	Profile_5 string `json:"profile5,omitempty"`
-	Region_5  string `json:"region5,omitempty"`
+// OCIXTenancySettings holds a target tenancy reached by a principal through cross tenancy policies.
+type OCIXTenancySettings struct {
+	Name string `json:"name,omitempty"`
+	OCID string `json:"ocid"`
+}
 
-	Xtenancy_0 string `json:"xtenancy0,omitempty"`
+// SecureKey returns the key of a secured setting of the profile in the secure json data. The key never
+// depends on the position of the profile in the list, so reordering or removing profiles keeps the
+// secured settings of the others.
+func (p OCIProfileSettings) SecureKey(field string) string {
+	if p.Index != nil {
+		return field + strconv.Itoa(*p.Index)
+	}
+	return field + "_" + p.Name
+}
+
+// legacyProfiles reads the tenancy profiles from the numbered settings used by previous
+// versions of the plugin (profile0, region0, customregion0, authtype0, profile1, ...).
+// Every numbered block with a profile name is read, in block order, even after a removed block.
+// The block number becomes the profile index so that the existing secured settings are still found.
+//
+// Parameters:
+// - jsonData: The raw JSONData of the datasource.
+//
+// Returns:
+// - []OCIProfileSettings: The migrated profiles.
+// - error: An error if the JSONData could not be unmarshalled.
+func legacyProfiles(jsonData []byte) ([]OCIProfileSettings, error) {
+	legacy := map[string]interface{}{}
+	if err := jsoniter.Unmarshal(jsonData, &legacy); err != nil {
+		return nil, err
+	}
+	setting := func(name string, block int) string {
+		value, _ := legacy[name+strconv.Itoa(block)].(string)
+		return value
+	}
+
+	var blocks []int
+	for key := range legacy {
+		number, ok := strings.CutPrefix(key, "profile")
+		if !ok {
+			continue
+		}
+		if block, err := strconv.Atoi(number); err == nil && block >= 0 && strconv.Itoa(block) == number && setting("profile", block) != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	sort.Ints(blocks)
+
+	var profiles []OCIProfileSettings
+	for _, block := range blocks {
+		name := setting("profile", block)
+		index := block
+		profiles = append(profiles, OCIProfileSettings{
+			Index:        &index,
+			Name:         name,
+			Region:       setting("region", block),
+			CustomRegion: setting("customregion", block),
+			AuthType:     setting("authtype", block),
+		})
+	}
 
-	CustomRegion_0 string `json:"customregion0,omitempty"`
-	CustomRegion_1 string `json:"customregion1,omitempty"`
-	CustomRegion_2 string `json:"customregion2,omitempty"`
-	CustomRegion_3 string `json:"customregion3,omitempty"`
-	CustomRegion_4 string `json:"customregion4,omitempty"`
-	CustomRegion_5 string `json:"customregion5,omitempty"`
+	return profiles, nil
 }
 
 // Load initializes the OCIDatasourceSettings from the provided backend.DataSourceInstanceSettings.
 // It unmarshals the JSONData from the DataSourceInstanceSettings into the OCIDatasourceSettings struct.
 // If the JSONData is not nil and has more than one element, it attempts to unmarshal it.
 // If unmarshalling fails, it returns an error indicating the failure.
+// Settings saved with the numbered profile fields of previous versions are migrated to the Profiles list.
 // Additionally, it sets the ConfigProfile to the default instance profile.
 //
 // Parameters:
@@ -68,6 +137,11 @@ func (d *OCIDatasourceSettings) Load(dsiSettings backend.DataSourceInstanceSetti
 		if err = jsoniter.Unmarshal(dsiSettings.JSONData, d); err != nil {
 			return fmt.Errorf("could not unmarshal OCIDatasourceSettings json: %w", err)
 		}
+		if len(d.Profiles) == 0 {
+			if d.Profiles, err = legacyProfiles(dsiSettings.JSONData); err != nil {
+				return fmt.Errorf("could not migrate OCIDatasourceSettings profiles: %w", err)
+			}
+		}
 	}
 
 	// in case of instance principle auth provider
@@ -0,0 +1,117 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestLegacyProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonData string
+		want     []OCIProfileSettings
+	}{
+		{name: "no profile", jsonData: `{"tenancymode": "multitenancy"}`, want: nil},
+		{
+			name:     "consecutive blocks",
+			jsonData: `{"profile0": "DEFAULT", "region0": "us-ashburn-1", "profile1": "other", "region1": "eu-frankfurt-1", "customregion1": "custom", "authtype1": "security_token"}`,
+			want: []OCIProfileSettings{
+				{Index: intPtr(0), Name: "DEFAULT", Region: "us-ashburn-1"},
+				{Index: intPtr(1), Name: "other", Region: "eu-frankfurt-1", CustomRegion: "custom", AuthType: "security_token"},
+			},
+		},
+		{
+			name:     "removed blocks",
+			jsonData: `{"profile0": "DEFAULT", "profile1": "", "profile3": "third", "region3": "ap-tokyo-1", "profile12": "twelfth"}`,
+			want: []OCIProfileSettings{
+				{Index: intPtr(0), Name: "DEFAULT"},
+				{Index: intPtr(3), Name: "third", Region: "ap-tokyo-1"},
+				{Index: intPtr(12), Name: "twelfth"},
+			},
+		},
+		{
+			name:     "first block removed",
+			jsonData: `{"profile2": "second", "profilename": "ignored", "profile02": "ignored", "profile-1": "ignored"}`,
+			want: []OCIProfileSettings{
+				{Index: intPtr(2), Name: "second"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := legacyProfiles([]byte(tt.jsonData))
+			if err != nil {
+				t.Fatalf("legacyProfiles() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("legacyProfiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOCIDatasourceSettingsLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonData string
+		want     []OCIProfileSettings
+	}{
+		{
+			name:     "profiles list",
+			jsonData: `{"profiles": [{"name": "DEFAULT", "region": "us-ashburn-1"}], "profile0": "legacy"}`,
+			want:     []OCIProfileSettings{{Name: "DEFAULT", Region: "us-ashburn-1"}},
+		},
+		{
+			name:     "numbered settings migrated",
+			jsonData: `{"profile0": "DEFAULT", "region0": "us-ashburn-1", "profile2": "other"}`,
+			want: []OCIProfileSettings{
+				{Index: intPtr(0), Name: "DEFAULT", Region: "us-ashburn-1"},
+				{Index: intPtr(2), Name: "other"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &OCIDatasourceSettings{}
+			if err := settings.Load(backend.DataSourceInstanceSettings{JSONData: []byte(tt.jsonData)}); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !reflect.DeepEqual(settings.Profiles, tt.want) {
+				t.Errorf("Load() profiles = %v, want %v", settings.Profiles, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecureKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile OCIProfileSettings
+		want    string
+	}{
+		{name: "profile by name", profile: OCIProfileSettings{Name: "DEFAULT"}, want: "privkey_DEFAULT"},
+		{name: "migrated profile by index", profile: OCIProfileSettings{Index: intPtr(3), Name: "DEFAULT"}, want: "privkey3"},
+		{name: "first migrated profile", profile: OCIProfileSettings{Index: intPtr(0), Name: "other"}, want: "privkey0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.SecureKey("privkey"); got != tt.want {
+				t.Errorf("SecureKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@
 
 package models
 
+import "time"
+
 // OCIResource represents a generic OCI resource with a name and OCID.
 type OCIResource struct {
 	// Name is the display name of the OCI resource.
@@ -61,10 +63,12 @@ type OCIMetricDataPoints struct {
 	UniqueDataID string
 	// DimensionKey is the key of the dimension used to identify the resource.
 	DimensionKey string
-	// DataPoints is a list of float64 values representing the metric data points.
-	DataPoints []float64
+	// DataPoints is a list of float64 values representing the metric data points, nil where the series has no datapoint.
+	DataPoints []*float64
 	// Labels is a map of string to string representing the labels for the metric data.
 	Labels map[string]string
+	// Metadata is the metadata returned with the metric data, such as its unit and display name.
+	Metadata map[string]string
 }
 
 // OCIResourceTagsResponse represents the response structure for OCI resource tags.
@@ -78,3 +82,99 @@ type OCIResourceTagsResponse struct {
 	// FreeFormTags is a map of free-form tag keys to their values.
 	FreeFormTags map[string]string
 }
+
+// OCIAlarmTransition represents a state transition of an OCI Monitoring alarm.
+type OCIAlarmTransition struct {
+	// AlarmID is the OCID of the alarm.
+	AlarmID string
+	// AlarmName is the display name of the alarm.
+	AlarmName string
+	// Severity is the severity of the alarm (CRITICAL, ERROR, WARNING, INFO).
+	Severity string
+	// State is the state the alarm transitioned to (FIRING, OK).
+	State string
+	// Summary is the description of the transition.
+	Summary string
+	// AlarmSummary is the customizable summary of the alarm.
+	AlarmSummary string
+	// Dimensions are the dimensions filtered by the alarm query.
+	Dimensions map[string]string
+	// Region is the OCI region of the alarm.
+	Region string
+	// Time is the time of the transition.
+	Time time.Time
+}
+
+// OCIAlarmStatus represents the current status of an OCI Monitoring alarm.
+type OCIAlarmStatus struct {
+	// AlarmID is the OCID of the alarm.
+	AlarmID string
+	// AlarmName is the display name of the alarm.
+	AlarmName string
+	// Severity is the severity of the alarm (CRITICAL, ERROR, WARNING, INFO).
+	Severity string
+	// Namespace is the metric namespace of the alarm query.
+	Namespace string
+	// Query is the MQL query of the alarm.
+	Query string
+	// State is the status of the alarm (FIRING, OK, SUSPENDED).
+	State string
+	// Suppression describes the alarm-wide suppression, empty when the alarm is not suppressed.
+	Suppression string
+	// Region is the OCI region of the alarm.
+	Region string
+	// Time is the time the alarm entered its current state.
+	Time time.Time
+}
+
+// OCIAuditEvent represents an event recorded by the OCI Audit service.
+type OCIAuditEvent struct {
+	// EventID is the unique identifier of the event.
+	EventID string
+	// EventType is the type of the event, e.g. com.oraclecloud.computeApi.LaunchInstance.begin.
+	EventType string
+	// EventName is the name of the API operation, e.g. LaunchInstance.
+	EventName string
+	// Source is the service that produced the event.
+	Source string
+	// CompartmentName is the name of the compartment of the resource.
+	CompartmentName string
+	// ResourceName is the name of the resource affected by the event.
+	ResourceName string
+	// ResourceID is the OCID of the resource affected by the event.
+	ResourceID string
+	// PrincipalName is the name of the user or service principal that made the request.
+	PrincipalName string
+	// IPAddress is the IP address the request was made from.
+	IPAddress string
+	// Action is the HTTP method of the request.
+	Action string
+	// Path is the path of the request.
+	Path string
+	// Status is the HTTP status of the response.
+	Status string
+	// Message is the message of the response.
+	Message string
+	// Region is the OCI region of the event.
+	Region string
+	// Time is the time of the event.
+	Time time.Time
+}
+
+// OCIResourceInfo represents the metadata of a resource found with the Resource Search service.
+type OCIResourceInfo struct {
+	// ResourceID is the OCID of the resource.
+	ResourceID string
+	// ResourceType is the type of the resource, e.g. Instance.
+	ResourceType string
+	// DisplayName is the display name of the resource.
+	DisplayName string
+	// CompartmentID is the OCID of the compartment of the resource.
+	CompartmentID string
+	// CompartmentName is the name of the compartment of the resource.
+	CompartmentName string
+	// FreeformTags are the freeform tags of the resource.
+	FreeformTags map[string]string
+	// DefinedTags are the defined tags of the resource, keyed by tag namespace then tag key.
+	DefinedTags map[string]map[string]string
+}
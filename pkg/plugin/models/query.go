@@ -20,6 +20,12 @@ type QueryModel struct {
 	Statistic       string   `json:"statistic"`
 	LegendFormat    string   `json:"legendFormat"`
 	ResourceGroup   string   `json:"resourcegroup,omitempty"`
+	GroupBy         string   `json:"groupBy,omitempty"`
+	FillMode        string   `json:"fillMode,omitempty"`
+	FrameFormat     string   `json:"frameFormat,omitempty"`
 	DimensionValues []string `json:"dimensionValues,omitempty"`
 	TagsValues      []string `json:"tagsValues,omitempty"`
+	AlarmName       string   `json:"alarmName,omitempty"`
+	EventType       string   `json:"eventType,omitempty"`
+	Principal       string   `json:"principal,omitempty"`
 }
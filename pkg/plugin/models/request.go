@@ -16,6 +16,7 @@ type MetricsDataRequest struct {
 	Namespace       string
 	QueryText       string
 	Interval        string
+	FillMode        string
 	ResourceGroup   string
 	LegendFormat    string
 	RawQuery        bool
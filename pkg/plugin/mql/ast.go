@@ -0,0 +1,206 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package mql
+
+import (
+	"strings"
+)
+
+// Node is a node of the syntax tree of a MQL query.
+type Node interface {
+	// Pos returns the offset of the node in the query text it was parsed from.
+	Pos() int
+	// String returns the MQL text of the node.
+	String() string
+}
+
+// MetricQuery selects the aggregated datapoints of a metric, such as
+// CpuUtilization[1m]{resourceId = "ocid1..."}.groupBy(availabilityDomain).mean().
+type MetricQuery struct {
+	// Metric is the name of the metric.
+	Metric string
+	// Interval is the aggregation window, such as 1m or auto, without brackets.
+	Interval string
+	// Dimensions are the dimension filters, all of them must match.
+	Dimensions []DimensionFilter
+	// Functions are the grouping and statistic functions, in the order of the query.
+	Functions []Function
+
+	pos         int
+	intervalPos int
+	end         int
+}
+
+// DimensionFilter filters the datapoints on the value of a dimension, such as resourceId = "ocid1...".
+type DimensionFilter struct {
+	// Name is the name of the dimension.
+	Name string
+	// Operator is one of =, !=, =~ and !~.
+	Operator string
+	// Value is the value of the dimension, unquoted.
+	Value string
+
+	pos int
+}
+
+// Function is a grouping or statistic function applied to a metric query, such as groupBy(poolId) or percentile(.90).
+type Function struct {
+	// Name is the name of the function.
+	Name string
+	// Args are the arguments of the function, as written in the query.
+	Args []string
+
+	pos int
+}
+
+// BinaryExpr combines two expressions with an arithmetic, comparison or boolean operator.
+type BinaryExpr struct {
+	// Op is the operator, such as +, >, == or &&.
+	Op  string
+	LHS Node
+	RHS Node
+
+	pos int
+}
+
+// UnaryExpr negates an expression.
+type UnaryExpr struct {
+	// Op is the operator, -.
+	Op   string
+	Expr Node
+
+	pos int
+}
+
+// ParenExpr is an expression in parentheses.
+type ParenExpr struct {
+	Expr Node
+
+	pos int
+}
+
+// RangeExpr tests whether an expression is within a range, such as CpuUtilization[1m].mean() in (0, 50).
+type RangeExpr struct {
+	Expr Node
+	// Not is true for not in ranges.
+	Not  bool
+	Low  Node
+	High Node
+
+	pos int
+}
+
+// NumberLiteral is a number, such as 85 or .90.
+type NumberLiteral struct {
+	// Value is the number as written in the query.
+	Value string
+
+	pos int
+}
+
+// Pos returns the offset of the metric query.
+func (q *MetricQuery) Pos() int { return q.pos }
+
+// Pos returns the offset of the filter.
+func (d DimensionFilter) Pos() int { return d.pos }
+
+// Pos returns the offset of the function name.
+func (f Function) Pos() int { return f.pos }
+
+// Pos returns the offset of the operator.
+func (e *BinaryExpr) Pos() int { return e.pos }
+
+// Pos returns the offset of the operator.
+func (e *UnaryExpr) Pos() int { return e.pos }
+
+// Pos returns the offset of the opening parenthesis.
+func (e *ParenExpr) Pos() int { return e.pos }
+
+// Pos returns the offset of the in keyword, or of not.
+func (e *RangeExpr) Pos() int { return e.pos }
+
+// Pos returns the offset of the number.
+func (n *NumberLiteral) Pos() int { return n.pos }
+
+// String returns the metric query as Metric[interval]{dimensions}.functions.
+func (q *MetricQuery) String() string {
+	var b strings.Builder
+	b.WriteString(q.Metric)
+	b.WriteString("[" + q.Interval + "]")
+	if len(q.Dimensions) > 0 {
+		filters := make([]string, 0, len(q.Dimensions))
+		for _, d := range q.Dimensions {
+			filters = append(filters, d.String())
+		}
+		b.WriteString("{" + strings.Join(filters, ", ") + "}")
+	}
+	for _, f := range q.Functions {
+		b.WriteString("." + f.String())
+	}
+
+	return b.String()
+}
+
+// String returns the filter as name operator "value".
+func (d DimensionFilter) String() string {
+	return d.Name + " " + d.Operator + " " + QuoteString(d.Value)
+}
+
+// String returns the function as name(args).
+func (f Function) String() string {
+	return f.Name + "(" + strings.Join(f.Args, ", ") + ")"
+}
+
+// String returns the expression as lhs op rhs.
+func (e *BinaryExpr) String() string {
+	return e.LHS.String() + " " + e.Op + " " + e.RHS.String()
+}
+
+// String returns the expression as op expr.
+func (e *UnaryExpr) String() string {
+	return e.Op + e.Expr.String()
+}
+
+// String returns the expression as (expr).
+func (e *ParenExpr) String() string {
+	return "(" + e.Expr.String() + ")"
+}
+
+// String returns the expression as expr in (low, high).
+func (e *RangeExpr) String() string {
+	op := " in "
+	if e.Not {
+		op = " not in "
+	}
+	return e.Expr.String() + op + "(" + e.Low.String() + ", " + e.High.String() + ")"
+}
+
+// String returns the number as written in the query.
+func (n *NumberLiteral) String() string {
+	return n.Value
+}
+
+// Walk calls fn for the node and each of its descendants, depth first. The descendants of a node are
+// skipped when fn returns false for it.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *BinaryExpr:
+		Walk(n.LHS, fn)
+		Walk(n.RHS, fn)
+	case *UnaryExpr:
+		Walk(n.Expr, fn)
+	case *ParenExpr:
+		Walk(n.Expr, fn)
+	case *RangeExpr:
+		Walk(n.Expr, fn)
+		Walk(n.Low, fn)
+		Walk(n.High, fn)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package mql
+
+import "fmt"
+
+// Error is a syntax or validation error of a MQL query, located in the query text.
+type Error struct {
+	// Message describes the error.
+	Message string `json:"message"`
+	// Position is the byte offset of the error in the query text, starting at 0.
+	Position int `json:"position"`
+	// Line is the line of the error, starting at 1.
+	Line int `json:"line"`
+	// Column is the column of the error in its line, in characters, starting at 1.
+	Column int `json:"column"`
+}
+
+// Error returns the message of the error followed by its location.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Line, e.Column)
+}
+
+// newError returns an error located at the given offset of the query text.
+func newError(text string, pos int, format string, args ...interface{}) *Error {
+	if pos > len(text) {
+		pos = len(text)
+	}
+
+	line, column := 1, 1
+	for _, r := range text[:pos] {
+		if r == '\n' {
+			line++
+			column = 1
+			continue
+		}
+		column++
+	}
+
+	return &Error{
+		Message:  fmt.Sprintf(format, args...),
+		Position: pos,
+		Line:     line,
+		Column:   column,
+	}
+}
@@ -0,0 +1,159 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package mql
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a token of a MQL query.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+// token is a lexical token of a MQL query.
+type token struct {
+	kind tokenKind
+	// text is the text of the token, the unquoted value for strings.
+	text string
+	// pos and end are the offsets of the token in the query text.
+	pos int
+	end int
+}
+
+// symbols are the operators and punctuation of MQL, the two characters ones first.
+var symbols = []string{
+	"!=", "=~", "!~", "==", ">=", "<=", "&&", "||",
+	"=", ">", "<", "+", "-", "*", "/", "%", "(", ")", "[", "]", "{", "}", ",", ".",
+}
+
+// lex splits a MQL query into tokens, the last token being tokenEOF.
+func lex(text string) ([]token, error) {
+	tokens := []token{}
+	pos := 0
+
+	for pos < len(text) {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+
+		case isIdentStart(r):
+			end := pos + size
+			for end < len(text) {
+				next, nextSize := utf8.DecodeRuneInString(text[end:])
+				if !isIdentPart(next) {
+					break
+				}
+				end += nextSize
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: text[pos:end], pos: pos, end: end})
+			pos = end
+
+		case isDigit(r) || (r == '.' && pos+1 < len(text) && isDigit(rune(text[pos+1]))):
+			end := pos
+			for end < len(text) && isDigit(rune(text[end])) {
+				end++
+			}
+			if end < len(text) && text[end] == '.' {
+				end++
+				for end < len(text) && isDigit(rune(text[end])) {
+					end++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text[pos:end], pos: pos, end: end})
+			pos = end
+
+		case r == '"':
+			var value strings.Builder
+			end := pos + 1
+			closed := false
+			for end < len(text) {
+				c := text[end]
+				if c == '\\' && end+1 < len(text) {
+					value.WriteByte(text[end+1])
+					end += 2
+					continue
+				}
+				end++
+				if c == '"' {
+					closed = true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return nil, newError(text, pos, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value.String(), pos: pos, end: end})
+			pos = end
+
+		case r == '\'':
+			return nil, newError(text, pos, "strings must be enclosed in double quotes")
+
+		default:
+			symbol := ""
+			for _, s := range symbols {
+				if strings.HasPrefix(text[pos:], s) {
+					symbol = s
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, newError(text, pos, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol, pos: pos, end: pos + len(symbol)})
+			pos += len(symbol)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(text), end: len(text)}), nil
+}
+
+// isIdentStart tells whether a character can start a name: metric, dimension or function.
+// Names may start with $ so that template variables are accepted.
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '$'
+}
+
+// isIdentPart tells whether a character can be part of a name. A '-' is lexed as a symbol, the parser joins it
+// to the dimension names, see parseHyphenated.
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
+
+// isDigit tells whether a character is an ASCII digit.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// QuoteString returns a value as a MQL string literal: in double quotes, with only the double quotes and the
+// backslashes escaped, as read back by lex.
+func QuoteString(value string) string {
+	return `"` + stringEscaper.Replace(value) + `"`
+}
+
+// stringEscaper escapes the characters that cannot be written as is in a MQL string.
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// describe returns the token as written in the error messages.
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return "string \"" + t.text + "\""
+	}
+	return "'" + t.text + "'"
+}
@@ -0,0 +1,390 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package mql
+
+import (
+	"strings"
+)
+
+// precedences of the binary operators, comparisons being non associative.
+var precedences = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, ">": 3, ">=": 3, "<": 3, "<=": 3,
+	"+": 4, "-": 4,
+	"*": 5, "/": 5, "%": 5,
+}
+
+// comparisonPrecedence is the precedence of the comparisons and of the in and not in ranges.
+const comparisonPrecedence = 3
+
+// parser is a recursive descent parser of MQL queries.
+type parser struct {
+	text   string
+	tokens []token
+	i      int
+}
+
+// Parse parses a MQL query into its syntax tree. Only the syntax is checked, see Validate.
+//
+// Parameters:
+//   - text: The MQL query.
+//
+// Returns:
+//   - Node: The root of the syntax tree.
+//   - error: A *Error locating the first syntax error.
+func Parse(text string) (Node, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, newError(text, 0, "the query is empty")
+	}
+
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{text: text, tokens: tokens}
+	node, err := p.parseExpr(1)
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, p.errorAt(next, "unexpected %s", next.describe())
+	}
+
+	return node, nil
+}
+
+// peek returns the current token.
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+// peekAt returns the token at the given distance from the current one, tokenEOF past the end.
+func (p *parser) peekAt(distance int) token {
+	if p.i+distance >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.i+distance]
+}
+
+// next returns the current token and moves to the following one.
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// isSymbol tells whether a token is the given symbol.
+func isSymbol(t token, symbol string) bool {
+	return t.kind == tokenSymbol && t.text == symbol
+}
+
+// isKeyword tells whether a token is the given keyword, in, not.
+func isKeyword(t token, keyword string) bool {
+	return t.kind == tokenIdent && t.text == keyword
+}
+
+// expect moves past the given symbol, failing with the description of what was expected.
+func (p *parser) expect(symbol string, what string) (token, error) {
+	t := p.peek()
+	if !isSymbol(t, symbol) {
+		return t, p.errorAt(t, "expected %s, found %s", what, t.describe())
+	}
+	return p.next(), nil
+}
+
+// errorAt returns an error located at the token.
+func (p *parser) errorAt(t token, format string, args ...interface{}) error {
+	return newError(p.text, t.pos, format, args...)
+}
+
+// parseExpr parses the binary expressions whose operators have at least the given precedence.
+func (p *parser) parseExpr(minPrecedence int) (Node, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	comparisons := 0
+	for {
+		t := p.peek()
+
+		// in and not in ranges
+		if comparisonPrecedence >= minPrecedence && (isKeyword(t, "in") || (isKeyword(t, "not") && isKeyword(p.peekAt(1), "in"))) {
+			if comparisons++; comparisons > 1 {
+				return nil, p.errorAt(t, "comparisons cannot be chained, use && or ||")
+			}
+			lhs, err = p.parseRange(lhs)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		precedence, ok := precedences[t.text]
+		if t.kind != tokenSymbol || !ok || precedence < minPrecedence {
+			return lhs, nil
+		}
+		if precedence == comparisonPrecedence {
+			if comparisons++; comparisons > 1 {
+				return nil, p.errorAt(t, "comparisons cannot be chained, use && or ||")
+			}
+		}
+		p.next()
+
+		rhs, err := p.parseExpr(precedence + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: t.text, LHS: lhs, RHS: rhs, pos: t.pos}
+	}
+}
+
+// parseRange parses the in (low, high) or not in (low, high) range following an expression.
+func (p *parser) parseRange(expr Node) (Node, error) {
+	start := p.next()
+	not := false
+	if isKeyword(start, "not") {
+		not = true
+		p.next()
+	}
+
+	if _, err := p.expect("(", "'(' and the range"); err != nil {
+		return nil, err
+	}
+	low, err := p.parseExpr(comparisonPrecedence + 1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(",", "',' between the bounds of the range"); err != nil {
+		return nil, err
+	}
+	high, err := p.parseExpr(comparisonPrecedence + 1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(")", "')' closing the range"); err != nil {
+		return nil, err
+	}
+
+	return &RangeExpr{Expr: expr, Not: not, Low: low, High: high, pos: start.pos}, nil
+}
+
+// parseUnary parses a negated expression or a primary one.
+func (p *parser) parseUnary() (Node, error) {
+	t := p.peek()
+	if isSymbol(t, "-") {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: "-", Expr: expr, pos: t.pos}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a number, an expression in parentheses or a metric query.
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenNumber:
+		p.next()
+		return &NumberLiteral{Value: t.text, pos: t.pos}, nil
+
+	case isSymbol(t, "("):
+		p.next()
+		expr, err := p.parseExpr(1)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")", "')'"); err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Expr: expr, pos: t.pos}, nil
+
+	case t.kind == tokenIdent:
+		return p.parseMetricQuery()
+
+	case t.kind == tokenEOF:
+		return nil, p.errorAt(t, "unexpected end of query, expected a metric")
+	}
+
+	return nil, p.errorAt(t, "expected a metric, a number or '(', found %s", t.describe())
+}
+
+// parseName parses a name made of identifiers separated by dots. Dimension names may also contain '-', such as
+// resource-id, while in a metric name a '-' is the subtraction. A metric name stops before a dot followed by a
+// function call.
+func (p *parser) parseName(what string, dimension bool) (string, token, error) {
+	first := p.peek()
+	if first.kind != tokenIdent {
+		return "", first, p.errorAt(first, "expected %s, found %s", what, first.describe())
+	}
+	p.next()
+
+	name := first.text
+	if dimension {
+		name += p.parseHyphenated()
+	}
+	for isSymbol(p.peek(), ".") && p.peekAt(1).kind == tokenIdent && p.peekAt(1).pos == p.peek().end {
+		if !dimension && isSymbol(p.peekAt(2), "(") {
+			break
+		}
+		p.next()
+		name += "." + p.next().text
+		if dimension {
+			name += p.parseHyphenated()
+		}
+	}
+
+	return name, first, nil
+}
+
+// parseHyphenated returns the '-' and the identifiers or numbers written right after the previous token, without
+// spaces, such as the -id of resource-id.
+func (p *parser) parseHyphenated() string {
+	var b strings.Builder
+	for {
+		previous := p.tokens[p.i-1]
+		hyphen, part := p.peek(), p.peekAt(1)
+		if !isSymbol(hyphen, "-") || hyphen.pos != previous.end ||
+			(part.kind != tokenIdent && part.kind != tokenNumber) || part.pos != hyphen.end {
+			return b.String()
+		}
+		p.next()
+		p.next()
+		b.WriteString("-" + part.text)
+	}
+}
+
+// parseMetricQuery parses Metric[interval]{dimensions}.functions.
+func (p *parser) parseMetricQuery() (Node, error) {
+	metric, start, err := p.parseName("a metric", false)
+	if err != nil {
+		return nil, err
+	}
+	query := &MetricQuery{Metric: metric, pos: start.pos}
+
+	// the interval is taken as written, it is checked by Validate
+	open := p.peek()
+	if !isSymbol(open, "[") {
+		return nil, p.errorAt(open, "expected '[' and the interval after the metric %s, found %s", metric, open.describe())
+	}
+	p.next()
+	for !isSymbol(p.peek(), "]") {
+		if p.peek().kind == tokenEOF {
+			return nil, p.errorAt(open, "the interval is not closed with ']'")
+		}
+		p.next()
+	}
+	closing := p.next()
+	query.Interval = strings.TrimSpace(p.text[open.end:closing.pos])
+	query.intervalPos = open.end
+	if query.Interval == "" {
+		return nil, p.errorAt(closing, "the interval is empty, expected a value such as [1m] or [auto]")
+	}
+
+	if isSymbol(p.peek(), "{") {
+		if query.Dimensions, err = p.parseDimensions(); err != nil {
+			return nil, err
+		}
+	}
+
+	for isSymbol(p.peek(), ".") {
+		p.next()
+		function, err := p.parseFunction()
+		if err != nil {
+			return nil, err
+		}
+		query.Functions = append(query.Functions, function)
+	}
+	query.end = p.tokens[p.i-1].end
+
+	return query, nil
+}
+
+// parseDimensions parses {name operator value, ...}.
+func (p *parser) parseDimensions() ([]DimensionFilter, error) {
+	p.next()
+
+	dimensions := []DimensionFilter{}
+	for !isSymbol(p.peek(), "}") {
+		name, start, err := p.parseName("a dimension name", true)
+		if err != nil {
+			return nil, err
+		}
+
+		op := p.next()
+		if op.kind != tokenSymbol || (op.text != "=" && op.text != "!=" && op.text != "=~" && op.text != "!~") {
+			return nil, p.errorAt(op, "expected a dimension operator (=, !=, =~ or !~) after %s, found %s", name, op.describe())
+		}
+
+		value := p.next()
+		if value.kind != tokenString && value.kind != tokenIdent && value.kind != tokenNumber {
+			return nil, p.errorAt(value, "expected the value of the dimension %s, found %s", name, value.describe())
+		}
+		valueText := value.text
+		if value.kind != tokenString {
+			// unquoted values such as US-ASHBURN-AD-1
+			valueText += p.parseHyphenated()
+		}
+		dimensions = append(dimensions, DimensionFilter{Name: name, Operator: op.text, Value: valueText, pos: start.pos})
+
+		separator := p.peek()
+		if isSymbol(separator, ",") {
+			p.next()
+			continue
+		}
+		if !isSymbol(separator, "}") {
+			return nil, p.errorAt(separator, "expected ',' or '}' after the dimension %s, found %s", name, separator.describe())
+		}
+	}
+	p.next()
+
+	return dimensions, nil
+}
+
+// parseFunction parses name(args), the arguments being taken as written.
+func (p *parser) parseFunction() (Function, error) {
+	name := p.peek()
+	if name.kind != tokenIdent {
+		return Function{}, p.errorAt(name, "expected a function after '.', found %s", name.describe())
+	}
+	p.next()
+	open, err := p.expect("(", "'(' after the function "+name.text)
+	if err != nil {
+		return Function{}, err
+	}
+
+	function := Function{Name: name.text, Args: []string{}, pos: name.pos}
+	argStart := open.end
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokenEOF:
+			return Function{}, p.errorAt(open, "the function %s is not closed with ')'", name.text)
+		case isSymbol(t, "("):
+			return Function{}, p.errorAt(t, "unexpected '(' in the arguments of %s", name.text)
+		case isSymbol(t, ",") || isSymbol(t, ")"):
+			arg := strings.TrimSpace(p.text[argStart:t.pos])
+			if arg == "" && (isSymbol(t, ",") || len(function.Args) > 0) {
+				return Function{}, p.errorAt(t, "missing argument in %s", name.text)
+			}
+			if arg != "" {
+				function.Args = append(function.Args, arg)
+			}
+			if isSymbol(t, ")") {
+				return function, nil
+			}
+			argStart = t.end
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package mql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "metric", query: `CpuUtilization[1m].mean()`, want: `CpuUtilization[1m].mean()`},
+		{
+			name:  "dimensions and grouping",
+			query: `CpuUtilization[1m]{resourceId = "ocid1.instance.oc1..a", availabilityDomain != "AD-1"}.groupBy(poolId).mean()`,
+			want:  `CpuUtilization[1m]{resourceId = "ocid1.instance.oc1..a", availabilityDomain != "AD-1"}.groupBy(poolId).mean()`,
+		},
+		{
+			name:  "spacing normalized",
+			query: `CpuUtilization[ 5m ]{resource-id="a"}.percentile( .90 )`,
+			want:  `CpuUtilization[5m]{resource-id = "a"}.percentile(.90)`,
+		},
+		{name: "dotted metric name", query: `oci.custom.Metric[1m].mean()`, want: `oci.custom.Metric[1m].mean()`},
+		{name: "subtraction without spaces", query: `A[1m].mean()-B[1m].mean()`, want: `A[1m].mean() - B[1m].mean()`},
+		{name: "negation", query: `-A[1m].mean()`, want: `-A[1m].mean()`},
+		{
+			name:  "conditions",
+			query: `(A[1m].max() + 1) * 2 > 80 && B[1m].mean() not in (0, 5)`,
+			want:  `(A[1m].max() + 1) * 2 > 80 && B[1m].mean() not in (0, 5)`,
+		},
+		{name: "escaped string", query: `A[1m]{d = "a\"b\\c"}.mean()`, want: `A[1m]{d = "a\"b\\c"}.mean()`},
+		{name: "unquoted value", query: `A[1m]{ad = US-ASHBURN-AD-1}.mean()`, want: `A[1m]{ad = "US-ASHBURN-AD-1"}.mean()`},
+		{name: "non ASCII value kept as is", query: "A[1m]{d = \"é\t\"}.mean()", want: "A[1m]{d = \"é\t\"}.mean()"},
+		{name: "template variables", query: `A[$interval]{d =~ "$value"}.mean()`, want: `A[$interval]{d =~ "$value"}.mean()`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := node.String(); got != tt.want {
+				t.Fatalf("Parse().String() = %v, want %v", got, tt.want)
+			}
+
+			// the text of the tree parses back into the same tree
+			again, err := Parse(node.String())
+			if err != nil {
+				t.Fatalf("Parse() of %v error = %v", node.String(), err)
+			}
+			if again.String() != tt.want {
+				t.Errorf("Parse().String() of %v = %v", tt.want, again.String())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		message  string
+		position int
+		line     int
+		column   int
+	}{
+		{name: "empty", query: "  ", message: "the query is empty", position: 0, line: 1, column: 1},
+		{name: "single quotes", query: `A[1m].mean() 'x'`, message: "strings must be enclosed in double quotes", position: 13, line: 1, column: 14},
+		{name: "unterminated string", query: `A[1m]{d = "x}.mean()`, message: "unterminated string", position: 10, line: 1, column: 11},
+		{name: "unexpected character", query: `A[1m]{d ~ "x"}.mean()`, message: "unexpected character '~'", position: 8, line: 1, column: 9},
+		{name: "chained comparisons", query: `A[1m].mean() > 80 > 90`, message: "comparisons cannot be chained", position: 18, line: 1, column: 19},
+		{name: "missing interval", query: `A.mean()`, message: "expected '[' and the interval after the metric A", position: 1, line: 1, column: 2},
+		{name: "interval not closed", query: `A[1m`, message: "the interval is not closed", position: 1, line: 1, column: 2},
+		{name: "empty interval", query: `A[].mean()`, message: "the interval is empty", position: 2, line: 1, column: 3},
+		{name: "missing separator", query: `A[1m]{d = "x" e = "y"}.mean()`, message: "expected ',' or '}' after the dimension d", position: 14, line: 1, column: 15},
+		{name: "missing operator", query: `A[1m]{d "x"}.mean()`, message: "expected a dimension operator", position: 8, line: 1, column: 9},
+		{name: "function not closed", query: `A[1m].mean(`, message: "the function mean is not closed", position: 10, line: 1, column: 11},
+		{name: "missing argument", query: `A[1m].groupBy(a,).mean()`, message: "missing argument in groupBy", position: 16, line: 1, column: 17},
+		{name: "second line", query: "A[1m].mean()\n  + )", message: "expected a metric, a number or '('", position: 17, line: 2, column: 5},
+		{name: "end of query", query: `A[1m].mean() +`, message: "unexpected end of query", position: 14, line: 1, column: 15},
+		{name: "trailing token", query: `A[1m].mean() B[1m].mean()`, message: "unexpected 'B'", position: 13, line: 1, column: 14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			checkError(t, err, tt.message, tt.position, tt.line, tt.column)
+		})
+	}
+}
+
+// checkError checks that err is a *Error with the given message and location.
+func checkError(t *testing.T, err error, message string, position int, line int, column int) {
+	t.Helper()
+
+	var mqlErr *Error
+	if !errors.As(err, &mqlErr) {
+		t.Fatalf("error = %v, want a *Error", err)
+	}
+	if !strings.Contains(mqlErr.Message, message) {
+		t.Errorf("message = %q, want it to contain %q", mqlErr.Message, message)
+	}
+	if mqlErr.Position != position || mqlErr.Line != line || mqlErr.Column != column {
+		t.Errorf("location = %d (line %d, column %d), want %d (line %d, column %d)",
+			mqlErr.Position, mqlErr.Line, mqlErr.Column, position, line, column)
+	}
+}
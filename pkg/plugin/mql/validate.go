@@ -0,0 +1,244 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package mql
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// statistics are the statistic functions of MQL, with their number of arguments: minimum and maximum.
+var statistics = map[string][2]int{
+	"absent":     {0, 1},
+	"avg":        {0, 0},
+	"count":      {0, 0},
+	"first":      {0, 0},
+	"increment":  {0, 0},
+	"last":       {0, 0},
+	"max":        {0, 0},
+	"mean":       {0, 0},
+	"min":        {0, 0},
+	"percentile": {1, 1},
+	"rate":       {0, 0},
+	"sum":        {0, 0},
+}
+
+// groupings are the grouping functions of MQL, with their number of arguments: minimum and maximum.
+var groupings = map[string][2]int{
+	"grouping": {0, 0},
+	"groupBy":  {1, -1},
+}
+
+// intervalPattern matches the intervals of MQL, such as 1m, 5m, 1h or 1d.
+var intervalPattern = regexp.MustCompile(`^(\d+)([mhd])$`)
+
+// maxIntervals are the largest interval of each unit.
+var maxIntervals = map[string]int{"m": 60, "h": 24, "d": 1}
+
+// valueKind is what an expression evaluates to: datapoints or numbers, or a condition such as an alarm trigger.
+type valueKind int
+
+const (
+	kindValue valueKind = iota
+	kindCondition
+)
+
+// validator checks the semantics of a parsed MQL query.
+type validator struct {
+	text string
+}
+
+// Validate parses a MQL query and checks its semantics: the intervals, the grouping and statistic functions
+// and their arguments, and the operands of the operators. Unresolved template variables, names starting
+// with $, are accepted where a name or an interval is expected.
+//
+// Parameters:
+//   - text: The MQL query.
+//
+// Returns:
+//   - Node: The root of the syntax tree.
+//   - error: A *Error locating the first error.
+func Validate(text string) (Node, error) {
+	node, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validator{text: text}
+	if _, err := v.check(node); err != nil {
+		return nil, err
+	}
+
+	hasMetric := false
+	Walk(node, func(n Node) bool {
+		if _, ok := n.(*MetricQuery); ok {
+			hasMetric = true
+		}
+		return !hasMetric
+	})
+	if !hasMetric {
+		return nil, newError(text, 0, "the query does not select any metric")
+	}
+
+	return node, nil
+}
+
+// check validates a node and returns what it evaluates to.
+func (v *validator) check(node Node) (valueKind, error) {
+	switch n := node.(type) {
+	case *MetricQuery:
+		return kindValue, v.checkMetricQuery(n)
+
+	case *NumberLiteral:
+		return kindValue, nil
+
+	case *ParenExpr:
+		return v.check(n.Expr)
+
+	case *UnaryExpr:
+		return kindValue, v.checkOperand(n.Expr, n.Op, kindValue)
+
+	case *RangeExpr:
+		for _, operand := range []Node{n.Expr, n.Low, n.High} {
+			if err := v.checkOperand(operand, "in", kindValue); err != nil {
+				return kindCondition, err
+			}
+		}
+		return kindCondition, nil
+
+	case *BinaryExpr:
+		operandKind, resultKind := kindValue, kindValue
+		switch n.Op {
+		case "&&", "||":
+			operandKind, resultKind = kindCondition, kindCondition
+		case "==", "!=", ">", ">=", "<", "<=":
+			resultKind = kindCondition
+		}
+		if err := v.checkOperand(n.LHS, n.Op, operandKind); err != nil {
+			return resultKind, err
+		}
+		return resultKind, v.checkOperand(n.RHS, n.Op, operandKind)
+	}
+
+	return kindValue, newError(v.text, node.Pos(), "unexpected expression %s", node.String())
+}
+
+// checkOperand validates the operand of an operator, which must evaluate to the expected kind.
+func (v *validator) checkOperand(operand Node, op string, expected valueKind) error {
+	kind, err := v.check(operand)
+	if err != nil {
+		return err
+	}
+	if kind == expected {
+		return nil
+	}
+	if expected == kindCondition {
+		return newError(v.text, operand.Pos(), "the operands of %s must be conditions, such as CpuUtilization[1m].mean() > 80", op)
+	}
+	return newError(v.text, operand.Pos(), "a condition cannot be an operand of %s, use && or || to combine conditions", op)
+}
+
+// checkMetricQuery validates the interval and the functions of a metric query.
+func (v *validator) checkMetricQuery(q *MetricQuery) error {
+	if err := v.checkInterval(q); err != nil {
+		return err
+	}
+
+	var statistic, grouping *Function
+	for i := range q.Functions {
+		f := &q.Functions[i]
+		arity, isStatistic := statistics[f.Name]
+		if !isStatistic {
+			var isGrouping bool
+			if arity, isGrouping = groupings[f.Name]; !isGrouping {
+				return v.unknownFunction(f)
+			}
+			if grouping != nil {
+				return newError(v.text, f.pos, "only one grouping function is allowed, %s is already applied", grouping.Name)
+			}
+			grouping = f
+		} else {
+			if statistic != nil {
+				return newError(v.text, f.pos, "only one statistic is allowed, %s is already applied", statistic.Name)
+			}
+			statistic = f
+		}
+
+		if len(f.Args) < arity[0] || (arity[1] >= 0 && len(f.Args) > arity[1]) {
+			return newError(v.text, f.pos, "%s expects %s", f.Name, describeArity(arity))
+		}
+		if err := v.checkArgs(f); err != nil {
+			return err
+		}
+	}
+
+	if statistic == nil {
+		return newError(v.text, q.end, "the metric %s has no statistic, such as .mean() or .max()", q.Metric)
+	}
+
+	return nil
+}
+
+// checkInterval validates the interval of a metric query: 1m to 60m, 1h to 24h, 1d or auto.
+func (v *validator) checkInterval(q *MetricQuery) error {
+	if q.Interval == "auto" || strings.HasPrefix(q.Interval, "$") {
+		return nil
+	}
+
+	match := intervalPattern.FindStringSubmatch(q.Interval)
+	if match == nil {
+		return newError(v.text, q.intervalPos, "invalid interval %s, expected a number and a unit m, h or d, such as 1m, 5m, 1h or 1d", q.Interval)
+	}
+	value, _ := strconv.Atoi(match[1])
+	if value < 1 || value > maxIntervals[match[2]] {
+		return newError(v.text, q.intervalPos, "invalid interval %s, the interval must be between 1%s and %d%s", q.Interval, match[2], maxIntervals[match[2]], match[2])
+	}
+
+	return nil
+}
+
+// checkArgs validates the arguments of the functions taking some.
+func (v *validator) checkArgs(f *Function) error {
+	switch f.Name {
+	case "percentile":
+		p, err := strconv.ParseFloat(f.Args[0], 64)
+		if err != nil || p <= 0 || p >= 1 {
+			return newError(v.text, f.pos, "percentile expects a number between 0 and 1, such as .90, found %s", f.Args[0])
+		}
+	case "absent":
+		if len(f.Args) == 1 && !strings.HasPrefix(f.Args[0], "$") && !intervalPattern.MatchString(f.Args[0]) {
+			return newError(v.text, f.pos, "absent expects a duration, such as 5m, found %s", f.Args[0])
+		}
+	}
+
+	return nil
+}
+
+// unknownFunction returns the error of an unknown function, suggesting the function differing only by case.
+func (v *validator) unknownFunction(f *Function) error {
+	for _, known := range []map[string][2]int{statistics, groupings} {
+		for name := range known {
+			if strings.EqualFold(name, f.Name) {
+				return newError(v.text, f.pos, "unknown function %s, did you mean %s?", f.Name, name)
+			}
+		}
+	}
+	return newError(v.text, f.pos, "unknown function %s", f.Name)
+}
+
+// describeArity returns the number of arguments of a function as written in the error messages.
+func describeArity(arity [2]int) string {
+	switch {
+	case arity[1] == 0:
+		return "no argument"
+	case arity[1] < 0:
+		return "at least " + strconv.Itoa(arity[0]) + " argument"
+	case arity[0] == arity[1]:
+		return strconv.Itoa(arity[0]) + " argument"
+	}
+	return "at most " + strconv.Itoa(arity[1]) + " argument"
+}
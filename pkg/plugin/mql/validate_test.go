@@ -0,0 +1,68 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package mql
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "grouping and percentile", query: `CpuUtilization[auto].groupBy(a, b).percentile(.9)`},
+		{name: "template variables", query: `A[$window].absent($duration)`},
+		{name: "absent with a duration", query: `A[1m].absent(5m)`},
+		{name: "largest intervals", query: `A[60m].mean() + B[24h].mean() + C[1d].mean()`},
+		{name: "conditions", query: `A[1m].mean() > 80 || B[1h].max() in (1, 2)`},
+		{name: "arithmetic on numbers", query: `A[1m].sum() / 1024 * 100`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Validate(tt.query); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		message  string
+		position int
+	}{
+		{name: "syntax error", query: `A[1m].mean(`, message: "the function mean is not closed", position: 10},
+		{name: "interval without unit", query: `A[1].mean()`, message: "invalid interval 1, expected a number and a unit", position: 2},
+		{name: "unknown unit", query: `A[1x].mean()`, message: "invalid interval 1x", position: 2},
+		{name: "minutes out of range", query: `A[61m].mean()`, message: "the interval must be between 1m and 60m", position: 2},
+		{name: "hours out of range", query: `A[0h].mean()`, message: "the interval must be between 1h and 24h", position: 2},
+		{name: "days out of range", query: `A[2d].mean()`, message: "the interval must be between 1d and 1d", position: 2},
+		{name: "no statistic", query: `A[1m].groupBy(a)`, message: "the metric A has no statistic", position: 16},
+		{name: "two statistics", query: `A[1m].mean().max()`, message: "only one statistic is allowed, mean is already applied", position: 13},
+		{name: "two groupings", query: `A[1m].groupBy(a).grouping().mean()`, message: "only one grouping function is allowed", position: 17},
+		{name: "argument to a statistic without", query: `A[1m].mean(1)`, message: "mean expects no argument", position: 6},
+		{name: "missing percentile", query: `A[1m].percentile()`, message: "percentile expects 1 argument", position: 6},
+		{name: "too many absent arguments", query: `A[1m].absent(1m, 2m)`, message: "absent expects at most 1 argument", position: 6},
+		{name: "groupBy without dimension", query: `A[1m].groupBy().mean()`, message: "groupBy expects at least 1 argument", position: 6},
+		{name: "percentile out of range", query: `A[1m].percentile(1.5)`, message: "percentile expects a number between 0 and 1", position: 6},
+		{name: "absent without duration", query: `A[1m].absent(x)`, message: "absent expects a duration", position: 6},
+		{name: "function case", query: `A[1m].Mean()`, message: "unknown function Mean, did you mean mean?", position: 6},
+		{name: "unknown function", query: `A[1m].median()`, message: "unknown function median", position: 6},
+		{name: "no metric", query: `1 + 2`, message: "the query does not select any metric", position: 0},
+		{name: "value combined as condition", query: `A[1m].mean() && B[1m].mean() > 1`, message: "the operands of && must be conditions", position: 0},
+		{name: "condition as value", query: `(A[1m].mean() > 1) + 2`, message: "a condition cannot be an operand of +", position: 0},
+		{name: "condition in range", query: `(A[1m].mean() > 1) in (0, 1)`, message: "a condition cannot be an operand of in", position: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate(tt.query)
+			checkError(t, err, tt.message, tt.position, 1, tt.position+1)
+		})
+	}
+}
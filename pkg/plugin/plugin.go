@@ -4,13 +4,11 @@ package plugin
 
 import (
 	"context"
-	"encoding/json"
-	"encoding/pem"
 	"fmt"
 	"net/http"
-	"reflect"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -19,12 +17,16 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/oracle/oci-go-sdk/v65/audit"
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/common/auth"
 	"github.com/oracle/oci-go-sdk/v65/identity"
 	"github.com/oracle/oci-go-sdk/v65/monitoring"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
 
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
 	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
 )
 
@@ -39,102 +41,237 @@ type TenancyAccess struct {
 	monitoringClient monitoring.MonitoringClient
 	identityClient   identity.IdentityClient
 	config           common.ConfigurationProvider
+	customDomain     string
+	regionClients    map[string]monitoring.MonitoringClient
+	auditClients     map[string]audit.AuditClient
+	searchClients    map[string]resourcesearch.ResourceSearchClient
+	regionClientsMu  sync.Mutex
 }
 
 type OCIDatasource struct {
-	tenancyAccess map[string]*TenancyAccess
-	logger        log.Logger
-	nameToOCID    map[string]string
+	tenancyAccess   map[string]*TenancyAccess
+	tenancyAccessMu sync.RWMutex
+	configFile      *configFileWatch
+	logger          log.Logger
+	nameToOCID      map[string]string
 	// timeCacheUpdated time.Time
 	backend.CallResourceHandler
 	// clients  *client.OCIClients
 	settings *models.OCIDatasourceSettings
-	cache    *ristretto.Cache
+	// instanceSettings are the Grafana settings of the datasource, used by the stream pollers
+	instanceSettings *backend.DataSourceInstanceSettings
+	cache            *ristretto.Cache
+	streams          *streamRegistry
+	// inflight holds the upstream calls in flight and inflightCalls their waiters, see coalesce
+	inflight      singleflight.Group
+	inflightMu    sync.Mutex
+	inflightCalls map[string]*coalescedCall
 }
 
-type OCIConfigFile struct {
-	tenancyocid  map[string]string
-	region       map[string]string
-	user         map[string]string
-	fingerprint  map[string]string
-	privkey      map[string]string
-	privkeypass  map[string]*string
-	customregion map[string]string
-	customdomain map[string]string
-	logger       log.Logger
+// getTenancyAccess returns the tenancy access of the key, nil if the key is not configured.
+// With the config file environment the profiles are reloaded first when the file changed.
+func (o *OCIDatasource) getTenancyAccess(key string) *TenancyAccess {
+	o.reloadConfigFileIfChanged()
+
+	o.tenancyAccessMu.RLock()
+	defer o.tenancyAccessMu.RUnlock()
+	return o.tenancyAccess[key]
+}
+
+// tenancyAccessFor returns the tenancy access of the key, or an error when the key is not configured, such as a
+// profile removed from a reloaded config file. Requests resolve the access once and use it throughout.
+func (o *OCIDatasource) tenancyAccessFor(key string) (*TenancyAccess, error) {
+	ta := o.getTenancyAccess(key)
+	if ta == nil {
+		return nil, errors.New("tenancy not configured: " + key)
+	}
+	return ta, nil
+}
+
+// setTenancyAccess configures the tenancy access of a key.
+func (o *OCIDatasource) setTenancyAccess(key string, ta *TenancyAccess) {
+	o.tenancyAccessMu.Lock()
+	defer o.tenancyAccessMu.Unlock()
+	o.tenancyAccess[key] = ta
 }
 
-type OCISecuredSettings struct {
-	Profile_0      string `json:"profile0,omitempty"`
-	Tenancy_0      string `json:"tenancy0,omitempty"`
-	Region_0       string `json:"region0,omitempty"`
-	User_0         string `json:"user0,omitempty"`
-	Privkey_0      string `json:"privkey0,omitempty"`
-	Fingerprint_0  string `json:"fingerprint0,omitempty"`
-	CustomRegion_0 string `json:"customregion0,omitempty"`
-	CustomDomain_0 string `json:"customdomain0,omitempty"`
-
-	Profile_1      string `json:"profile1,omitempty"`
-	Tenancy_1      string `json:"tenancy1,omitempty"`
-	Region_1       string `json:"region1,omitempty"`
-	User_1         string `json:"user1,omitempty"`
-	Fingerprint_1  string `json:"fingerprint1,omitempty"`
-	Privkey_1      string `json:"privkey1,omitempty"`
-	CustomRegion_1 string `json:"customregion1,omitempty"`
-	CustomDomain_1 string `json:"customdomain1,omitempty"`
-
-	Profile_2      string `json:"profile2,omitempty"`
-	Tenancy_2      string `json:"tenancy2,omitempty"`
-	Region_2       string `json:"region2,omitempty"`
-	User_2         string `json:"user2,omitempty"`
-	Fingerprint_2  string `json:"fingerprint2,omitempty"`
-	Privkey_2      string `json:"privkey2,omitempty"`
-	CustomRegion_2 string `json:"customregion2,omitempty"`
-	CustomDomain_2 string `json:"customdomain2,omitempty"`
-
-	Profile_3      string `json:"profile3,omitempty"`
-	Tenancy_3      string `json:"tenancy3,omitempty"`
-	Region_3       string `json:"region3,omitempty"`
-	User_3         string `json:"user3,omitempty"`
-	Fingerprint_3  string `json:"fingerprint3,omitempty"`
-	Privkey_3      string `json:"privkey3,omitempty"`
-	CustomRegion_3 string `json:"customregion3,omitempty"`
-	CustomDomain_3 string `json:"customdomain3,omitempty"`
-
-	Profile_4      string `json:"profile4,omitempty"`
-	Tenancy_4      string `json:"tenancy4,omitempty"`
-	Region_4       string `json:"region4,omitempty"`
-	User_4         string `json:"user4,omitempty"`
-	Fingerprint_4  string `json:"fingerprint4,omitempty"`
-	Privkey_4      string `json:"privkey4,omitempty"`
-	CustomRegion_4 string `json:"customregion4,omitempty"`
-	CustomDomain_4 string `json:"customdomain4,omitempty"`
-
-	Profile_5      string `json:"profile5,omitempty"`
-	Tenancy_5      string `json:"tenancy5,omitempty"`
-	Region_5       string `json:"region5,omitempty"`
-	User_5         string `json:"user5,omitempty"`
-	Fingerprint_5  string `json:"fingerprint5,omitempty"`
-	Privkey_5      string `json:"privkey5,omitempty"`
-	CustomRegion_5 string `json:"customregion5,omitempty"`
-	CustomDomain_5 string `json:"customdomain5,omitempty"`
-
-	Xtenancy_0 string `json:"xtenancy0,omitempty"`
+// hasTenancyAccess tells whether a key is configured, without reloading the config file.
+func (o *OCIDatasource) hasTenancyAccess(key string) bool {
+	o.tenancyAccessMu.RLock()
+	defer o.tenancyAccessMu.RUnlock()
+	_, ok := o.tenancyAccess[key]
+	return ok
+}
+
+// tenancyAccessSnapshot returns a copy of the configured tenancy accesses, safe to iterate
+// while the config file is reloaded.
+func (o *OCIDatasource) tenancyAccessSnapshot() map[string]*TenancyAccess {
+	o.reloadConfigFileIfChanged()
+
+	o.tenancyAccessMu.RLock()
+	defer o.tenancyAccessMu.RUnlock()
+	snapshot := make(map[string]*TenancyAccess, len(o.tenancyAccess))
+	for key, ta := range o.tenancyAccess {
+		snapshot[key] = ta
+	}
+	return snapshot
+}
+
+type OCIConfigFile struct {
+	tenancyocid   map[string]string
+	region        map[string]string
+	user          map[string]string
+	fingerprint   map[string]string
+	privkey       map[string]string
+	privkeypass   map[string]*string
+	customregion  map[string]string
+	customdomain  map[string]string
+	authtype      map[string]string
+	token         map[string]string
+	privkeysecret map[string]string
+	logger        log.Logger
 }
 
 // NewOCIConfigFile - constructor
 func NewOCIConfigFile() *OCIConfigFile {
 	return &OCIConfigFile{
-		tenancyocid:  make(map[string]string),
-		region:       make(map[string]string),
-		user:         make(map[string]string),
-		fingerprint:  make(map[string]string),
-		privkey:      make(map[string]string),
-		privkeypass:  make(map[string]*string),
-		customregion: make(map[string]string),
-		customdomain: make(map[string]string),
-		logger:       log.DefaultLogger,
+		tenancyocid:   make(map[string]string),
+		region:        make(map[string]string),
+		user:          make(map[string]string),
+		fingerprint:   make(map[string]string),
+		privkey:       make(map[string]string),
+		privkeypass:   make(map[string]*string),
+		customregion:  make(map[string]string),
+		customdomain:  make(map[string]string),
+		authtype:      make(map[string]string),
+		token:         make(map[string]string),
+		privkeysecret: make(map[string]string),
+		logger:        log.DefaultLogger,
+	}
+}
+
+// NewTenancyAccess - constructor
+func NewTenancyAccess(monitoringClient monitoring.MonitoringClient, identityClient identity.IdentityClient, config common.ConfigurationProvider, customDomain string) *TenancyAccess {
+	return &TenancyAccess{
+		monitoringClient: monitoringClient,
+		identityClient:   identityClient,
+		config:           config,
+		customDomain:     customDomain,
+		regionClients:    make(map[string]monitoring.MonitoringClient),
+		auditClients:     make(map[string]audit.AuditClient),
+		searchClients:    make(map[string]resourcesearch.ResourceSearchClient),
+	}
+}
+
+// MonitoringClientForRegion returns a monitoring client targeting the Telemetry endpoint of the given region.
+//
+// The client is a copy of the tenancy monitoring client, so it shares the configuration provider and
+// retry policy, but its host is re-targeted at the requested region. When a custom domain is configured
+// for the tenancy the endpoint is built using that domain, otherwise the SDK realm lookup is used.
+// Clients are created once per region and reused afterwards.
+//
+// Parameters:
+//   - region: The region the client has to target. An empty region or the configured region return the tenancy client.
+//
+// Returns:
+//   - monitoring.MonitoringClient: The monitoring client for the region.
+func (ta *TenancyAccess) MonitoringClientForRegion(region string) monitoring.MonitoringClient {
+	if region == "" || region == constants.ALL_REGION {
+		return ta.monitoringClient
+	}
+	if configuredRegion, err := ta.config.Region(); err == nil && configuredRegion == region {
+		return ta.monitoringClient
+	}
+
+	ta.regionClientsMu.Lock()
+	defer ta.regionClientsMu.Unlock()
+
+	if mc, ok := ta.regionClients[region]; ok {
+		return mc
+	}
+
+	mc := ta.monitoringClient
+	if ta.customDomain != "" {
+		mc.Host = common.StringToRegion(region).EndpointForTemplate("telemetry", "https://telemetry."+region+"."+ta.customDomain)
+	} else {
+		mc.SetRegion(region)
 	}
+	backend.Logger.Debug("plugin", "MonitoringClientForRegion", "region "+region+" uses endpoint "+mc.Host)
+	ta.regionClients[region] = mc
+
+	return mc
+}
+
+// AuditClientForRegion returns an audit client targeting the Audit endpoint of the given region.
+//
+// The client uses the configuration provider of the tenancy and the same retry policy as the monitoring
+// clients. When a custom domain is configured for the tenancy the endpoint is built using that domain.
+// Clients are created once per region and reused afterwards.
+//
+// Parameters:
+//   - region: The region the client has to target.
+//
+// Returns:
+//   - audit.AuditClient: The audit client for the region.
+//   - error: An error if the client cannot be created.
+func (ta *TenancyAccess) AuditClientForRegion(region string) (audit.AuditClient, error) {
+	ta.regionClientsMu.Lock()
+	defer ta.regionClientsMu.Unlock()
+
+	if ac, ok := ta.auditClients[region]; ok {
+		return ac, nil
+	}
+
+	ac, err := audit.NewAuditClientWithConfigurationProvider(ta.config)
+	if err != nil {
+		return audit.AuditClient{}, errors.Wrap(err, "error creating audit client")
+	}
+	arp := clientRetryPolicy()
+	ac.Configuration.RetryPolicy = &arp
+	useVaultSigner(&ac.BaseClient, ta.config)
+	if ta.customDomain != "" {
+		ac.Host = common.StringToRegion(region).EndpointForTemplate("audit", "https://audit."+region+"."+ta.customDomain)
+	} else {
+		ac.SetRegion(region)
+	}
+	backend.Logger.Debug("plugin", "AuditClientForRegion", "region "+region+" uses endpoint "+ac.Host)
+	ta.auditClients[region] = ac
+
+	return ac, nil
+}
+
+// SearchClientForRegion returns a resource search client targeting the Search endpoint of the given region.
+//
+// Parameters:
+//   - region: The region the client has to target.
+//
+// Returns:
+//   - resourcesearch.ResourceSearchClient: The resource search client for the region.
+//   - error: An error if the client cannot be created.
+func (ta *TenancyAccess) SearchClientForRegion(region string) (resourcesearch.ResourceSearchClient, error) {
+	ta.regionClientsMu.Lock()
+	defer ta.regionClientsMu.Unlock()
+
+	if sc, ok := ta.searchClients[region]; ok {
+		return sc, nil
+	}
+
+	sc, err := resourcesearch.NewResourceSearchClientWithConfigurationProvider(ta.config)
+	if err != nil {
+		return resourcesearch.ResourceSearchClient{}, errors.Wrap(err, "error creating resource search client")
+	}
+	srp := clientRetryPolicy()
+	sc.Configuration.RetryPolicy = &srp
+	useVaultSigner(&sc.BaseClient, ta.config)
+	if ta.customDomain != "" {
+		sc.Host = common.StringToRegion(region).EndpointForTemplate("query", "https://query."+region+"."+ta.customDomain)
+	} else {
+		sc.SetRegion(region)
+	}
+	backend.Logger.Debug("plugin", "SearchClientForRegion", "region "+region+" uses endpoint "+sc.Host)
+	ta.searchClients[region] = sc
+
+	return sc, nil
 }
 
 // NewOCIDatasourceConstructor - constructor
@@ -143,6 +280,8 @@ func NewOCIDatasourceConstructor() *OCIDatasource {
 		tenancyAccess: make(map[string]*TenancyAccess),
 		logger:        log.DefaultLogger,
 		nameToOCID:    make(map[string]string),
+		streams:       NewStreamRegistry(),
+		inflightCalls: make(map[string]*coalescedCall),
 	}
 }
 
@@ -166,11 +305,12 @@ func NewOCIDatasource(settings backend.DataSourceInstanceSettings) (instancemgmt
 		return nil, err
 	}
 	o.settings = dsSettings
+	o.instanceSettings = &settings
 
 	backend.Logger.Error("plugin", "dsSettings.Environment", "dsSettings.Environment: "+dsSettings.Environment)
 	backend.Logger.Error("plugin", "dsSettings.TenancyMode", "dsSettings.TenancyMode: "+dsSettings.TenancyMode)
 
-	if len(o.tenancyAccess) == 0 {
+	if len(o.tenancyAccessSnapshot()) == 0 {
 		err := o.getConfigProvider(dsSettings.Environment, dsSettings.TenancyMode, settings)
 		if err != nil {
 			return nil, err
@@ -196,6 +336,13 @@ func NewOCIDatasource(settings backend.DataSourceInstanceSettings) (instancemgmt
 	return o, nil
 }
 
+// Dispose is called by the instance manager before the datasource is replaced, when its settings change.
+// The stream pollers of the datasource are stopped, the streams are restarted by Grafana on the new instance.
+func (o *OCIDatasource) Dispose() {
+	backend.Logger.Debug("plugin", "Dispose", "stopping the stream pollers")
+	o.streams.close()
+}
+
 /**
 * @Description: QueryData handles the execution of multiple queries against the OCI Monitoring service.
 * It iterates through each query in the request, executes it, and aggregates the results into a single response.
@@ -235,6 +382,8 @@ func (o *OCIDatasource) QueryData(ctx context.Context, req *backend.QueryDataReq
 // The main use case for these health checks is the test button on the
 // datasource configuration page which allows users to verify that
 // a datasource is working as expected.
+// Profiles authenticated with a security token report the token expiry,
+// and an expired token fails the check with an explicit message.
 func (o *OCIDatasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
 	backend.Logger.Error("plugin", "CheckHealth", req.PluginContext.PluginID)
 
@@ -247,129 +396,109 @@ func (o *OCIDatasource) CheckHealth(ctx context.Context, req *backend.CheckHealt
 		return hRes, nil
 	}
 
+	message := "Success"
+	for key, ta := range o.tenancyAccessSnapshot() {
+		if stProvider, ok := ta.config.(*SessionTokenConfigurationProvider); ok {
+			message += fmt.Sprintf(", security token of %v expires at %v", key, stProvider.ExpiresAt().Format(time.RFC3339))
+		}
+	}
+
 	return &backend.CheckHealthResult{
 		Status:  backend.HealthStatusOk,
-		Message: "Success",
+		Message: message,
 	}, nil
 }
 
 // OCILoadSettings loads and processes OCI configuration settings from the Grafana data source instance settings.
 //
-// This function handles both secured and non-secured settings, merging them to create a comprehensive
-// configuration. It iterates through the settings, parsing and storing them in an OCIConfigFile struct.
-// The function supports multiple tenancy configurations, identified by a numerical suffix (e.g., _0, _1).
+// The profiles are read from the profiles list of the datasource settings, legacy numbered settings are
+// migrated by models.OCIDatasourceSettings.Load. The secured settings of every profile are read from the
+// decrypted secure json data under the key models.OCIProfileSettings.SecureKey returns, such as privkey_DEFAULT,
+// never under the position of the profile in the list.
 //
 // Parameters:
 //   - req: backend.DataSourceInstanceSettings - The data source instance settings from Grafana.
 //
 // Returns:
-//   - *OCIConfigFile: A pointer to an OCIConfigFile struct containing the parsed settings.
-//   - error: An error if any issues occur during the loading or parsing of settings.
-//
-// The function performs the following steps:
-//  1. Initializes an empty OCIConfigFile.
-//  2. Unmarshals the JSON data from req.JSONData into both OCISecuredSettings and OCIDatasourceSettings structs.
-//  3. Merges the non-secured settings into the secured settings.
-//  4. Iterates through the fields of the OCISecuredSettings struct using reflection.
-//  5. Parses the field names to determine the tenancy block index (e.g., _0, _1).
-//  6. Extracts the profile name as the key for each tenancy block.
-//  7. Stores the tenancy OCID, region, user, private key, fingerprint, private key passphrase, custom region, and custom domain in the OCIConfigFile.
-//  8. Handles multiple tenancy blocks by incrementing the TenancySettingsBlock index.
-//  9. Returns the populated OCIConfigFile or an error if any step fails.
+//   - *OCIConfigFile: A pointer to an OCIConfigFile struct containing the parsed settings, keyed by profile name.
+//   - error: An error if the settings cannot be read, a profile name is duplicated or a secured setting is missing.
 func OCILoadSettings(req backend.DataSourceInstanceSettings) (*OCIConfigFile, error) {
 	q := NewOCIConfigFile()
 
-	// Load secured and non-secured settings
-	TenancySettingsBlock := 0
-	var dat OCISecuredSettings
-	var nonsecdat models.OCIDatasourceSettings
-
-	if err := json.Unmarshal(req.JSONData, &dat); err != nil {
-		return nil, fmt.Errorf("can not read Secured settings: %s", err.Error())
-	}
-
-	if err := json.Unmarshal(req.JSONData, &nonsecdat); err != nil {
+	var dsSettings models.OCIDatasourceSettings
+	if err := dsSettings.Load(req); err != nil {
 		return nil, fmt.Errorf("can not read settings: %s", err.Error())
 	}
 
-	// merge non secured settings into secured
-	decryptedJSONData := req.DecryptedSecureJSONData
-	transcode(decryptedJSONData, &dat)
-
-	dat.Region_0 = nonsecdat.Region_0
-	dat.Region_1 = nonsecdat.Region_1
-	dat.Region_2 = nonsecdat.Region_2
-	dat.Region_3 = nonsecdat.Region_3
-	dat.Region_4 = nonsecdat.Region_4
-	dat.Region_5 = nonsecdat.Region_5
-
-	dat.Profile_0 = nonsecdat.Profile_0
-	dat.Profile_1 = nonsecdat.Profile_1
-	dat.Profile_2 = nonsecdat.Profile_2
-	dat.Profile_3 = nonsecdat.Profile_3
-	dat.Profile_4 = nonsecdat.Profile_4
-	dat.Profile_5 = nonsecdat.Profile_5
-
-	dat.Xtenancy_0 = nonsecdat.Xtenancy_0
-
-	dat.CustomRegion_0 = nonsecdat.CustomRegion_0
-	dat.CustomRegion_1 = nonsecdat.CustomRegion_1
-	dat.CustomRegion_2 = nonsecdat.CustomRegion_2
-	dat.CustomRegion_3 = nonsecdat.CustomRegion_3
-	dat.CustomRegion_4 = nonsecdat.CustomRegion_4
-	dat.CustomRegion_5 = nonsecdat.CustomRegion_5
-
-	v := reflect.ValueOf(dat)
-	typeOfS := v.Type()
-	var key string
-
-	for FieldIndex := 0; FieldIndex < v.NumField(); FieldIndex++ {
-		splits := strings.Split(typeOfS.Field(FieldIndex).Name, "_")
-		SettingsBlockIndex, interr := strconv.Atoi(splits[1])
-		if interr != nil {
-			return nil, fmt.Errorf("can not read settings: %s", interr.Error())
+	secured := req.DecryptedSecureJSONData
+	for position, profile := range dsSettings.Profiles {
+		key := profile.Name
+		if key == "" {
+			backend.Logger.Warn("OCILoadSettings", "skipping profile without name at position", position)
+			continue
+		}
+		if _, ok := q.tenancyocid[key]; ok {
+			return nil, fmt.Errorf("can not read settings: duplicate profile %s", key)
+		}
+		if err := checkProfileSecrets(profile, secured); err != nil {
+			return nil, err
 		}
 
-		if SettingsBlockIndex == TenancySettingsBlock {
-			if splits[0] == "Profile" {
-				if v.Field(FieldIndex).Interface() != "" {
-					key = fmt.Sprintf("%v", v.Field(FieldIndex).Interface())
-				} else {
-					return q, nil
-				}
-			} else {
-				switch value := v.Field(FieldIndex).Interface(); strings.ToLower(splits[0]) {
-				case "tenancy":
-					q.tenancyocid[key] = fmt.Sprintf("%v", value)
-				case "region":
-					q.region[key] = fmt.Sprintf("%v", value)
-				case "user":
-					q.user[key] = fmt.Sprintf("%v", value)
-				case "privkey":
-					q.privkey[key] = fmt.Sprintf("%v", value)
-				case "fingerprint":
-					q.fingerprint[key] = fmt.Sprintf("%v", value)
-				case "privkeypass":
-					q.privkeypass[key] = EmptyKeyPass
-				case "customregion":
-					q.customregion[key] = fmt.Sprintf("%v", value)
-				case "customdomain":
-					q.customdomain[key] = fmt.Sprintf("%v", value)
-				}
-			}
+		q.tenancyocid[key] = secured[profile.SecureKey("tenancy")]
+		q.region[key] = profile.Region
+		q.user[key] = secured[profile.SecureKey("user")]
+		q.privkey[key] = secured[profile.SecureKey("privkey")]
+		q.fingerprint[key] = secured[profile.SecureKey("fingerprint")]
+		if passphrase := secured[profile.SecureKey("privkeypass")]; passphrase != "" {
+			q.privkeypass[key] = &passphrase
 		} else {
-			TenancySettingsBlock++
-			FieldIndex--
+			q.privkeypass[key] = EmptyKeyPass
 		}
+		q.customregion[key] = profile.CustomRegion
+		q.customdomain[key] = secured[profile.SecureKey("customdomain")]
+		q.authtype[key] = profile.AuthType
+		q.token[key] = secured[profile.SecureKey("token")]
+		q.privkeysecret[key] = profile.PrivkeySecret
 	}
 	return q, nil
 }
 
+// checkProfileSecrets checks that the secure json data holds the secured settings the authentication of a
+// profile requires, so that a profile whose settings are missing is reported rather than configured with
+// empty credentials.
+//
+// Parameters:
+// - profile: The settings of the profile.
+// - secured: The decrypted secure json data of the datasource.
+//
+// Returns:
+// - error: An error naming the first missing setting and the key it is expected under, otherwise nil.
+func checkProfileSecrets(profile models.OCIProfileSettings, secured map[string]string) error {
+	required := []string{"tenancy", "user", "fingerprint", "privkey"}
+	switch {
+	case profile.AuthType == constants.AUTH_TYPE_SECURITY_TOKEN:
+		// the tenancy and the user are read from the token
+		required = []string{"token", "privkey"}
+	case profile.PrivkeySecret != "":
+		// the key is read from the vault secret and its fingerprint computed from it
+		required = []string{"tenancy", "user"}
+	}
+
+	for _, field := range required {
+		if secured[profile.SecureKey(field)] == "" {
+			return fmt.Errorf("can not read settings: profile %s has no %s, expected in secureJsonData as %s",
+				profile.Name, field, profile.SecureKey(field))
+		}
+	}
+
+	return nil
+}
+
 // getConfigProvider configures the OCI Datasource based on the provided environment and tenancy mode.
-// It supports two environments: "local" and "OCI Instance".
+// It supports the "local", "OCI Config File", "OCI Instance", "OCI Resource Principal" and "OCI Workload Identity" environments.
 //
 // Parameters:
-// - environment: A string indicating the environment type ("local" or "OCI Instance").
+// - environment: A string indicating the environment type ("local", "OCI Config File", "OCI Instance", "OCI Resource Principal" or "OCI Workload Identity").
 // - tenancymode: A string indicating the tenancy mode ("singletenancy" or "multitenancy").
 // - req: A backend.DataSourceInstanceSettings object containing the datasource instance settings.
 //
@@ -379,7 +508,9 @@ func OCILoadSettings(req backend.DataSourceInstanceSettings) (*OCIConfigFile, er
 // For "local" environment:
 // - Configures using User Principals.
 // - Loads settings from the provided datasource instance settings.
-// - Validates the PEM key.
+// - Validates the PEM key and its passphrase, if the key is encrypted.
+// - Reads the PEM key from an OCI Vault secret when the profile references one, using the principal of the Grafana host.
+// - Uses the security token and session key of the profile when its authentication type is "security_token".
 // - Creates OCI monitoring and identity clients with retry policies.
 // - Overrides region and domain if a custom region is configured.
 // - Stores the configured clients in the tenancyAccess map.
@@ -387,9 +518,19 @@ func OCILoadSettings(req backend.DataSourceInstanceSettings) (*OCIConfigFile, er
 // For "OCI Instance" environment:
 // - Configures using Instance Principal.
 // - Optionally configures cross-tenancy instance principal if Xtenancy_0 is set.
+// - In multitenancy mode every target tenancy of Xtenancies becomes a tenancy access, through cross tenancy policies.
 // - Creates OCI monitoring and identity clients.
 // - Stores the configured clients in the tenancyAccess map.
 //
+// For "OCI Config File" environment:
+// - Configures every profile of an OCI CLI config file, key files are read from the paths set in the profiles.
+// - The config file is reloaded when it changes.
+//
+// For "OCI Resource Principal" and "OCI Workload Identity" environments:
+// - Configures using Resource Principal (e.g. OCI Functions) or OKE Workload Identity (pods running in OKE).
+// - The principal material is read from the environment the SDK documents for each provider.
+// - Cross tenancy and client creation are handled the same way as for "OCI Instance".
+//
 // Returns an error if the environment type is unknown or if any configuration steps fail.
 func (o *OCIDatasource) getConfigProvider(environment string, tenancymode string, req backend.DataSourceInstanceSettings) error {
 
@@ -399,7 +540,7 @@ func (o *OCIDatasource) getConfigProvider(environment string, tenancymode string
 
 		q, err := OCILoadSettings(req)
 		if err != nil {
-			return errors.New("Error Loading config settings")
+			return errors.Wrap(err, "Error Loading config settings")
 		}
 		for key := range q.tenancyocid {
 
@@ -410,17 +551,42 @@ func (o *OCIDatasource) getConfigProvider(environment string, tenancymode string
 					continue
 				}
 			}
-			// test if PEM key is valid
-			block, _ := pem.Decode([]byte(q.privkey[key]))
-			if block == nil {
-				return errors.New("Invalid Private Key in profile " + key)
+			// test if PEM key is valid and can be decrypted with the configured passphrase
+			// keys stored in a vault secret are validated when they are fetched
+			if q.privkeysecret[key] == "" {
+				if err := validatePrivateKey(key, q.privkey[key], q.privkeypass[key]); err != nil {
+					return err
+				}
 			}
 			// Override region in Configuration Provider in case a Custom region is configured
+			region := q.region[key]
 			if q.customregion[key] != "" {
 				backend.Logger.Error("getConfigProvider", "CustomRegion", q.customregion[key])
-				configProvider = common.NewRawConfigurationProvider(q.tenancyocid[key], q.user[key], q.customregion[key], q.fingerprint[key], q.privkey[key], q.privkeypass[key])
+				region = q.customregion[key]
+			}
+			if q.privkeysecret[key] != "" {
+				if q.authtype[key] == constants.AUTH_TYPE_SECURITY_TOKEN {
+					return errors.New("vault secret private keys are not supported with security tokens in profile " + key)
+				}
+				// API key read from an OCI Vault secret with the principal of the Grafana host
+				secretsClient, scErr := vaultSecretsClient(q.privkeysecret[key])
+				if scErr != nil {
+					return errors.Wrap(scErr, "error with vault secret of profile "+key)
+				}
+				vkProvider, vkErr := NewVaultKeyConfigurationProvider(secretsClient, q.tenancyocid[key], q.user[key], region, q.privkeysecret[key], q.privkeypass[key])
+				if vkErr != nil {
+					return errors.Wrap(vkErr, "error with vault secret of profile "+key)
+				}
+				configProvider = vkProvider
+			} else if q.authtype[key] == constants.AUTH_TYPE_SECURITY_TOKEN {
+				// session key and security token created by oci session authenticate
+				stProvider, stErr := NewSessionTokenConfigurationProvider(key, q.tenancyocid[key], region, q.customdomain[key], q.token[key], q.privkey[key], q.privkeypass[key])
+				if stErr != nil {
+					return stErr
+				}
+				configProvider = stProvider
 			} else {
-				configProvider = common.NewRawConfigurationProvider(q.tenancyocid[key], q.user[key], q.region[key], q.fingerprint[key], q.privkey[key], q.privkeypass[key])
+				configProvider = common.NewRawConfigurationProvider(q.tenancyocid[key], q.user[key], region, q.fingerprint[key], q.privkey[key], q.privkeypass[key])
 			}
 
 			// creating oci monitoring client
@@ -431,6 +597,7 @@ func (o *OCIDatasource) getConfigProvider(environment string, tenancymode string
 				return errors.New("error with client")
 			}
 			monitoringClient.Configuration.RetryPolicy = &mrp
+			useVaultSigner(&monitoringClient.BaseClient, configProvider)
 
 			// creating oci identity client
 			irp := clientRetryPolicy()
@@ -439,6 +606,7 @@ func (o *OCIDatasource) getConfigProvider(environment string, tenancymode string
 				return errors.New("Error creating identity client")
 			}
 			identityClient.Configuration.RetryPolicy = &irp
+			useVaultSigner(&identityClient.BaseClient, configProvider)
 
 			// Override Identity and Telemetry EndPoint region and domain in case a Custom region is configured
 			if q.customdomain[key] != "" {
@@ -453,39 +621,107 @@ func (o *OCIDatasource) getConfigProvider(environment string, tenancymode string
 				return errors.New("error with TenancyOCID")
 			}
 			if tenancymode == "multitenancy" {
-				o.tenancyAccess[key+"/"+tenancyocid] = &TenancyAccess{monitoringClient, identityClient, configProvider}
+				o.setTenancyAccess(key+"/"+tenancyocid, NewTenancyAccess(monitoringClient, identityClient, configProvider, q.customdomain[key]))
 			} else {
-				o.tenancyAccess[SingleTenancyKey] = &TenancyAccess{monitoringClient, identityClient, configProvider}
+				o.setTenancyAccess(SingleTenancyKey, NewTenancyAccess(monitoringClient, identityClient, configProvider, q.customdomain[key]))
 			}
 		}
 		return nil
 
+	case "OCI Config File":
+		log.DefaultLogger.Debug("Configuring using OCI Config File")
+		return o.setConfigFileTenancyAccess(o.settings.ConfigFilePath, tenancymode)
+
 	case "OCI Instance":
 		log.DefaultLogger.Debug("Configuring using Instance Principal")
-		var configProvider common.ConfigurationProvider
 		configProvider, err := auth.InstancePrincipalConfigurationProvider()
 		if err != nil {
 			return errors.New("error with instance principals")
 		}
-		if o.settings.Xtenancy_0 != "" {
-			log.DefaultLogger.Debug("Configuring using Cross Tenancy Instance Principal")
-			tocid, _ := configProvider.TenancyOCID()
-			log.DefaultLogger.Debug("Source Tenancy OCID: " + tocid)
-			log.DefaultLogger.Debug("Target Tenancy OCID: " + o.settings.Xtenancy_0)
-		}
-		monitoringClient, err := monitoring.NewMonitoringClientWithConfigurationProvider(configProvider)
+		return o.setPrincipalTenancyAccess(configProvider, "Instance Principal")
+
+	case "OCI Resource Principal":
+		log.DefaultLogger.Debug("Configuring using Resource Principal")
+		configProvider, err := auth.ResourcePrincipalConfigurationProvider()
 		if err != nil {
-			backend.Logger.Error("getConfigProvider", "Error with config", SingleTenancyKey)
-			return errors.New("error with client")
+			return errors.Wrap(err, "error with resource principals")
 		}
-		identityClient, err := identity.NewIdentityClientWithConfigurationProvider(configProvider)
+		return o.setPrincipalTenancyAccess(configProvider, "Resource Principal")
+
+	case "OCI Workload Identity":
+		log.DefaultLogger.Debug("Configuring using OKE Workload Identity")
+		configProvider, err := auth.OkeWorkloadIdentityConfigurationProvider()
 		if err != nil {
-			return errors.New("Error creating identity client")
+			return errors.Wrap(err, "error with OKE workload identity")
 		}
-		o.tenancyAccess[SingleTenancyKey] = &TenancyAccess{monitoringClient, identityClient, configProvider}
-		return nil
+		return o.setPrincipalTenancyAccess(configProvider, "Workload Identity")
 
 	default:
 		return errors.New("unknown environment type")
 	}
 }
+
+// setPrincipalTenancyAccess creates the OCI clients for a principal based configuration provider
+// (instance principal, resource principal or OKE workload identity) and stores them as tenancy access.
+// In single tenancy mode the clients are stored as the single tenancy access. In multitenancy mode
+// (cross tenancy policies) the tenancy of the principal and every configured target tenancy get their
+// own tenancy access, all sharing the clients of the principal.
+//
+// Parameters:
+// - configProvider: The configuration provider of the principal.
+// - principal: A human readable name of the principal, used in logs and errors.
+//
+// Returns:
+// - error: An error if the clients cannot be created or the target tenancies are not valid.
+func (o *OCIDatasource) setPrincipalTenancyAccess(configProvider common.ConfigurationProvider, principal string) error {
+	multitenancy := o.settings.TenancyMode == "multitenancy"
+	if o.settings.Xtenancy_0 != "" && !multitenancy {
+		log.DefaultLogger.Debug("Configuring using Cross Tenancy " + principal)
+		tocid, _ := configProvider.TenancyOCID()
+		log.DefaultLogger.Debug("Source Tenancy OCID: " + tocid)
+		log.DefaultLogger.Debug("Target Tenancy OCID: " + o.settings.Xtenancy_0)
+	}
+	monitoringClient, err := monitoring.NewMonitoringClientWithConfigurationProvider(configProvider)
+	if err != nil {
+		backend.Logger.Error("getConfigProvider", "Error with config", SingleTenancyKey, "principal", principal)
+		return errors.New("error with client")
+	}
+	identityClient, err := identity.NewIdentityClientWithConfigurationProvider(configProvider)
+	if err != nil {
+		return errors.New("Error creating identity client")
+	}
+	if !multitenancy {
+		o.setTenancyAccess(SingleTenancyKey, NewTenancyAccess(monitoringClient, identityClient, configProvider, ""))
+		return nil
+	}
+
+	log.DefaultLogger.Debug("Configuring " + principal + " in " + constants.MULTI_TENANCY_MODE_POLICY + " mode")
+	if len(o.settings.Xtenancies) == 0 {
+		return errors.New("multitenancy mode using " + principal + " requires at least one target tenancy")
+	}
+	sourceocid, err := configProvider.TenancyOCID()
+	if err != nil {
+		return errors.Wrap(err, "error with TenancyOCID of "+principal)
+	}
+	o.setTenancyAccess("DEFAULT/"+sourceocid, NewTenancyAccess(monitoringClient, identityClient, configProvider, ""))
+
+	for position, target := range o.settings.Xtenancies {
+		name := target.Name
+		if name == "" {
+			name = fmt.Sprintf("tenancy-%d", position+1)
+		}
+		if strings.Contains(name, "/") {
+			return errors.New("invalid target tenancy name " + name + ", it can not contain /")
+		}
+		if !strings.HasPrefix(target.OCID, "ocid1.tenancy.") {
+			return errors.New("invalid OCID of target tenancy " + name + ": " + target.OCID)
+		}
+		key := name + "/" + target.OCID
+		if o.hasTenancyAccess(key) {
+			return errors.New("duplicate target tenancy " + key)
+		}
+		log.DefaultLogger.Debug("Source Tenancy OCID: " + sourceocid + ", Target Tenancy OCID: " + target.OCID)
+		o.setTenancyAccess(key, NewTenancyAccess(monitoringClient, identityClient, configProvider, ""))
+	}
+	return nil
+}
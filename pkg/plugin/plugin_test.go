@@ -0,0 +1,68 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+// newRegionTenancyAccess returns the access to the test tenancy configured in us-ashburn-1, with the given
+// custom domain.
+func newRegionTenancyAccess(t *testing.T, customDomain string) *TenancyAccess {
+	t.Helper()
+	config := testConfigProvider(t, "us-ashburn-1")
+	mc, err := monitoring.NewMonitoringClientWithConfigurationProvider(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic, err := identity.NewIdentityClientWithConfigurationProvider(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customDomain != "" {
+		mc.Host = "https://telemetry.us-ashburn-1." + customDomain
+	}
+	return NewTenancyAccess(mc, ic, config, customDomain)
+}
+
+func TestMonitoringClientForRegion(t *testing.T) {
+	tests := []struct {
+		name         string
+		customDomain string
+		region       string
+		wantHost     string
+	}{
+		{name: "no region", region: "", wantHost: "https://telemetry.us-ashburn-1.oraclecloud.com"},
+		{name: "all regions", region: constants.ALL_REGION, wantHost: "https://telemetry.us-ashburn-1.oraclecloud.com"},
+		{name: "configured region", region: "us-ashburn-1", wantHost: "https://telemetry.us-ashburn-1.oraclecloud.com"},
+		{name: "other region", region: "eu-frankfurt-1", wantHost: "https://telemetry.eu-frankfurt-1.oraclecloud.com"},
+		{name: "other realm", region: "us-langley-1", wantHost: "https://telemetry.us-langley-1.oraclegovcloud.com"},
+		{name: "custom domain", customDomain: "example.com", region: "custom-region-1", wantHost: "https://telemetry.custom-region-1.example.com"},
+		{name: "custom domain of the configured region", customDomain: "example.com", region: "us-ashburn-1", wantHost: "https://telemetry.us-ashburn-1.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := newRegionTenancyAccess(t, tt.customDomain)
+			tenancyHost := ta.monitoringClient.Host
+			if got := ta.MonitoringClientForRegion(tt.region).Host; got != tt.wantHost {
+				t.Errorf("MonitoringClientForRegion() host = %v, want %v", got, tt.wantHost)
+			}
+			// the client of the region is reused
+			if got := ta.MonitoringClientForRegion(tt.region).Host; got != tt.wantHost {
+				t.Errorf("MonitoringClientForRegion() host of the reused client = %v, want %v", got, tt.wantHost)
+			}
+			if ta.monitoringClient.Host != tenancyHost {
+				t.Errorf("the tenancy client is re-targeted at %v, want %v", ta.monitoringClient.Host, tenancyHost)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/identity"
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+// setResourcePrincipalEnv sets the environment of a resource principal of the test tenancy, version 2.2 with the
+// token and the key given as values.
+func setResourcePrincipalEnv(t *testing.T) {
+	t.Helper()
+	claims, err := json.Marshal(map[string]interface{}{
+		"res_tenant": testTenancyOCID,
+		"res_type":   "fnfunc",
+		"exp":        time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	token := encode([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + encode(claims) + "." + encode([]byte("signature"))
+
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_VERSION", "2.2")
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_RPST", token)
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_PRIVATE_PEM", testPrivateKey(t))
+	t.Setenv("OCI_RESOURCE_PRINCIPAL_REGION", "us-ashburn-1")
+}
+
+func TestIsPrincipalEnvironment(t *testing.T) {
+	tests := []struct {
+		environment string
+		want        bool
+	}{
+		{environment: "OCI Instance", want: true},
+		{environment: "OCI Resource Principal", want: true},
+		{environment: "OCI Workload Identity", want: true},
+		{environment: "local", want: false},
+		{environment: "OCI Config File", want: false},
+		{environment: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			if got := isPrincipalEnvironment(tt.environment); got != tt.want {
+				t.Errorf("isPrincipalEnvironment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetConfigProviderResourcePrincipal(t *testing.T) {
+	t.Run("single tenancy", func(t *testing.T) {
+		setResourcePrincipalEnv(t)
+		o := newTestDatasource(t, "singletenancy")
+		o.settings.Environment = "OCI Resource Principal"
+
+		if err := o.getConfigProvider("OCI Resource Principal", "singletenancy", backend.DataSourceInstanceSettings{}); err != nil {
+			t.Fatalf("getConfigProvider() error = %v", err)
+		}
+		ta := o.getTenancyAccess(SingleTenancyKey)
+		if ta == nil {
+			t.Fatal("getConfigProvider() did not configure the single tenancy")
+		}
+		if tenancy, err := ta.config.TenancyOCID(); err != nil || tenancy != testTenancyOCID {
+			t.Errorf("TenancyOCID() = %v, %v, want the tenancy of the resource principal", tenancy, err)
+		}
+		if region, _ := ta.config.Region(); region != "us-ashburn-1" {
+			t.Errorf("Region() = %v, want the region of the resource principal", region)
+		}
+		if tenancy, err := o.FetchTenancyOCID(SingleTenancyKey); err != nil || tenancy != testTenancyOCID {
+			t.Errorf("FetchTenancyOCID() = %v, %v, want the tenancy of the resource principal", tenancy, err)
+		}
+	})
+
+	t.Run("multitenancy", func(t *testing.T) {
+		setResourcePrincipalEnv(t)
+		o := newTestDatasource(t, "multitenancy")
+		o.settings.Environment = "OCI Resource Principal"
+		o.settings.Xtenancies = []models.OCIXTenancySettings{{Name: "target", OCID: "ocid1.tenancy.oc1..target"}}
+
+		if err := o.getConfigProvider("OCI Resource Principal", "multitenancy", backend.DataSourceInstanceSettings{}); err != nil {
+			t.Fatalf("getConfigProvider() error = %v", err)
+		}
+		keys := []string{}
+		for key := range o.tenancyAccessSnapshot() {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if want := []string{"DEFAULT/" + testTenancyOCID, "target/ocid1.tenancy.oc1..target"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("tenancy accesses = %v, want %v", keys, want)
+		}
+	})
+
+	t.Run("no resource principal", func(t *testing.T) {
+		t.Setenv("OCI_RESOURCE_PRINCIPAL_VERSION", "")
+		o := newTestDatasource(t, "singletenancy")
+		err := o.getConfigProvider("OCI Resource Principal", "singletenancy", backend.DataSourceInstanceSettings{})
+		if err == nil || !strings.Contains(err.Error(), "resource principals") {
+			t.Errorf("getConfigProvider() error = %v, want an error with resource principals", err)
+		}
+	})
+
+	t.Run("not running in OKE", func(t *testing.T) {
+		setResourcePrincipalEnv(t)
+		t.Setenv("OCI_KUBERNETES_SERVICE_ACCOUNT_CERT_PATH", t.TempDir()+"/missing.crt")
+		o := newTestDatasource(t, "singletenancy")
+		err := o.getConfigProvider("OCI Workload Identity", "singletenancy", backend.DataSourceInstanceSettings{})
+		if err == nil || !strings.Contains(err.Error(), "OKE workload identity") {
+			t.Errorf("getConfigProvider() error = %v, want an error with OKE workload identity", err)
+		}
+	})
+}
+
+func TestFetchTenancyOCIDCrossTenancy(t *testing.T) {
+	const target = "ocid1.tenancy.oc1..target"
+
+	tests := []struct {
+		name        string
+		environment string
+		tenancyMode string
+		xtenancy    string
+		key         string
+		want        string
+		wantErr     bool
+	}{
+		{name: "principal", environment: "OCI Instance", tenancyMode: "singletenancy", key: SingleTenancyKey, want: testTenancyOCID},
+		{name: "principal with target tenancy", environment: "OCI Instance", tenancyMode: "singletenancy", xtenancy: target, key: SingleTenancyKey, want: target},
+		{name: "resource principal with target tenancy", environment: "OCI Resource Principal", tenancyMode: "singletenancy", xtenancy: target, key: SingleTenancyKey, want: target},
+		{name: "user principal ignores target tenancy", environment: "local", tenancyMode: "singletenancy", xtenancy: target, key: SingleTenancyKey, want: testTenancyOCID},
+		{name: "multitenancy target", environment: "OCI Instance", tenancyMode: "multitenancy", key: "target/" + target, want: target},
+		{name: "key not configured", environment: "OCI Instance", tenancyMode: "multitenancy", key: "other/ocid1.tenancy.oc1..other", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestDatasource(t, tt.tenancyMode)
+			o.settings.Environment = tt.environment
+			o.settings.Xtenancy_0 = tt.xtenancy
+			ta := NewTenancyAccess(monitoring.MonitoringClient{}, identity.IdentityClient{}, testConfigProvider(t, "us-ashburn-1"), "")
+			if tt.tenancyMode == "multitenancy" {
+				o.setTenancyAccess("target/"+target, ta)
+			} else {
+				o.setTenancyAccess(SingleTenancyKey, ta)
+			}
+
+			got, err := o.FetchTenancyOCID(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchTenancyOCID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FetchTenancyOCID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPrincipalTenancyAccess(t *testing.T) {
+	tests := []struct {
+		name        string
+		tenancyMode string
+		xtenancies  []models.OCIXTenancySettings
+		wantKeys    []string
+		wantErr     string
+	}{
+		{name: "single tenancy", tenancyMode: "singletenancy", wantKeys: []string{SingleTenancyKey}},
+		{
+			name:        "target tenancies",
+			tenancyMode: "multitenancy",
+			xtenancies:  []models.OCIXTenancySettings{{Name: "prod", OCID: "ocid1.tenancy.oc1..prod"}, {OCID: "ocid1.tenancy.oc1..unnamed"}},
+			wantKeys:    []string{"DEFAULT/" + testTenancyOCID, "prod/ocid1.tenancy.oc1..prod", "tenancy-2/ocid1.tenancy.oc1..unnamed"},
+		},
+		{name: "no target tenancy", tenancyMode: "multitenancy", wantErr: "requires at least one target tenancy"},
+		{
+			name:        "name with a slash",
+			tenancyMode: "multitenancy",
+			xtenancies:  []models.OCIXTenancySettings{{Name: "a/b", OCID: "ocid1.tenancy.oc1..prod"}},
+			wantErr:     "can not contain /",
+		},
+		{
+			name:        "not a tenancy OCID",
+			tenancyMode: "multitenancy",
+			xtenancies:  []models.OCIXTenancySettings{{Name: "prod", OCID: "ocid1.compartment.oc1..prod"}},
+			wantErr:     "invalid OCID of target tenancy prod",
+		},
+		{
+			name:        "duplicate target tenancy",
+			tenancyMode: "multitenancy",
+			xtenancies:  []models.OCIXTenancySettings{{Name: "prod", OCID: "ocid1.tenancy.oc1..prod"}, {Name: "prod", OCID: "ocid1.tenancy.oc1..prod"}},
+			wantErr:     "duplicate target tenancy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestDatasource(t, tt.tenancyMode)
+			o.settings.Environment = "OCI Instance"
+			o.settings.Xtenancies = tt.xtenancies
+
+			err := o.setPrincipalTenancyAccess(testConfigProvider(t, "us-ashburn-1"), "Instance Principal")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("setPrincipalTenancyAccess() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setPrincipalTenancyAccess() error = %v", err)
+			}
+			keys := []string{}
+			for key := range o.tenancyAccessSnapshot() {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("tenancy accesses = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -5,12 +5,16 @@ package plugin
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
 
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
 	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
 )
 
@@ -19,13 +23,11 @@ import (
 // 1. Logs the initiation of the query.
 // 2. Creates a DataResponse object to hold the query results.
 // 3. Unmarshals the JSON query into a QueryModel object.
-// 4. Validates the presence of mandatory fields (TenancyOCID and Interval) in the query.
-// 5. Constructs a MetricsDataRequest object with the necessary details for fetching metrics data.
-// 6. Creates a data frame to store the response data.
-// 7. Fetches metric data points if the region, compartment, and namespace are valid.
-// 8. Handles errors during data fetching and returns the response if any errors occur.
-// 9. Plots the x-axis with time as the unit and processes metric data values to populate the data frame.
-// 10. Adds the data frame to the response and returns the response.
+// 4. Normalizes the query with normalizeQuery: the placeholders are resolved, the multi-value template variables
+// are expanded into one or more queries and the unresolved template variables are reported as errors.
+// 5. Runs each query, alarm history, alarm status and audit events queries are handed over to alarmHistoryQuery,
+// alarmStatusQuery and auditEventsQuery and the metric queries to metricQuery.
+// 6. Adds the frames of the queries to the response and returns the response.
 //
 // Parameters:
 // - ctx: The context for the query execution.
@@ -40,33 +42,80 @@ func (ocidx *OCIDatasource) query(ctx context.Context, pCtx backend.PluginContex
 	// Creating the Data response for query
 	response := backend.DataResponse{}
 
-	// Unmarshal the json into oci queryModel
-	qm := &models.QueryModel{}
+	// Unmarshal the json into oci queryModel, queries are in builder mode unless stated otherwise as in the query editor
+	qm := &models.QueryModel{RawQuery: true}
 	response.Error = jsoniter.Unmarshal(query.JSON, &qm)
 	if response.Error != nil {
 		return response
 	}
 
-	// checking if the query has valid tenancy detail
-	if qm.TenancyOCID == "" {
-		backend.Logger.Warn("plugin.query", "query", "tenancy ocid is mandatory but it is not present in query")
+	// alert rules and reports may send placeholders and template variables the query editor would have resolved
+	queries, err := ocidx.normalizeQuery(qm, query.QueryType)
+	if err != nil {
+		response.Error = err
 		return response
 	}
 
-	// checking if the query has valid Interval detail
-	if qm.Interval == "" {
-		backend.Logger.Warn("plugin.query", "query", "Interval is mandatory but it is not present in query")
-		return response
+	for _, q := range queries {
+		var queryResponse backend.DataResponse
+		switch query.QueryType {
+		case constants.QUERYTYPE_ALARM_HISTORY:
+			queryResponse = ocidx.alarmHistoryQuery(ctx, q, query)
+		case constants.QUERYTYPE_ALARM_STATUS:
+			queryResponse = ocidx.alarmStatusQuery(ctx, q)
+		case constants.QUERYTYPE_AUDIT_EVENTS:
+			queryResponse = ocidx.auditEventsQuery(ctx, q, query)
+		default:
+			queryResponse = ocidx.metricQuery(ctx, q, query)
+		}
+		if queryResponse.Error != nil {
+			return queryResponse
+		}
+		response.Frames = append(response.Frames, queryResponse.Frames...)
+	}
+
+	return response
+}
+
+// metricQuery handles the metric queries. It performs the following steps:
+// 1. Builds the MQL of builder mode queries.
+// 2. Resolves the auto interval and constructs a MetricsDataRequest object with the necessary details for fetching metrics data,
+// the whole tenancy, sub-compartments included, being queried when no compartment is selected.
+// 3. Fetches metric data points, failed regions are reported as notices when other regions succeeded.
+// 4. Names and labels the series, with the legend format when the query has one, and sets the unit and
+// description of the metric on the series, see getMetricMetadata.
+// 5. Returns the series as timeseries-multi or timeseries-wide frames, see metricFrames.
+//
+// Parameters:
+// - ctx: The context for the query execution.
+// - qm: The normalized query model.
+// - query: The data query, its time range and interval drive the aggregation.
+//
+// Returns:
+// - backend.DataResponse: The response holding the data frame or an error if the query fails.
+func (ocidx *OCIDatasource) metricQuery(ctx context.Context, qm *models.QueryModel, query backend.DataQuery) backend.DataResponse {
+	response := backend.DataResponse{}
+
+	// builder mode queries are built from their fields, so that every client sends the same MQL
+	if isBuilderQuery(qm) {
+		qm.QueryText, response.Error = buildQueryText(qm)
+		if response.Error != nil {
+			return response
+		}
 	}
 
+	// the auto interval, or a missing one, is chosen from the time range of the query
+	queryText, interval := resolveQueryText(qm.QueryText, resolveInterval(qm.Interval, query))
+
 	metricsDataRequest := models.MetricsDataRequest{
 		TenancyOCID:     qm.TenancyOCID,
 		CompartmentOCID: qm.CompartmentOCID,
 		CompartmentName: qm.CompartmentName,
 		Region:          qm.Region,
 		Namespace:       qm.Namespace,
-		QueryText:       qm.QueryText,
-		Interval:        qm.Interval[1 : len(qm.Interval)-1],
+		QueryText:       queryText,
+		Interval:        interval,
+		FillMode:        qm.FillMode,
 		ResourceGroup:   qm.ResourceGroup,
 		DimensionValues: qm.DimensionValues,
 		LegendFormat:    qm.LegendFormat,
@@ -76,24 +125,14 @@ func (ocidx *OCIDatasource) query(ctx context.Context, pCtx backend.PluginContex
 		EndTime:         query.TimeRange.To.UTC(),
 	}
 
-	// create data frame response
-	frame := data.NewFrame("response").SetMeta(&data.FrameMeta{ExecutedQueryString: qm.QueryText})
-	var err error
-	var metricDataValues []models.OCIMetricDataPoints
-	var times []time.Time
-
-	if (qm.Region != "" && qm.Region != "select region") &&
-		(qm.CompartmentOCID != "" && qm.CompartmentOCID != "select compartment") &&
-		(qm.Namespace != "" && qm.Namespace != "select namespace") {
-		times, metricDataValues, err = ocidx.GetMetricDataPoints(ctx, metricsDataRequest, qm.TenancyOCID)
-	}
+	times, metricDataValues, regionErrors, err := ocidx.GetMetricDataPoints(ctx, metricsDataRequest, qm.TenancyOCID)
 	if err != nil {
 		response.Error = err
 		return response
 	}
 
-	// plotting the x axis with time as unit
-	frame.Fields = append(frame.Fields, data.NewField("time", nil, times))
+	series := make([]metricSeries, 0, len(metricDataValues))
+	withUnit := keepsMetricUnit(queryText)
 	var name string
 	for _, metricDataValue := range metricDataValues {
 		name = metricDataValue.ResourceName
@@ -139,13 +178,160 @@ func (ocidx *OCIDatasource) query(ctx context.Context, pCtx backend.PluginContex
 				}
 			}
 		}
-		frame.Fields = append(frame.Fields,
-			data.NewField(name, dl, metricDataValue.DataPoints),
-		)
+		series = append(series, metricSeries{
+			name:   name,
+			metric: metricDataValue.MetricName,
+			labels: dl,
+			config: metricFieldConfig(name, ocidx.getMetricMetadata(qm.Namespace, metricDataValue.MetricName, metricDataValue.Metadata), withUnit),
+			values: metricDataValue.DataPoints,
+		})
 	}
 
+	// reporting the regions which could not be fetched, the other regions are still plotted
+	meta := data.FrameMeta{ExecutedQueryString: queryText, Notices: regionNotices(regionErrors)}
+
 	// add the frames to the response
-	response.Frames = append(response.Frames, frame)
+	response.Frames = append(response.Frames, metricFrames(qm.FrameFormat, times, series, meta)...)
 
 	return response
 }
+
+// metricSeries is a series of a metric query, named and labelled for display.
+type metricSeries struct {
+	// name is the display name of the series, the resource name or the legend format.
+	name string
+	// metric is the name of the metric.
+	metric string
+	labels data.Labels
+	// config is the display name, unit and description of the values.
+	config *data.FieldConfig
+	// values are the values of the series at the times of the query, nil where the series has no datapoint.
+	values []*float64
+}
+
+// metricFrames returns the frames of the series of a metric query following the data plane contract of Grafana.
+// The frames are timeseries-multi by default, one frame per series, or a single timeseries-wide frame sharing the
+// time field when the frame format is constants.FRAME_FORMAT_WIDE. In both formats the series keep every time of
+// the query, the missing datapoints being null, so that panels break the lines where the data is missing. The
+// value fields are named after the metric, the series being told apart by their labels, and carry the
+// configuration of their series.
+//
+// Parameters:
+// - frameFormat: The frame format of the query.
+// - times: The times of the query, in chronological order.
+// - series: The series of the query.
+// - meta: The metadata of the frames, the notices are only set on the first frame.
+//
+// Returns:
+// - data.Frames: The frames of the query, at least one so that the executed query is always reported.
+func metricFrames(frameFormat string, times []time.Time, series []metricSeries, meta data.FrameMeta) data.Frames {
+	valueField := func(s metricSeries, values []*float64) *data.Field {
+		field := data.NewField(s.metric, s.labels, values)
+		if s.config != nil {
+			field.SetConfig(s.config)
+		}
+		return field
+	}
+
+	if frameFormat == constants.FRAME_FORMAT_WIDE {
+		meta.Type = data.FrameTypeTimeSeriesWide
+		meta.TypeVersion = data.FrameTypeVersion{0, 1}
+
+		frame := data.NewFrame("response", data.NewField(data.TimeSeriesTimeFieldName, nil, times)).SetMeta(&meta)
+		for _, s := range series {
+			frame.Fields = append(frame.Fields, valueField(s, s.values))
+		}
+		return data.Frames{frame}
+	}
+
+	meta.Type = data.FrameTypeTimeSeriesMulti
+	meta.TypeVersion = data.FrameTypeVersion{0, 1}
+
+	// an empty multi response is a single frame without fields
+	if len(series) == 0 {
+		return data.Frames{data.NewFrame("response").SetMeta(&meta)}
+	}
+
+	frames := make(data.Frames, 0, len(series))
+	for i, s := range series {
+		frameMeta := meta
+		if i > 0 {
+			frameMeta.Notices = nil
+		}
+		frames = append(frames, data.NewFrame(s.name,
+			data.NewField(data.TimeSeriesTimeFieldName, nil, times),
+			valueField(s, s.values),
+		).SetMeta(&frameMeta))
+	}
+
+	return frames
+}
+
+// regionNotices converts the per-region errors returned by GetMetricDataPoints into frame notices.
+// The notices are sorted by region so that the panel shows them in a stable order.
+//
+// Parameters:
+// - regionErrors: The errors keyed by region.
+//
+// Returns:
+// - []data.Notice: A warning notice for each failed region.
+func regionNotices(regionErrors map[string]error) []data.Notice {
+	return regionNoticesFor("metric data", regionErrors)
+}
+
+// regionNoticesFor converts per-region errors into frame notices about what could not be fetched.
+//
+// Parameters:
+// - subject: What could not be fetched, e.g. "metric data".
+// - regionErrors: The errors keyed by region.
+//
+// Returns:
+// - []data.Notice: A warning notice for each failed region, sorted by region.
+func regionNoticesFor(subject string, regionErrors map[string]error) []data.Notice {
+	regions := make([]string, 0, len(regionErrors))
+	for region := range regionErrors {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	notices := make([]data.Notice, 0, len(regions))
+	for _, region := range regions {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     subject + " could not be fetched for region " + region + ": " + regionErrors[region].Error(),
+		})
+	}
+
+	return notices
+}
+
+// regionsError combines the errors of the regions that failed into a single error, sorted by region as the
+// notices of regionNoticesFor. The error of a single failed region is returned as is.
+//
+// Parameters:
+// - message: What failed, e.g. "metric data could not be fetched from any region".
+// - regionErrors: The errors keyed by region.
+//
+// Returns:
+// - error: The combined error, nil when no region failed.
+func regionsError(message string, regionErrors map[string]error) error {
+	regions := make([]string, 0, len(regionErrors))
+	for region := range regionErrors {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	switch len(regions) {
+	case 0:
+		return nil
+	case 1:
+		return regionErrors[regions[0]]
+	}
+
+	messages := make([]string, 0, len(regions))
+	for _, region := range regions {
+		messages = append(messages, region+": "+regionErrors[region].Error())
+	}
+
+	return errors.New(message + ", " + strings.Join(messages, "; "))
+}
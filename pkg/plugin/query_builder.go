@@ -0,0 +1,125 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/mql"
+)
+
+// isBuilderQuery tells whether the MQL of a query is to be built from its structured fields: the query is
+// in builder mode, rawQuery being true, and a metric is selected.
+func isBuilderQuery(qm *models.QueryModel) bool {
+	return qm.RawQuery && qm.Metric != "" && qm.Metric != constants.DEFAULT_METRIC_PLACEHOLDER
+}
+
+// buildQueryText builds the MQL query of a builder mode query from its metric, interval, dimensions,
+// group by and statistic, as Metric[interval]{dimensions}.groupBy(dimension).statistic.
+//
+// The interval is [auto] when the query has none or uses the auto interval, it is replaced when the query runs.
+// The statistic defaults to constants.DEFAULT_STATISTIC.
+//
+// Parameters:
+//   - qm: The query model.
+//
+// Returns:
+//   - string: The MQL query.
+//   - error: An error if a dimension or the statistic is not valid.
+func buildQueryText(qm *models.QueryModel) (string, error) {
+	query := &mql.MetricQuery{
+		Metric:   qm.Metric,
+		Interval: strings.Trim(qm.Interval, "[] "),
+	}
+	if isAutoInterval(qm.Interval) {
+		query.Interval = constants.AUTO_INTERVAL
+	}
+
+	// dimensions are selected as key="value", or with the operators !=, =~ and !~
+	for _, dimensionValue := range qm.DimensionValues {
+		dimension, err := parseDimension(dimensionValue)
+		if err != nil {
+			return "", err
+		}
+		query.Dimensions = append(query.Dimensions, dimension)
+	}
+
+	// the group by is either grouping(), or the dimensions to group by, with or without groupBy( )
+	if groupBy := strings.TrimSpace(qm.GroupBy); groupBy != "" && groupBy != constants.DEFAULT_GROUPBY_PLACEHOLDER {
+		if strings.TrimSuffix(groupBy, "()") == "grouping" {
+			query.Functions = append(query.Functions, mql.Function{Name: "grouping", Args: []string{}})
+		} else if dimensions := splitArgs(strings.TrimSuffix(strings.TrimPrefix(groupBy, "groupBy("), ")")); len(dimensions) > 0 {
+			query.Functions = append(query.Functions, mql.Function{Name: "groupBy", Args: dimensions})
+		}
+	}
+
+	statistic := strings.TrimSpace(qm.Statistic)
+	if statistic == "" || statistic == constants.DEFAULT_STATISTIC_PLACEHOLDER {
+		statistic = constants.DEFAULT_STATISTIC
+	}
+	function, err := parseFunction(statistic)
+	if err != nil {
+		return "", err
+	}
+	query.Functions = append(query.Functions, function)
+
+	return query.String(), nil
+}
+
+// dimensionOperators are the dimension operators of MQL, the two characters ones first.
+var dimensionOperators = []string{"!=", "=~", "!~", "="}
+
+// parseDimension reads a dimension selected in the query editor, such as key="value" or key=~"value1|value2".
+func parseDimension(text string) (mql.DimensionFilter, error) {
+	i := strings.IndexAny(text, "=!")
+	if i <= 0 || strings.TrimSpace(text[:i]) == "" {
+		return mql.DimensionFilter{}, errors.New("invalid dimension " + text + ", expected key=\"value\"")
+	}
+
+	operator := "="
+	for _, op := range dimensionOperators {
+		if strings.HasPrefix(text[i:], op) {
+			operator = op
+			break
+		}
+	}
+	if !strings.HasPrefix(text[i:], operator) {
+		return mql.DimensionFilter{}, errors.New("invalid dimension " + text + ", expected key=\"value\"")
+	}
+
+	return mql.DimensionFilter{
+		Name:     strings.TrimSpace(text[:i]),
+		Operator: operator,
+		Value:    strings.Trim(strings.TrimSpace(text[i+len(operator):]), "\""),
+	}, nil
+}
+
+// parseFunction reads a function selected in the query editor, such as mean(), percentile(.90) or mean.
+func parseFunction(text string) (mql.Function, error) {
+	name, args, hasArgs := strings.Cut(text, "(")
+	name = strings.TrimSpace(name)
+	if name == "" || (hasArgs && !strings.HasSuffix(args, ")")) {
+		return mql.Function{}, errors.New("invalid statistic " + text)
+	}
+
+	return mql.Function{Name: name, Args: splitArgs(strings.TrimSuffix(args, ")"))}, nil
+}
+
+// splitArgs splits comma separated arguments, dropping the empty ones.
+func splitArgs(text string) []string {
+	args := []string{}
+	for _, arg := range strings.Split(text, ",") {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			args = append(args, arg)
+		}
+	}
+
+	return args
+}
@@ -0,0 +1,128 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/mql"
+)
+
+func TestBuildQueryText(t *testing.T) {
+	tests := []struct {
+		name    string
+		qm      models.QueryModel
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			qm:   models.QueryModel{Metric: "CpuUtilization"},
+			want: "CpuUtilization[auto].avg()",
+		},
+		{
+			name: "placeholders",
+			qm:   models.QueryModel{Metric: "CpuUtilization", Interval: "select interval", Statistic: "select aggregation", GroupBy: "select option (optional)"},
+			want: "CpuUtilization[auto].avg()",
+		},
+		{
+			name: "interval and statistic",
+			qm:   models.QueryModel{Metric: "CpuUtilization", Interval: "[5m]", Statistic: "max()"},
+			want: "CpuUtilization[5m].max()",
+		},
+		{
+			name: "statistic without parentheses",
+			qm:   models.QueryModel{Metric: "CpuUtilization", Interval: "1h", Statistic: "mean"},
+			want: "CpuUtilization[1h].mean()",
+		},
+		{
+			name: "percentile",
+			qm:   models.QueryModel{Metric: "CpuUtilization", Interval: "[1m]", Statistic: "percentile(.90)"},
+			want: "CpuUtilization[1m].percentile(.90)",
+		},
+		{
+			name: "dimensions",
+			qm: models.QueryModel{
+				Metric:          "CpuUtilization",
+				Interval:        "[1m]",
+				DimensionValues: []string{`resourceId="ocid1.instance.oc1..a"`, `availabilityDomain != "AD-1"`, `shape=~"VM.*|BM.*"`},
+			},
+			want: `CpuUtilization[1m]{resourceId = "ocid1.instance.oc1..a", availabilityDomain != "AD-1", shape =~ "VM.*|BM.*"}.avg()`,
+		},
+		{
+			name: "group by dimensions",
+			qm:   models.QueryModel{Metric: "CpuUtilization", Interval: "[1m]", GroupBy: "groupBy(availabilityDomain, shape)", Statistic: "sum()"},
+			want: "CpuUtilization[1m].groupBy(availabilityDomain, shape).sum()",
+		},
+		{
+			name: "group by dimension without groupBy",
+			qm:   models.QueryModel{Metric: "CpuUtilization", Interval: "[1m]", GroupBy: "shape"},
+			want: "CpuUtilization[1m].groupBy(shape).avg()",
+		},
+		{
+			name: "grouping",
+			qm:   models.QueryModel{Metric: "CpuUtilization", Interval: "[1m]", GroupBy: "grouping()"},
+			want: "CpuUtilization[1m].grouping().avg()",
+		},
+		{
+			name:    "invalid dimension",
+			qm:      models.QueryModel{Metric: "CpuUtilization", DimensionValues: []string{"resourceId"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid statistic",
+			qm:      models.QueryModel{Metric: "CpuUtilization", Statistic: "percentile(.90"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildQueryText(&tt.qm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildQueryText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("buildQueryText() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDimension(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    mql.DimensionFilter
+		wantErr bool
+	}{
+		{name: "equal", text: `resourceId="ocid1"`, want: mql.DimensionFilter{Name: "resourceId", Operator: "=", Value: "ocid1"}},
+		{name: "unquoted value", text: `shape=VM.Standard2.1`, want: mql.DimensionFilter{Name: "shape", Operator: "=", Value: "VM.Standard2.1"}},
+		{name: "spaces", text: ` shape  !=  "VM" `, want: mql.DimensionFilter{Name: "shape", Operator: "!=", Value: "VM"}},
+		{name: "regular expression", text: `shape=~"VM.*|BM.*"`, want: mql.DimensionFilter{Name: "shape", Operator: "=~", Value: "VM.*|BM.*"}},
+		{name: "not regular expression", text: `shape!~"VM.*"`, want: mql.DimensionFilter{Name: "shape", Operator: "!~", Value: "VM.*"}},
+		{name: "equal sign in value", text: `query="a=b"`, want: mql.DimensionFilter{Name: "query", Operator: "=", Value: "a=b"}},
+		{name: "empty value", text: `shape=""`, want: mql.DimensionFilter{Name: "shape", Operator: "=", Value: ""}},
+		{name: "no operator", text: `shape`, wantErr: true},
+		{name: "no name", text: `="VM"`, wantErr: true},
+		{name: "blank name", text: ` ="VM"`, wantErr: true},
+		{name: "unknown operator", text: `shape!"VM"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDimension(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDimension() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDimension() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+const summarizeMetricsDataPath = "/20180401/metrics/actions/summarizeMetricsData"
+
+// metricDataResponse is a SummarizeMetricsData response with a datapoint per minute from t0 for each series,
+// the series being keyed by resource id.
+func metricDataResponse(t0 time.Time, series map[string][]float64) []map[string]interface{} {
+	items := []map[string]interface{}{}
+	for resourceID, values := range series {
+		datapoints := []map[string]interface{}{}
+		for i, value := range values {
+			datapoints = append(datapoints, map[string]interface{}{
+				"timestamp": t0.Add(time.Duration(i) * time.Minute).Format(time.RFC3339),
+				"value":     value,
+			})
+		}
+		items = append(items, map[string]interface{}{
+			"namespace":            "oci_computeagent",
+			"name":                 "CpuUtilization",
+			"dimensions":           map[string]string{"resourceId": resourceID},
+			"aggregatedDatapoints": datapoints,
+		})
+	}
+	return items
+}
+
+func TestMetricQueryCompartment(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		compartment     string
+		wantCompartment string
+		wantInSubtree   string
+	}{
+		{name: "no compartment", compartment: "", wantCompartment: testTenancyOCID, wantInSubtree: "true"},
+		{name: "compartment placeholder", compartment: "select compartment", wantCompartment: testTenancyOCID, wantInSubtree: "true"},
+		{name: "selected compartment", compartment: "ocid1.compartment.oc1..a", wantCompartment: "ocid1.compartment.oc1..a", wantInSubtree: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeOCI(t)
+			fake.handleJSON("POST "+summarizeMetricsDataPath, http.StatusOK, metricDataResponse(t0, map[string][]float64{"instance-a": {1, 2}}))
+
+			o := newTestDatasource(t, "singletenancy")
+			o.setTenancyAccess(SingleTenancyKey, newFakeTenancyAccess(t, []string{"us-ashburn-1"}, []*fakeOCI{fake}))
+
+			response := o.query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+				RefID:     "A",
+				TimeRange: backend.TimeRange{From: t0, To: t0.Add(time.Hour)},
+				JSON: []byte(`{"tenancy": "select tenancy", "compartment": "` + tt.compartment + `", "region": "us-ashburn-1",` +
+					` "namespace": "oci_computeagent", "queryText": "CpuUtilization[1m].mean()", "rawQuery": false}`),
+			})
+			if response.Error != nil {
+				t.Fatalf("query() error = %v", response.Error)
+			}
+			if len(response.Frames) != 1 {
+				t.Fatalf("query() returned %d frames, want 1", len(response.Frames))
+			}
+
+			requests := fake.requestsTo(summarizeMetricsDataPath)
+			if len(requests) != 1 {
+				t.Fatalf("SummarizeMetricsData called %d times, want 1", len(requests))
+			}
+			if got := requests[0].query.Get("compartmentId"); got != tt.wantCompartment {
+				t.Errorf("compartmentId = %v, want %v", got, tt.wantCompartment)
+			}
+			if got := requests[0].query.Get("compartmentIdInSubtree"); got != tt.wantInSubtree {
+				t.Errorf("compartmentIdInSubtree = %v, want %v", got, tt.wantInSubtree)
+			}
+		})
+	}
+}
+
+func TestRegionsError(t *testing.T) {
+	failure := errors.New("failure")
+
+	tests := []struct {
+		name         string
+		regionErrors map[string]error
+		want         string
+	}{
+		{name: "no region", regionErrors: map[string]error{}, want: ""},
+		{name: "single region", regionErrors: map[string]error{"us-ashburn-1": failure}, want: "failure"},
+		{
+			name:         "regions sorted",
+			regionErrors: map[string]error{"us-ashburn-1": failure, "ap-tokyo-1": errors.New("timeout"), "eu-frankfurt-1": failure},
+			want:         "nothing fetched, ap-tokyo-1: timeout; eu-frankfurt-1: failure; us-ashburn-1: failure",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := regionsError("nothing fetched", tt.regionErrors)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("regionsError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricFrames(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{t0, t0.Add(time.Minute)}
+	value := func(v float64) *float64 {
+		return &v
+	}
+	series := []metricSeries{
+		{name: "instance-a", metric: "CpuUtilization", labels: data.Labels{"resourceId": "a"}, config: &data.FieldConfig{Unit: "percent"}, values: []*float64{value(1), nil}},
+		{name: "instance-b", metric: "CpuUtilization", labels: data.Labels{"resourceId": "b"}, values: []*float64{value(3), value(4)}},
+	}
+	notices := []data.Notice{{Severity: data.NoticeSeverityWarning, Text: "region failed"}}
+	meta := data.FrameMeta{ExecutedQueryString: "CpuUtilization[1m].mean()", Notices: notices}
+
+	t.Run("multi", func(t *testing.T) {
+		frames := metricFrames(constants.FRAME_FORMAT_MULTI, times, series, meta)
+		if len(frames) != 2 {
+			t.Fatalf("metricFrames() returned %d frames, want a frame per series", len(frames))
+		}
+		for i, frame := range frames {
+			if frame.Name != series[i].name || len(frame.Fields) != 2 {
+				t.Errorf("frame %d = %v with %d fields, want %v with the time and value fields", i, frame.Name, len(frame.Fields), series[i].name)
+				continue
+			}
+			if frame.Meta.Type != data.FrameTypeTimeSeriesMulti || frame.Meta.ExecutedQueryString != meta.ExecutedQueryString {
+				t.Errorf("frame %d meta = %+v, want the timeseries-multi type and the executed query", i, frame.Meta)
+			}
+			if field := frame.Fields[1]; field.Name != "CpuUtilization" || !reflect.DeepEqual(field.Labels, series[i].labels) || field.Len() != len(times) {
+				t.Errorf("frame %d value field = %v %v with %d values, want the metric, the labels and every time", i, field.Name, field.Labels, field.Len())
+			}
+		}
+		if !reflect.DeepEqual(frames[0].Meta.Notices, notices) || frames[1].Meta.Notices != nil {
+			t.Errorf("notices = %v and %v, want the notices on the first frame only", frames[0].Meta.Notices, frames[1].Meta.Notices)
+		}
+		if config := frames[0].Fields[1].Config; config == nil || config.Unit != "percent" {
+			t.Errorf("value field config = %v, want the config of the series", config)
+		}
+		if missing := frames[0].Fields[1].At(1).(*float64); missing != nil {
+			t.Errorf("missing datapoint = %v, want null", *missing)
+		}
+	})
+
+	t.Run("wide", func(t *testing.T) {
+		frames := metricFrames(constants.FRAME_FORMAT_WIDE, times, series, meta)
+		if len(frames) != 1 {
+			t.Fatalf("metricFrames() returned %d frames, want a single frame", len(frames))
+		}
+		frame := frames[0]
+		if frame.Meta.Type != data.FrameTypeTimeSeriesWide || !reflect.DeepEqual(frame.Meta.Notices, notices) {
+			t.Errorf("frame meta = %+v, want the timeseries-wide type and the notices", frame.Meta)
+		}
+		if len(frame.Fields) != 3 || frame.Fields[0].Name != data.TimeSeriesTimeFieldName {
+			t.Fatalf("frame has %d fields, want the shared time field and a value field per series", len(frame.Fields))
+		}
+		for i, s := range series {
+			if field := frame.Fields[i+1]; !reflect.DeepEqual(field.Labels, s.labels) || field.Len() != len(times) {
+				t.Errorf("value field %d = %v with %d values, want the labels %v and every time", i, field.Labels, field.Len(), s.labels)
+			}
+		}
+	})
+
+	t.Run("empty response", func(t *testing.T) {
+		for _, frameFormat := range []string{constants.FRAME_FORMAT_MULTI, constants.FRAME_FORMAT_WIDE} {
+			frames := metricFrames(frameFormat, nil, nil, meta)
+			if len(frames) != 1 || frames[0].Meta == nil || frames[0].Meta.ExecutedQueryString != meta.ExecutedQueryString {
+				t.Errorf("metricFrames(%v) = %v, want a single frame reporting the executed query", frameFormat, frames)
+			}
+			if !reflect.DeepEqual(frames[0].Meta.Notices, notices) {
+				t.Errorf("metricFrames(%v) notices = %v, want %v", frameFormat, frames[0].Meta.Notices, notices)
+			}
+		}
+		if frames := metricFrames(constants.FRAME_FORMAT_MULTI, nil, nil, meta); len(frames[0].Fields) != 0 {
+			t.Errorf("empty multi frame has %d fields, want none", len(frames[0].Fields))
+		}
+	})
+}
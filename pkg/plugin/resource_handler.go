@@ -12,6 +12,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 
 	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/mql"
 )
 
 // rootRequest defines the structure for requests that only require a tenancy OCID.
@@ -52,6 +53,18 @@ type tagRequest struct {
 	Namespace       string `json:"namespace"`
 }
 
+// validateRequest defines the structure for requests that require a MQL query.
+type validateRequest struct {
+	Query string `json:"query"`
+}
+
+// validateResponse defines the result of the validation of a MQL query.
+type validateResponse struct {
+	Valid bool       `json:"valid"`
+	Query string     `json:"query,omitempty"`
+	Error *mql.Error `json:"error,omitempty"`
+}
+
 // registerRoutes registers the HTTP handlers for various resource endpoints.
 //
 // Parameters:
@@ -64,6 +77,7 @@ func (ocidx *OCIDatasource) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/resourcegroups", ocidx.GetResourceGroupHandler)
 	mux.HandleFunc("/dimensions", ocidx.GetDimensionsHandler)
 	mux.HandleFunc("/tags", ocidx.GetTagsHandler)
+	mux.HandleFunc("/validate", ocidx.ValidateQueryHandler)
 }
 
 // GetTenanciesHandler handles requests to list tenancies.
@@ -242,6 +256,41 @@ func (ocidx *OCIDatasource) GetTagsHandler(rw http.ResponseWriter, req *http.Req
 	writeResponse(rw, tags)
 }
 
+// ValidateQueryHandler handles requests to validate a MQL query before it is run.
+//
+// It expects a POST request with a JSON body containing the query. The response tells whether the query
+// is valid, with the query as it is rewritten when valid, or the located error when not.
+//
+// Parameters:
+//   - rw: http.ResponseWriter to write the response.
+//   - req: *http.Request representing the incoming request.
+func (ocidx *OCIDatasource) ValidateQueryHandler(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		respondWithError(rw, http.StatusMethodNotAllowed, "Invalid method", nil)
+		return
+	}
+
+	var vr validateRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&vr); err != nil {
+		backend.Logger.Error("plugin.resource_handler", "ValidateQueryHandler", err)
+		respondWithError(rw, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+
+	node, err := mql.Validate(vr.Query)
+	if err != nil {
+		mqlErr, ok := err.(*mql.Error)
+		if !ok {
+			respondWithError(rw, http.StatusInternalServerError, "Could not validate query", err)
+			return
+		}
+		writeResponse(rw, validateResponse{Valid: false, Error: mqlErr})
+		return
+	}
+
+	writeResponse(rw, validateResponse{Valid: true, Query: node.String()})
+}
+
 // writeResponse writes a successful JSON response to the http.ResponseWriter.
 //
 // Parameters:
@@ -0,0 +1,281 @@
+// Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+// Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/resourcesearch"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+// GetResourcesInfo returns the display name, compartment and tags of resources, keyed by resource OCID.
+//
+// The resources are looked up with structured queries of the Resource Search service, by batches of
+// constants.RESOURCE_SEARCH_BATCH_SIZE identifiers. Each resource is cached for constants.RESOURCE_INFO_CACHE_TTL,
+// resources not found are cached as well so that they are not searched again on every refresh.
+// Values that are not OCIDs are ignored.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - tenancyOCID: The tenancy of the query, as sent by the frontend.
+//   - region: The region of the resources.
+//   - resourceIDs: The OCIDs of the resources.
+//
+// Returns:
+//   - map[string]models.OCIResourceInfo: The resources found, keyed by lower case OCID.
+func (o *OCIDatasource) GetResourcesInfo(ctx context.Context, tenancyOCID string, region string, resourceIDs []string) map[string]models.OCIResourceInfo {
+	resourcesInfo := map[string]models.OCIResourceInfo{}
+
+	takey := o.GetTenancyAccessKey(tenancyOCID)
+	if len(takey) == 0 {
+		return resourcesInfo
+	}
+
+	var toSearch []string
+	seen := map[string]bool{}
+	for _, resourceID := range resourceIDs {
+		resourceID = strings.ToLower(resourceID)
+		if !strings.HasPrefix(resourceID, "ocid1.") || seen[resourceID] {
+			continue
+		}
+		seen[resourceID] = true
+
+		if cached, found := o.cache.Get(resourceInfoCacheKey(takey, region, resourceID)); found {
+			if info, ok := cached.(models.OCIResourceInfo); ok && info.ResourceType != "" {
+				resourcesInfo[resourceID] = info
+			}
+			continue
+		}
+		toSearch = append(toSearch, resourceID)
+	}
+	if len(toSearch) == 0 {
+		return resourcesInfo
+	}
+
+	ta, err := o.tenancyAccessFor(takey)
+	if err != nil {
+		backend.Logger.Error("client", "GetResourcesInfo", err)
+		return resourcesInfo
+	}
+	client, err := ta.SearchClientForRegion(region)
+	if err != nil {
+		backend.Logger.Error("client", "GetResourcesInfo", err)
+		return resourcesInfo
+	}
+
+	compartmentNames := map[string]string{}
+	for _, compartment := range o.GetCompartments(ctx, tenancyOCID) {
+		compartmentNames[compartment.OCID] = compartment.Name
+	}
+
+	for start := 0; start < len(toSearch); start += constants.RESOURCE_SEARCH_BATCH_SIZE {
+		end := start + constants.RESOURCE_SEARCH_BATCH_SIZE
+		if end > len(toSearch) {
+			end = len(toSearch)
+		}
+		batch := toSearch[start:end]
+
+		found, err := searchResources(ctx, client, batch)
+		if err != nil {
+			backend.Logger.Error("client", "GetResourcesInfo", "region "+region+": "+err.Error())
+			continue
+		}
+		for _, resourceID := range batch {
+			info, ok := found[resourceID]
+			if ok {
+				info.CompartmentName = compartmentNames[info.CompartmentID]
+				resourcesInfo[resourceID] = info
+			}
+			// resources not found are cached empty
+			o.cache.SetWithTTL(resourceInfoCacheKey(takey, region, resourceID), info, 1, constants.RESOURCE_INFO_CACHE_TTL)
+		}
+	}
+	o.cache.Wait()
+
+	return resourcesInfo
+}
+
+// resourceInfoCacheKey returns the cache key of the metadata of a resource.
+func resourceInfoCacheKey(takey string, region string, resourceID string) string {
+	return strings.Join([]string{takey, region, resourceID, constants.CACHE_KEY_RESOURCE_INFO}, "-")
+}
+
+// searchResources runs a structured search for the given resource OCIDs, following the pagination.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - client: The resource search client of the region.
+//   - resourceIDs: The OCIDs of the resources, at most constants.RESOURCE_SEARCH_BATCH_SIZE.
+//
+// Returns:
+//   - map[string]models.OCIResourceInfo: The resources found, keyed by lower case OCID.
+//   - error: An error if the search fails.
+func searchResources(ctx context.Context, client resourcesearch.ResourceSearchClient, resourceIDs []string) (map[string]models.OCIResourceInfo, error) {
+	conditions := make([]string, 0, len(resourceIDs))
+	for _, resourceID := range resourceIDs {
+		conditions = append(conditions, "identifier = '"+resourceID+"'")
+	}
+
+	req := resourcesearch.SearchResourcesRequest{
+		SearchDetails: resourcesearch.StructuredSearchDetails{
+			Query: common.String("query all resources where " + strings.Join(conditions, " || ")),
+		},
+	}
+
+	found := map[string]models.OCIResourceInfo{}
+	for page := 0; page < MaxPagesToFetch; page++ {
+		res, err := client.SearchResources(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range res.Items {
+			info := resourceInfo(item)
+			found[info.ResourceID] = info
+		}
+		if res.OpcNextPage == nil {
+			break
+		}
+		req.Page = res.OpcNextPage
+	}
+
+	return found, nil
+}
+
+// resourceInfo converts a resource summary of the Resource Search service.
+func resourceInfo(item resourcesearch.ResourceSummary) models.OCIResourceInfo {
+	info := models.OCIResourceInfo{
+		ResourceID:    strings.ToLower(stringValue(item.Identifier)),
+		ResourceType:  stringValue(item.ResourceType),
+		DisplayName:   stringValue(item.DisplayName),
+		CompartmentID: stringValue(item.CompartmentId),
+		FreeformTags:  item.FreeformTags,
+		DefinedTags:   map[string]map[string]string{},
+	}
+	for namespace, tags := range item.DefinedTags {
+		info.DefinedTags[namespace] = map[string]string{}
+		for key, value := range tags {
+			info.DefinedTags[namespace][key] = fmt.Sprint(value)
+		}
+	}
+
+	return info
+}
+
+// searchTaggedResources searches the resources of the types reporting the metrics of a namespace, all the
+// resources when the namespace is not a known service namespace. The compartments are searched by batches of
+// constants.RESOURCE_SEARCH_BATCH_SIZE.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - client: The resource search client of the region.
+//   - namespace: The metric namespace.
+//   - compartmentOCIDs: The compartments of the resources, the whole tenancy when empty.
+//
+// Returns:
+//   - []models.OCIResourceInfo: The resources found.
+//   - error: An error if the search fails.
+func searchTaggedResources(ctx context.Context, client resourcesearch.ResourceSearchClient, namespace string, compartmentOCIDs []string) ([]models.OCIResourceInfo, error) {
+	resourceTypes := "all"
+	if types, ok := constants.OCI_RESOURCE_TYPES[constants.OCI_NAMESPACES[namespace]]; ok {
+		resourceTypes = strings.Join(types, ", ")
+	}
+	query := "query " + resourceTypes + " resources"
+	if len(compartmentOCIDs) == 0 {
+		return searchAllResources(ctx, client, query)
+	}
+
+	var resourcesInfo []models.OCIResourceInfo
+	for start := 0; start < len(compartmentOCIDs); start += constants.RESOURCE_SEARCH_BATCH_SIZE {
+		end := start + constants.RESOURCE_SEARCH_BATCH_SIZE
+		if end > len(compartmentOCIDs) {
+			end = len(compartmentOCIDs)
+		}
+		conditions := make([]string, 0, end-start)
+		for _, compartmentOCID := range compartmentOCIDs[start:end] {
+			conditions = append(conditions, "compartmentId = '"+compartmentOCID+"'")
+		}
+
+		found, err := searchAllResources(ctx, client, query+" where "+strings.Join(conditions, " || "))
+		if err != nil {
+			return nil, err
+		}
+		resourcesInfo = append(resourcesInfo, found...)
+	}
+
+	return resourcesInfo, nil
+}
+
+// searchAllResources runs a structured search, following the pagination up to MaxPagesToFetch pages.
+//
+// Parameters:
+//   - ctx: The context for the request.
+//   - client: The resource search client of the region.
+//   - query: The structured query.
+//
+// Returns:
+//   - []models.OCIResourceInfo: The resources found.
+//   - error: An error if the search fails.
+func searchAllResources(ctx context.Context, client resourcesearch.ResourceSearchClient, query string) ([]models.OCIResourceInfo, error) {
+	req := resourcesearch.SearchResourcesRequest{
+		SearchDetails: resourcesearch.StructuredSearchDetails{Query: common.String(query)},
+		Limit:         common.Int(1000),
+	}
+
+	var resourcesInfo []models.OCIResourceInfo
+	for page := 0; page < MaxPagesToFetch; page++ {
+		res, err := client.SearchResources(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range res.Items {
+			resourcesInfo = append(resourcesInfo, resourceInfo(item))
+		}
+		if res.OpcNextPage == nil {
+			break
+		}
+		req.Page = res.OpcNextPage
+	}
+
+	return resourcesInfo, nil
+}
+
+// resourceInfoTags returns the tags of a resource: freeform tags keyed by their key,
+// defined tags keyed by <namespace>.<key>.
+func resourceInfoTags(info models.OCIResourceInfo) map[string]string {
+	tags := map[string]string{}
+	for key, value := range info.FreeformTags {
+		tags[key] = value
+	}
+	for namespace, definedTags := range info.DefinedTags {
+		for key, value := range definedTags {
+			tags[namespace+"."+key] = value
+		}
+	}
+
+	return tags
+}
+
+// resourceInfoLabels returns the labels describing a resource: resource_name, compartment,
+// the freeform tags as tags.<key> and the defined tags as tags.<namespace>.<key>.
+func resourceInfoLabels(info models.OCIResourceInfo) map[string]string {
+	labels := map[string]string{}
+	if info.DisplayName != "" {
+		labels["resource_name"] = info.DisplayName
+	}
+	if info.CompartmentName != "" {
+		labels["compartment"] = info.CompartmentName
+	}
+	for key, value := range resourceInfoTags(info) {
+		labels["tags."+key] = value
+	}
+
+	return labels
+}
@@ -0,0 +1,244 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+const searchResourcesPath = "/20180409/resources"
+
+// searchQueries returns the structured queries received by the search service.
+func searchQueries(t *testing.T, fake *fakeOCI) []string {
+	t.Helper()
+	queries := []string{}
+	for _, r := range fake.requestsTo(searchResourcesPath) {
+		var details struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal(r.body, &details); err != nil {
+			t.Fatal(err)
+		}
+		queries = append(queries, details.Query)
+	}
+	return queries
+}
+
+// searchItems returns the body of a search response holding the given resources, keyed by OCID, with
+// their compartment and freeform tags.
+func searchItems(resources map[string]map[string]string, compartments map[string]string) map[string]interface{} {
+	items := []map[string]interface{}{}
+	for id, tags := range resources {
+		items = append(items, map[string]interface{}{
+			"identifier":    id,
+			"resourceType":  "Instance",
+			"displayName":   "name of " + id,
+			"compartmentId": compartments[id],
+			"freeformTags":  tags,
+		})
+	}
+	return map[string]interface{}{"items": items}
+}
+
+func TestSearchTaggedResources(t *testing.T) {
+	manyCompartments := []string{}
+	for i := 0; i < 51; i++ {
+		manyCompartments = append(manyCompartments, fmt.Sprintf("c%d", i))
+	}
+	batch := func(compartments []string) string {
+		query := "query instance resources where "
+		for i, compartment := range compartments {
+			if i > 0 {
+				query += " || "
+			}
+			query += "compartmentId = '" + compartment + "'"
+		}
+		return query
+	}
+
+	tests := []struct {
+		name         string
+		namespace    string
+		compartments []string
+		want         []string
+	}{
+		{name: "whole tenancy", namespace: "oci_computeagent", want: []string{"query instance resources"}},
+		{name: "custom namespace", namespace: "custom", want: []string{"query all resources"}},
+		{
+			name:         "compartment and sub-compartment",
+			namespace:    "oci_computeagent",
+			compartments: []string{"parent", "child"},
+			want:         []string{"query instance resources where compartmentId = 'parent' || compartmentId = 'child'"},
+		},
+		{
+			name:         "compartments by batches",
+			namespace:    "oci_computeagent",
+			compartments: manyCompartments,
+			want:         []string{batch(manyCompartments[:50]), batch(manyCompartments[50:])},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeOCI(t)
+			fake.handleJSON("POST "+searchResourcesPath, http.StatusOK, searchItems(map[string]map[string]string{"ocid1.instance.oc1..a": {"env": "prod"}}, nil))
+			ta := newFakeTenancyAccess(t, []string{"us-ashburn-1"}, []*fakeOCI{fake})
+
+			got, err := searchTaggedResources(context.Background(), ta.searchClients["us-ashburn-1"], tt.namespace, tt.compartments)
+			if err != nil {
+				t.Fatalf("searchTaggedResources() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("searchTaggedResources() = %v, want a resource per search", got)
+			}
+			if queries := searchQueries(t, fake); !reflect.DeepEqual(queries, tt.want) {
+				t.Errorf("queries = %v, want %v", queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetResourcesInfo(t *testing.T) {
+	const (
+		instanceA  = "ocid1.instance.oc1..a"
+		instanceB  = "ocid1.instance.oc1..b"
+		missing    = "ocid1.instance.oc1..missing"
+		child      = "ocid1.compartment.oc1..child"
+		tenancyArg = "select tenancy"
+	)
+	o, servers := newRegionsDatasource(t, []string{"us-ashburn-1"})
+	servers[0].handleJSON("GET /20160918/tenancies/{tenancyId}", http.StatusOK, map[string]string{"id": testTenancyOCID, "name": "test"})
+	servers[0].handleJSON("GET /20160918/compartments", http.StatusOK, []map[string]string{
+		{"id": child, "compartmentId": testTenancyOCID, "name": "child", "lifecycleState": "ACTIVE"},
+	})
+	items := searchItems(map[string]map[string]string{
+		strings.ToUpper(instanceA): {"team": "blue"},
+		instanceB:                  {},
+	}, map[string]string{strings.ToUpper(instanceA): child, instanceB: "ocid1.compartment.oc1..unknown"})
+	items["items"].([]map[string]interface{})[0]["definedTags"] = map[string]map[string]interface{}{"ops": {"tier": 1}}
+	servers[0].handleJSON("POST "+searchResourcesPath, http.StatusOK, items)
+
+	resourceIDs := []string{instanceA, strings.ToUpper(instanceA), instanceB, missing, "instance-a", ""}
+	got := o.GetResourcesInfo(context.Background(), tenancyArg, "us-ashburn-1", resourceIDs)
+
+	want := map[string]models.OCIResourceInfo{
+		instanceA: {
+			ResourceID: instanceA, ResourceType: "Instance", DisplayName: "name of " + strings.ToUpper(instanceA),
+			CompartmentID: child, CompartmentName: "test > child",
+			FreeformTags: map[string]string{"team": "blue"}, DefinedTags: map[string]map[string]string{"ops": {"tier": "1"}},
+		},
+		instanceB: {
+			ResourceID: instanceB, ResourceType: "Instance", DisplayName: "name of " + instanceB,
+			CompartmentID: "ocid1.compartment.oc1..unknown",
+			FreeformTags:  map[string]string{}, DefinedTags: map[string]map[string]string{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetResourcesInfo() = %+v, want %+v", got, want)
+	}
+	wantQuery := "query all resources where identifier = '" + instanceA + "' || identifier = '" + instanceB + "' || identifier = '" + missing + "'"
+	if queries := searchQueries(t, servers[0]); !reflect.DeepEqual(queries, []string{wantQuery}) {
+		t.Errorf("search queries = %v, want %v", queries, []string{wantQuery})
+	}
+
+	// the resources found and the resources not found are both cached
+	if again := o.GetResourcesInfo(context.Background(), tenancyArg, "us-ashburn-1", resourceIDs); !reflect.DeepEqual(again, want) {
+		t.Errorf("GetResourcesInfo() cached = %+v, want %+v", again, want)
+	}
+	if queries := searchQueries(t, servers[0]); len(queries) != 1 {
+		t.Errorf("search queries = %v, want the cached resources not searched again", queries)
+	}
+
+	t.Run("labels", func(t *testing.T) {
+		dataPoints := []monitoring.MetricData{
+			{Dimensions: map[string]string{"resourceId": strings.ToUpper(instanceA)}},
+			{Dimensions: map[string]string{"resourceId": missing}},
+		}
+		labels := o.getResourceLabels(context.Background(), tenancyArg, "us-ashburn-1", models.MetricsDataRequest{Namespace: "oci_computeagent"}, dataPoints)
+		wantLabels := map[string]map[string]string{
+			instanceA: {"resource_name": "name of " + strings.ToUpper(instanceA), "compartment": "test > child", "tags.team": "blue", "tags.ops.tier": "1"},
+		}
+		if !reflect.DeepEqual(labels, wantLabels) {
+			t.Errorf("getResourceLabels() = %v, want %v", labels, wantLabels)
+		}
+	})
+}
+
+func TestGetResourcesInfoBatches(t *testing.T) {
+	o, servers := newRegionsDatasource(t, []string{"us-ashburn-1"})
+	servers[0].handleJSON("GET /20160918/tenancies/{tenancyId}", http.StatusOK, map[string]string{"id": testTenancyOCID, "name": "test"})
+	servers[0].handleJSON("GET /20160918/compartments", http.StatusOK, []map[string]string{})
+	var failing atomic.Bool
+	failing.Store(true)
+	servers[0].handle("POST "+searchResourcesPath, func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			writeJSON(w, http.StatusNotFound, ociError("NotAuthorizedOrNotFound", "search not authorized"))
+			return
+		}
+		writeJSON(w, http.StatusOK, searchItems(nil, nil))
+	})
+
+	resourceIDs := []string{}
+	for i := 0; i < 51; i++ {
+		resourceIDs = append(resourceIDs, fmt.Sprintf("ocid1.instance.oc1..%d", i))
+	}
+
+	// a failed search is not cached, the resources are searched again on the next request
+	if got := o.GetResourcesInfo(context.Background(), "select tenancy", "us-ashburn-1", resourceIDs); len(got) != 0 {
+		t.Errorf("GetResourcesInfo() = %v, want no resource when the search fails", got)
+	}
+	failing.Store(false)
+	o.GetResourcesInfo(context.Background(), "select tenancy", "us-ashburn-1", resourceIDs)
+
+	queries := searchQueries(t, servers[0])
+	if len(queries) != 4 {
+		t.Fatalf("search queries = %d, want 2 batches searched twice", len(queries))
+	}
+	if n := strings.Count(queries[0], "identifier = "); n != 50 {
+		t.Errorf("first batch holds %d resources, want 50", n)
+	}
+	if queries[1] != "query all resources where identifier = 'ocid1.instance.oc1..50'" {
+		t.Errorf("second batch = %q, want the last resource", queries[1])
+	}
+}
+
+func TestResourceInfoLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		info models.OCIResourceInfo
+		want map[string]string
+	}{
+		{name: "no metadata", info: models.OCIResourceInfo{ResourceID: "ocid1.instance.oc1..a"}, want: map[string]string{}},
+		{
+			name: "name, compartment and tags",
+			info: models.OCIResourceInfo{
+				DisplayName: "instance-a", CompartmentName: "child",
+				FreeformTags: map[string]string{"team": "blue"},
+				DefinedTags:  map[string]map[string]string{"ops": {"tier": "1"}, "cost": {"center": "42"}},
+			},
+			want: map[string]string{"resource_name": "instance-a", "compartment": "child", "tags.team": "blue", "tags.ops.tier": "1", "tags.cost.center": "42"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resourceInfoLabels(tt.info); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resourceInfoLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+// sessionTokenClaims holds the claims of an OCI security token used by the plugin.
+type sessionTokenClaims struct {
+	Subject        string `json:"sub"`
+	Tenant         string `json:"tenant"`
+	ExpiresAt      int64  `json:"exp"`
+	SessionExpires int64  `json:"sess_exp"`
+}
+
+// SessionTokenConfigurationProvider is a ConfigurationProvider for user principals authenticated
+// with a security token, as created by `oci session authenticate`.
+//
+// Requests are signed with the session key and the security token is used as key id. The token is
+// refreshed against the Identity auth endpoint shortly before its expiry, as long as the token is
+// still valid and the session did not reach its maximum lifetime. The refresh runs in the background,
+// requests keep being signed with the current token until the new one is swapped in.
+type SessionTokenConfigurationProvider struct {
+	tenancy string
+	region  string
+	// authEndpoint is the Identity auth endpoint of the region the security token is refreshed against
+	authEndpoint         string
+	privateKey           string
+	privateKeyPassphrase *string
+	profile              string
+
+	mu          sync.RWMutex
+	token       string
+	claims      sessionTokenClaims
+	lastAttempt time.Time
+	refreshable bool
+	refreshes   singleflight.Group
+}
+
+// NewSessionTokenConfigurationProvider - constructor
+//
+// Parameters:
+//   - profile: The profile name, used in logs and errors.
+//   - tenancy: The tenancy OCID. When empty the tenancy is read from the token.
+//   - region: The region of the profile.
+//   - customDomain: The custom domain of the region, if any.
+//   - token: The security token.
+//   - privateKey: The PEM encoded session key.
+//   - privateKeyPassphrase: The passphrase of the session key.
+//
+// Returns:
+//   - *SessionTokenConfigurationProvider: The configuration provider.
+//   - error: An error if the security token cannot be parsed.
+func NewSessionTokenConfigurationProvider(profile, tenancy, region, customDomain, token, privateKey string, privateKeyPassphrase *string) (*SessionTokenConfigurationProvider, error) {
+	token = strings.TrimSpace(token)
+	claims, err := parseSessionToken(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid security token in profile "+profile)
+	}
+	if tenancy == "" {
+		tenancy = claims.Tenant
+	}
+
+	authEndpoint := common.StringToRegion(region).EndpointForTemplate("auth", "https://auth.{region}.{secondLevelDomain}")
+	if customDomain != "" {
+		authEndpoint = "https://auth." + region + "." + customDomain
+	}
+
+	return &SessionTokenConfigurationProvider{
+		tenancy:              tenancy,
+		region:               region,
+		authEndpoint:         authEndpoint,
+		privateKey:           privateKey,
+		privateKeyPassphrase: privateKeyPassphrase,
+		profile:              profile,
+		token:                token,
+		claims:               claims,
+		refreshable:          true,
+	}, nil
+}
+
+// parseSessionToken decodes the claims of the security token without verifying it.
+// The token is verified by the OCI services when it is used.
+func parseSessionToken(token string) (sessionTokenClaims, error) {
+	var claims sessionTokenClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("security token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, errors.Wrap(err, "cannot decode security token")
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, errors.Wrap(err, "cannot read security token claims")
+	}
+	if claims.ExpiresAt == 0 {
+		return claims, errors.New("security token has no expiry")
+	}
+
+	return claims, nil
+}
+
+// PrivateRSAKey returns the session key.
+func (p *SessionTokenConfigurationProvider) PrivateRSAKey() (*rsa.PrivateKey, error) {
+	return common.PrivateKeyFromBytes([]byte(p.privateKey), p.privateKeyPassphrase)
+}
+
+// KeyID returns the security token as key id, refreshing it first when it is about to expire.
+func (p *SessionTokenConfigurationProvider) KeyID() (string, error) {
+	p.refreshIfNeeded()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return "ST$" + p.token, nil
+}
+
+// TenancyOCID returns the tenancy OCID of the profile.
+func (p *SessionTokenConfigurationProvider) TenancyOCID() (string, error) {
+	if p.tenancy == "" {
+		return "", fmt.Errorf("tenancy OCID can not be empty")
+	}
+	return p.tenancy, nil
+}
+
+// UserOCID returns the user OCID the security token was issued to.
+func (p *SessionTokenConfigurationProvider) UserOCID() (string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.claims.Subject, nil
+}
+
+// KeyFingerprint is not used to sign requests with a security token.
+func (p *SessionTokenConfigurationProvider) KeyFingerprint() (string, error) {
+	return "", nil
+}
+
+// Region returns the region of the profile.
+func (p *SessionTokenConfigurationProvider) Region() (string, error) {
+	return p.region, nil
+}
+
+// AuthType returns the authentication type of the provider.
+func (p *SessionTokenConfigurationProvider) AuthType() (common.AuthConfig, error) {
+	return common.AuthConfig{AuthType: common.UnknownAuthenticationType, IsFromConfigFile: false, OboToken: nil}, nil
+}
+
+// ExpiresAt returns the expiry time of the current security token.
+func (p *SessionTokenConfigurationProvider) ExpiresAt() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return time.Unix(p.claims.ExpiresAt, 0).UTC()
+}
+
+// Expired reports whether the current security token is expired.
+func (p *SessionTokenConfigurationProvider) Expired() bool {
+	return !time.Now().Before(p.ExpiresAt())
+}
+
+// refreshIfNeeded starts a refresh of the security token when it expires within constants.SESSION_TOKEN_REFRESH_WINDOW.
+// It does not wait for the refresh: the token is still valid during the refresh window, so the requests keep
+// being signed with it. Failed attempts are not repeated before constants.SESSION_TOKEN_REFRESH_RETRY, so a
+// token which cannot be refreshed anymore does not slow down every request.
+func (p *SessionTokenConfigurationProvider) refreshIfNeeded() {
+	p.mu.RLock()
+	needed := p.needsRefresh(time.Now())
+	p.mu.RUnlock()
+	if !needed {
+		return
+	}
+
+	// a single refresh runs at a time, the requests arriving meanwhile do not start another one
+	p.refreshes.DoChan("refresh", func() (interface{}, error) {
+		p.refresh()
+		return nil, nil
+	})
+}
+
+// needsRefresh tells whether the security token is to be refreshed, the caller holds p.mu.
+func (p *SessionTokenConfigurationProvider) needsRefresh(now time.Time) bool {
+	if !p.refreshable {
+		return false
+	}
+	expiresAt := time.Unix(p.claims.ExpiresAt, 0)
+	if now.Before(expiresAt.Add(-constants.SESSION_TOKEN_REFRESH_WINDOW)) || !now.Before(expiresAt) {
+		// nothing to do yet, or too late: an expired token cannot be refreshed
+		return false
+	}
+	if p.claims.SessionExpires != 0 && !now.Before(time.Unix(p.claims.SessionExpires, 0)) {
+		return false
+	}
+
+	return now.Sub(p.lastAttempt) >= constants.SESSION_TOKEN_REFRESH_RETRY
+}
+
+// refresh exchanges the security token with a new one. The lock is only held to check the token again and to
+// swap the new token in, never during the call to the Identity auth endpoint.
+func (p *SessionTokenConfigurationProvider) refresh() {
+	p.mu.Lock()
+	now := time.Now()
+	if !p.needsRefresh(now) {
+		p.mu.Unlock()
+		return
+	}
+	p.lastAttempt = now
+	currentToken, currentClaims := p.token, p.claims
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := p.requestRefresh(ctx, currentToken, currentClaims)
+	if err != nil {
+		backend.Logger.Error("SessionTokenConfigurationProvider", "profile", p.profile, "refresh failed", err)
+		return
+	}
+	claims, err := parseSessionToken(token)
+	if err != nil {
+		backend.Logger.Error("SessionTokenConfigurationProvider", "profile", p.profile, "refreshed token is invalid", err)
+		return
+	}
+
+	p.mu.Lock()
+	p.token = token
+	p.claims = claims
+	p.mu.Unlock()
+	backend.Logger.Debug("SessionTokenConfigurationProvider", "profile", p.profile, "token refreshed, expires at", time.Unix(claims.ExpiresAt, 0).UTC())
+}
+
+// requestRefresh calls the Identity auth endpoint to exchange the current security token with a new one.
+// The request is signed with the current token, so it is only accepted while the token is valid.
+func (p *SessionTokenConfigurationProvider) requestRefresh(ctx context.Context, currentToken string, currentClaims sessionTokenClaims) (string, error) {
+	// signing provider bound to the current token, it never refreshes itself
+	signer := &SessionTokenConfigurationProvider{
+		tenancy:              p.tenancy,
+		region:               p.region,
+		privateKey:           p.privateKey,
+		privateKeyPassphrase: p.privateKeyPassphrase,
+		profile:              p.profile,
+		token:                currentToken,
+		claims:               currentClaims,
+	}
+	client, err := common.NewClientWithConfig(signer)
+	if err != nil {
+		return "", err
+	}
+	client.Host = p.authEndpoint
+
+	body, err := json.Marshal(map[string]string{"currentToken": currentToken})
+	if err != nil {
+		return "", err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/authentication/refresh", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Call(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	var refreshed struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(content, &refreshed); err != nil {
+		return "", errors.Wrap(err, "cannot read refreshed security token")
+	}
+	if refreshed.Token == "" {
+		return "", errors.New("empty security token returned by refresh")
+	}
+
+	return refreshed.Token, nil
+}
+
+// checkSessionToken returns a descriptive error when the tenancy access key uses an expired security token.
+// A refresh is started first when possible. Any other kind of credentials is always accepted.
+//
+// Parameters:
+//   - takey: The tenancy access key.
+//
+// Returns:
+//   - error: An error describing the expired token, otherwise nil.
+func (o *OCIDatasource) checkSessionToken(takey string) error {
+	ta := o.getTenancyAccess(takey)
+	if ta == nil {
+		return nil
+	}
+	stProvider, ok := ta.config.(*SessionTokenConfigurationProvider)
+	if !ok {
+		return nil
+	}
+
+	// KeyID starts a refresh of the token when it is about to expire
+	if _, err := stProvider.KeyID(); err != nil {
+		return err
+	}
+	if stProvider.Expired() {
+		return fmt.Errorf("security token of profile %v expired at %v, run 'oci session authenticate' and update the datasource with the new token and session key",
+			stProvider.profile, stProvider.ExpiresAt().Format(time.RFC3339))
+	}
+
+	return nil
+}
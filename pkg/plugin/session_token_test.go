@@ -0,0 +1,231 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+const sessionRefreshPath = "/v1/authentication/refresh"
+
+// testSessionToken returns an unsigned security token with the given claims.
+func testSessionToken(t *testing.T, claims sessionTokenClaims) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + encode(payload) + "." + encode([]byte("signature"))
+}
+
+// newTestSessionTokenProvider returns a provider of the test tenancy refreshing its token against the fake server.
+func newTestSessionTokenProvider(t *testing.T, token string, fake *fakeOCI) *SessionTokenConfigurationProvider {
+	t.Helper()
+	p, err := NewSessionTokenConfigurationProvider("DEFAULT", "", "us-ashburn-1", "", token, testPrivateKey(t), nil)
+	if err != nil {
+		t.Fatalf("NewSessionTokenConfigurationProvider() error = %v", err)
+	}
+	p.authEndpoint = fake.server.URL
+	return p
+}
+
+func TestParseSessionToken(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	valid := sessionTokenClaims{Subject: "ocid1.user.oc1..test", Tenant: testTenancyOCID, ExpiresAt: expiresAt, SessionExpires: expiresAt + 3600}
+	expired := sessionTokenClaims{Subject: "ocid1.user.oc1..test", Tenant: testTenancyOCID, ExpiresAt: time.Now().Add(-time.Hour).Unix()}
+	encode := base64.RawURLEncoding.EncodeToString
+
+	tests := []struct {
+		name    string
+		token   string
+		want    sessionTokenClaims
+		wantErr bool
+	}{
+		{name: "valid token", token: testSessionToken(t, valid), want: valid},
+		{name: "expired token", token: testSessionToken(t, expired), want: expired},
+		{name: "padded payload", token: "header." + base64.URLEncoding.EncodeToString([]byte(`{"sub":"user","exp":1}`)) + ".signature", want: sessionTokenClaims{Subject: "user", ExpiresAt: 1}},
+		{name: "not a JWT", token: "not a token", wantErr: true},
+		{name: "payload not base64", token: "header.!!!.signature", wantErr: true},
+		{name: "payload not JSON", token: "header." + encode([]byte("not json")) + ".signature", wantErr: true},
+		{name: "no expiry", token: "header." + encode([]byte(`{"sub":"user"}`)) + ".signature", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSessionToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSessionToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSessionToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSessionTokenConfigurationProvider(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Minute).Unix()
+	token := testSessionToken(t, sessionTokenClaims{Subject: "ocid1.user.oc1..test", Tenant: testTenancyOCID, ExpiresAt: expiresAt})
+
+	p, err := NewSessionTokenConfigurationProvider("DEFAULT", "", "us-ashburn-1", "", " "+token+"\n", testPrivateKey(t), nil)
+	if err != nil {
+		t.Fatalf("NewSessionTokenConfigurationProvider() error = %v", err)
+	}
+	if tenancy, _ := p.TenancyOCID(); tenancy != testTenancyOCID {
+		t.Errorf("TenancyOCID() = %v, want the tenant of the token", tenancy)
+	}
+	if user, _ := p.UserOCID(); user != "ocid1.user.oc1..test" {
+		t.Errorf("UserOCID() = %v, want the subject of the token", user)
+	}
+	if keyID, _ := p.KeyID(); keyID != "ST$"+token {
+		t.Errorf("KeyID() = %v, want the trimmed token", keyID)
+	}
+	if !p.Expired() || !p.ExpiresAt().Equal(time.Unix(expiresAt, 0).UTC()) {
+		t.Errorf("Expired() = %v, ExpiresAt() = %v, want the expiry of the token", p.Expired(), p.ExpiresAt())
+	}
+	if p.authEndpoint != "https://auth.us-ashburn-1.oraclecloud.com" {
+		t.Errorf("authEndpoint = %v, want the auth endpoint of the region", p.authEndpoint)
+	}
+
+	custom, err := NewSessionTokenConfigurationProvider("DEFAULT", "ocid1.tenancy.oc1..other", "custom-region-1", "example.com", token, testPrivateKey(t), nil)
+	if err != nil {
+		t.Fatalf("NewSessionTokenConfigurationProvider() error = %v", err)
+	}
+	if tenancy, _ := custom.TenancyOCID(); tenancy != "ocid1.tenancy.oc1..other" {
+		t.Errorf("TenancyOCID() = %v, want the tenancy of the profile", tenancy)
+	}
+	if custom.authEndpoint != "https://auth.custom-region-1.example.com" {
+		t.Errorf("authEndpoint = %v, want the auth endpoint of the custom domain", custom.authEndpoint)
+	}
+
+	if _, err := NewSessionTokenConfigurationProvider("DEFAULT", "", "us-ashburn-1", "", "not a token", testPrivateKey(t), nil); err == nil {
+		t.Error("NewSessionTokenConfigurationProvider() error = nil, want an error for a malformed token")
+	}
+}
+
+func TestSessionTokenNeedsRefresh(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name           string
+		expiresAt      time.Time
+		sessionExpires time.Time
+		lastAttempt    time.Time
+		want           bool
+	}{
+		{name: "before the refresh window", expiresAt: now.Add(constants.SESSION_TOKEN_REFRESH_WINDOW + time.Minute), want: false},
+		{name: "in the refresh window", expiresAt: now.Add(time.Minute), want: true},
+		{name: "expired", expiresAt: now.Add(-time.Second), want: false},
+		{name: "session over", expiresAt: now.Add(time.Minute), sessionExpires: now.Add(-time.Second), want: false},
+		{name: "session still open", expiresAt: now.Add(time.Minute), sessionExpires: now.Add(time.Hour), want: true},
+		{name: "recent attempt", expiresAt: now.Add(time.Minute), lastAttempt: now.Add(-time.Second), want: false},
+		{name: "previous attempt", expiresAt: now.Add(time.Minute), lastAttempt: now.Add(-constants.SESSION_TOKEN_REFRESH_RETRY), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &SessionTokenConfigurationProvider{
+				claims:      sessionTokenClaims{ExpiresAt: tt.expiresAt.Unix()},
+				lastAttempt: tt.lastAttempt,
+				refreshable: true,
+			}
+			if !tt.sessionExpires.IsZero() {
+				p.claims.SessionExpires = tt.sessionExpires.Unix()
+			}
+			if got := p.needsRefresh(now); got != tt.want {
+				t.Errorf("needsRefresh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionTokenRefresh(t *testing.T) {
+	claims := sessionTokenClaims{Subject: "ocid1.user.oc1..test", Tenant: testTenancyOCID, ExpiresAt: time.Now().Add(time.Minute).Unix()}
+	current := testSessionToken(t, claims)
+	refreshedClaims := claims
+	refreshedClaims.ExpiresAt = time.Now().Add(time.Hour).Unix()
+	refreshed := testSessionToken(t, refreshedClaims)
+
+	t.Run("token refreshed in background", func(t *testing.T) {
+		fake := newFakeOCI(t)
+		hold := make(chan struct{})
+		release := sync.OnceFunc(func() { close(hold) })
+		t.Cleanup(release)
+		fake.handle("POST "+sessionRefreshPath, func(w http.ResponseWriter, r *http.Request) {
+			<-hold
+			writeJSON(w, http.StatusOK, map[string]string{"token": refreshed})
+		})
+		p := newTestSessionTokenProvider(t, current, fake)
+
+		// the requests are signed with the current token while it is refreshed
+		for i := 0; i < 3; i++ {
+			if keyID, _ := p.KeyID(); keyID != "ST$"+current {
+				t.Fatalf("KeyID() = %v during the refresh, want the current token", keyID)
+			}
+		}
+		release()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if keyID, _ := p.KeyID(); keyID == "ST$"+refreshed {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("the refreshed token is never used")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if !p.ExpiresAt().Equal(time.Unix(refreshedClaims.ExpiresAt, 0).UTC()) {
+			t.Errorf("ExpiresAt() = %v, want the expiry of the refreshed token", p.ExpiresAt())
+		}
+
+		requests := fake.requestsTo(sessionRefreshPath)
+		if len(requests) != 1 {
+			t.Fatalf("refresh called %d times, want 1", len(requests))
+		}
+		var body map[string]string
+		if err := json.Unmarshal(requests[0].body, &body); err != nil || body["currentToken"] != current {
+			t.Errorf("refresh body = %s, want the current token", requests[0].body)
+		}
+	})
+
+	t.Run("refresh failure", func(t *testing.T) {
+		fake := newFakeOCI(t)
+		fake.handleJSON("POST "+sessionRefreshPath, http.StatusNotFound, ociError("NotAuthenticated", "token not valid"))
+		p := newTestSessionTokenProvider(t, current, fake)
+
+		p.refresh()
+		if keyID, _ := p.KeyID(); keyID != "ST$"+current {
+			t.Errorf("KeyID() = %v after a failed refresh, want the current token", keyID)
+		}
+		p.mu.RLock()
+		needed := p.needsRefresh(time.Now())
+		p.mu.RUnlock()
+		if needed {
+			t.Error("needsRefresh() = true after a failed refresh, want the next attempt to wait")
+		}
+	})
+
+	t.Run("invalid refreshed token", func(t *testing.T) {
+		fake := newFakeOCI(t)
+		fake.handleJSON("POST "+sessionRefreshPath, http.StatusOK, map[string]string{"token": "not a token"})
+		p := newTestSessionTokenProvider(t, current, fake)
+
+		p.refresh()
+		if keyID, _ := p.KeyID(); !strings.HasSuffix(keyID, current) {
+			t.Errorf("KeyID() = %v after an invalid refreshed token, want the current token", keyID)
+		}
+	})
+}
@@ -0,0 +1,400 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+// streamPoller polls OCI for one metric query and sends the new datapoints to every stream subscribed to the query.
+type streamPoller struct {
+	query    []byte
+	interval time.Duration
+	pCtx     backend.PluginContext
+	cancel   context.CancelFunc
+	// streams counts the streams of the poller, the poller stops when it drops to zero, guarded by the registry lock
+	streams int
+
+	// sendMu orders the frames sent to the streams: the history replayed to a joining stream comes before
+	// the rows of the next poll. It is held while sending, the other locks are not.
+	sendMu sync.Mutex
+
+	mu       sync.Mutex
+	senders  map[int64]*backend.StreamSender
+	lastTime time.Time
+	// history holds the last constants.STREAM_INITIAL_POINTS rows sent, for the streams joining a running poller
+	history []*data.Frame
+}
+
+// streamRegistry holds the pollers of the running streams, keyed by query.
+type streamRegistry struct {
+	// ctx is the context of the datasource, the pollers stop when it is cancelled, see OCIDatasource.Dispose
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	nextID  int64
+	pollers map[string]*streamPoller
+}
+
+// NewStreamRegistry - constructor
+func NewStreamRegistry() *streamRegistry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &streamRegistry{
+		ctx:     ctx,
+		cancel:  cancel,
+		pollers: make(map[string]*streamPoller),
+	}
+}
+
+// close stops every poller, the streams still running end with their own context.
+func (r *streamRegistry) close() {
+	r.cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pollers = make(map[string]*streamPoller)
+}
+
+// streamQuery reads the metric query sent with a stream request and returns it with the key
+// identifying the query: streams of the same query share the same key, whatever their channel path.
+//
+// Parameters:
+//   - raw: The data of the stream request, a query model.
+//
+// Returns:
+//   - *models.QueryModel: The query model.
+//   - string: The key of the query.
+//   - error: An error if the query is not a valid streaming query.
+func streamQuery(raw []byte) (*models.QueryModel, string, error) {
+	qm := &models.QueryModel{RawQuery: true}
+	if err := jsoniter.Unmarshal(raw, qm); err != nil {
+		return nil, "", errors.Wrap(err, "invalid stream query")
+	}
+	if qm.TenancyOCID == "" {
+		return nil, "", errors.New("invalid stream query: tenancy is mandatory")
+	}
+
+	// the query model is re-encoded so that refId, time range and other panel fields are not part of the key
+	normalized, err := jsoniter.Marshal(qm)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(normalized)
+
+	return qm, hex.EncodeToString(sum[:]), nil
+}
+
+// streamInterval returns the polling interval of a query interval such as [1m], [5m], [1h] or [1d].
+// Intervals shorter than constants.STREAM_MIN_POLL_INTERVAL, not valid or auto are polled every constants.STREAM_MIN_POLL_INTERVAL.
+func streamInterval(interval string) time.Duration {
+	duration, ok := parseInterval(interval)
+	if !ok || duration < constants.STREAM_MIN_POLL_INTERVAL {
+		return constants.STREAM_MIN_POLL_INTERVAL
+	}
+
+	return duration
+}
+
+// SubscribeStream is called when a panel subscribes to a metric stream.
+// Only the metrics/ channels are available, and the request must hold a valid metric query.
+func (o *OCIDatasource) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	backend.Logger.Debug("plugin", "SubscribeStream", req.Path)
+
+	if !strings.HasPrefix(req.Path, constants.STREAM_PATH_PREFIX) {
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+	if _, _, err := streamQuery(req.Data); err != nil {
+		backend.Logger.Error("plugin", "SubscribeStream", err)
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusPermissionDenied}, nil
+	}
+
+	return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusOK}, nil
+}
+
+// PublishStream is called when a client publishes to a stream, metric streams are read only.
+func (o *OCIDatasource) PublishStream(ctx context.Context, req *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	return &backend.PublishStreamResponse{Status: backend.PublishStreamStatusPermissionDenied}, nil
+}
+
+// RunStream is called once per channel when its first subscriber joins, and runs until the last one leaves.
+// The stream joins the poller of its query, the poller is started by the first stream of the query and
+// stopped when its last stream ends, so identical queries only poll OCI once.
+func (o *OCIDatasource) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	backend.Logger.Debug("plugin", "RunStream", req.Path)
+
+	qm, key, err := streamQuery(req.Data)
+	if err != nil {
+		return err
+	}
+
+	// a stream pushes the new rows of a single frame, the query runs in the wide format whatever the panel format
+	qm.FrameFormat = constants.FRAME_FORMAT_WIDE
+	query, err := jsoniter.Marshal(qm)
+	if err != nil {
+		return err
+	}
+
+	id := o.streams.join(o, key, o.pollerPluginContext(req.PluginContext), qm, query, sender)
+	defer o.streams.leave(key, id)
+
+	<-ctx.Done()
+	return nil
+}
+
+// pollerPluginContext returns the plugin context of a poller, built from the datasource rather than from the
+// subscriber starting the poller: the poller is shared by the streams of every user subscribed to the query.
+func (o *OCIDatasource) pollerPluginContext(pCtx backend.PluginContext) backend.PluginContext {
+	pollerCtx := backend.PluginContext{
+		OrgID:         pCtx.OrgID,
+		PluginID:      pCtx.PluginID,
+		PluginVersion: pCtx.PluginVersion,
+		GrafanaConfig: pCtx.GrafanaConfig,
+	}
+	if o.instanceSettings != nil {
+		pollerCtx.DataSourceInstanceSettings = o.instanceSettings
+	} else {
+		pollerCtx.DataSourceInstanceSettings = pCtx.DataSourceInstanceSettings
+	}
+
+	return pollerCtx
+}
+
+// join adds a stream sender to the poller of the query, starting the poller if needed. A stream joining a running
+// poller first receives the rows the poller already sent, instead of waiting for the next datapoint.
+// The poller keeps the plugin context of the stream starting it, see pollerPluginContext.
+func (r *streamRegistry) join(o *OCIDatasource, key string, pCtx backend.PluginContext, qm *models.QueryModel, query []byte, sender *backend.StreamSender) int64 {
+	r.mu.Lock()
+	r.nextID++
+	id := r.nextID
+
+	poller, ok := r.pollers[key]
+	if !ok {
+		pollerCtx, cancel := context.WithCancel(r.ctx)
+		poller = &streamPoller{
+			query:    query,
+			interval: streamInterval(qm.Interval),
+			pCtx:     pCtx,
+			cancel:   cancel,
+			senders:  make(map[int64]*backend.StreamSender),
+		}
+		r.pollers[key] = poller
+		go o.runPoller(pollerCtx, poller)
+	}
+	poller.streams++
+	r.mu.Unlock()
+
+	poller.sendMu.Lock()
+	defer poller.sendMu.Unlock()
+
+	poller.mu.Lock()
+	poller.senders[id] = sender
+	history := append([]*data.Frame(nil), poller.history...)
+	poller.mu.Unlock()
+
+	for _, frame := range history {
+		if err := sender.SendFrame(frame, data.IncludeAll); err != nil {
+			backend.Logger.Error("plugin", "join", "stream", id, "error", err)
+		}
+	}
+
+	return id
+}
+
+// leave removes a stream sender from the poller of the query, stopping the poller after its last sender.
+func (r *streamRegistry) leave(key string, id int64) {
+	r.mu.Lock()
+	poller, ok := r.pollers[key]
+	if ok {
+		poller.streams--
+		if poller.streams == 0 {
+			poller.cancel()
+			delete(r.pollers, key)
+		}
+	}
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	poller.mu.Lock()
+	delete(poller.senders, id)
+	poller.mu.Unlock()
+}
+
+// runPoller polls the query at its interval until the context is cancelled.
+// The first poll covers constants.STREAM_INITIAL_POINTS intervals, the following ones only the datapoints
+// newer than the last sent one.
+func (o *OCIDatasource) runPoller(ctx context.Context, poller *streamPoller) {
+	ticker := time.NewTicker(poller.interval)
+	defer ticker.Stop()
+
+	for {
+		o.pollStream(ctx, poller)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollStream runs the query over the window following the last sent datapoint and sends the new rows.
+func (o *OCIDatasource) pollStream(ctx context.Context, poller *streamPoller) {
+	now := time.Now().UTC()
+
+	poller.mu.Lock()
+	from := poller.lastTime
+	poller.mu.Unlock()
+	if from.IsZero() {
+		from = now.Add(-constants.STREAM_INITIAL_POINTS * poller.interval)
+	} else {
+		// aggregated datapoints are aligned on the interval, the previous one is included and filtered out below
+		from = from.Add(-poller.interval)
+	}
+
+	response := o.query(ctx, poller.pCtx, backend.DataQuery{
+		RefID:     "stream",
+		JSON:      poller.query,
+		TimeRange: backend.TimeRange{From: from, To: now},
+	})
+	if response.Error != nil {
+		backend.Logger.Error("plugin", "pollStream", response.Error)
+		return
+	}
+
+	poller.sendMu.Lock()
+	defer poller.sendMu.Unlock()
+
+	poller.mu.Lock()
+	var newFrames []*data.Frame
+	for i, frame := range response.Frames {
+		newFrame, lastTime := framesAfter(frame, poller.lastTime)
+		if newFrame == nil {
+			continue
+		}
+		if i < len(poller.history) {
+			poller.history[i] = appendRows(poller.history[i], newFrame, constants.STREAM_INITIAL_POINTS)
+		} else {
+			poller.history = append(poller.history, appendRows(nil, newFrame, constants.STREAM_INITIAL_POINTS))
+		}
+		newFrames = append(newFrames, newFrame)
+		if lastTime.After(poller.lastTime) {
+			poller.lastTime = lastTime
+		}
+	}
+	senders := make(map[int64]*backend.StreamSender, len(poller.senders))
+	for id, sender := range poller.senders {
+		senders[id] = sender
+	}
+	poller.mu.Unlock()
+
+	for _, newFrame := range newFrames {
+		for id, sender := range senders {
+			if err := sender.SendFrame(newFrame, data.IncludeAll); err != nil {
+				backend.Logger.Error("plugin", "pollStream", "stream", id, "error", err)
+			}
+		}
+	}
+}
+
+// appendRows appends the rows of a frame to a history frame holding the same fields and keeps its last max rows.
+// A copy of the frame replaces the history when the fields differ, such as when a series appears.
+func appendRows(history *data.Frame, frame *data.Frame, max int) *data.Frame {
+	if history == nil || !sameFields(history, frame) {
+		history = data.NewFrame(frame.Name).SetMeta(frame.Meta)
+		for _, field := range frame.Fields {
+			newField := data.NewFieldFromFieldType(field.Type(), 0)
+			newField.Name = field.Name
+			newField.Labels = field.Labels
+			newField.Config = field.Config
+			history.Fields = append(history.Fields, newField)
+		}
+	}
+
+	for i, field := range frame.Fields {
+		for row := 0; row < field.Len(); row++ {
+			history.Fields[i].Append(field.At(row))
+		}
+	}
+	for _, field := range history.Fields {
+		for field.Len() > max {
+			field.Delete(0)
+		}
+	}
+
+	return history
+}
+
+// sameFields tells whether two frames hold fields of the same names, types and labels.
+func sameFields(a *data.Frame, b *data.Frame) bool {
+	if len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	for i := range a.Fields {
+		if a.Fields[i].Name != b.Fields[i].Name || a.Fields[i].Type() != b.Fields[i].Type() ||
+			a.Fields[i].Labels.String() != b.Fields[i].Labels.String() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// framesAfter returns a copy of the frame holding only the rows newer than after, together with the
+// time of the newest row. A nil frame is returned when there is no new row.
+func framesAfter(frame *data.Frame, after time.Time) (*data.Frame, time.Time) {
+	if len(frame.Fields) == 0 || frame.Fields[0].Type() != data.FieldTypeTime {
+		return nil, after
+	}
+	timeField := frame.Fields[0]
+
+	var rows []int
+	last := after
+	for i := 0; i < timeField.Len(); i++ {
+		t, ok := timeField.At(i).(time.Time)
+		if !ok || !t.After(after) {
+			continue
+		}
+		rows = append(rows, i)
+		if t.After(last) {
+			last = t
+		}
+	}
+	if len(rows) == 0 {
+		return nil, after
+	}
+
+	newFrame := data.NewFrame(frame.Name).SetMeta(frame.Meta)
+	for _, field := range frame.Fields {
+		newField := data.NewFieldFromFieldType(field.Type(), 0)
+		newField.Name = field.Name
+		newField.Labels = field.Labels
+		newField.Config = field.Config
+		for _, row := range rows {
+			if row < field.Len() {
+				newField.Append(field.At(row))
+			}
+		}
+		newFrame.Fields = append(newFrame.Fields, newField)
+	}
+
+	return newFrame, last
+}
@@ -0,0 +1,299 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/models"
+)
+
+// packetRecorder records the packets sent to a stream.
+type packetRecorder struct {
+	mu      sync.Mutex
+	packets []*backend.StreamPacket
+}
+
+func (p *packetRecorder) Send(packet *backend.StreamPacket) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.packets = append(p.packets, packet)
+	return nil
+}
+
+func (p *packetRecorder) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.packets)
+}
+
+// streamFrame returns a wide frame of a time field and a value field.
+func streamFrame(times []time.Time, values []float64) *data.Frame {
+	pointers := make([]*float64, 0, len(values))
+	for i := range values {
+		pointers = append(pointers, &values[i])
+	}
+	return data.NewFrame("response",
+		data.NewField(data.TimeSeriesTimeFieldName, nil, times),
+		data.NewField("CpuUtilization", data.Labels{"resourceId": "a"}, pointers),
+	)
+}
+
+// fieldValues returns the values of a field.
+func fieldValues(field *data.Field) []interface{} {
+	values := make([]interface{}, 0, field.Len())
+	for i := 0; i < field.Len(); i++ {
+		values = append(values, field.At(i))
+	}
+	return values
+}
+
+func TestStreamRegistryJoinLeave(t *testing.T) {
+	o := newTestDatasource(t, "singletenancy")
+	r := o.streams
+	qm := &models.QueryModel{Interval: "[1m]"}
+
+	first := r.join(o, "a", backend.PluginContext{}, qm, nil, backend.NewStreamSender(&packetRecorder{}))
+	second := r.join(o, "a", backend.PluginContext{}, qm, nil, backend.NewStreamSender(&packetRecorder{}))
+	other := r.join(o, "b", backend.PluginContext{}, qm, nil, backend.NewStreamSender(&packetRecorder{}))
+	if first == second || second == other {
+		t.Fatalf("join() ids = %v, %v, %v, want distinct ids", first, second, other)
+	}
+
+	pollers := func() map[string]int {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		streams := map[string]int{}
+		for key, poller := range r.pollers {
+			streams[key] = poller.streams
+		}
+		return streams
+	}
+	if got, want := pollers(), map[string]int{"a": 2, "b": 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("streams per poller = %v, want %v", got, want)
+	}
+
+	r.leave("a", first)
+	if got, want := pollers(), map[string]int{"a": 1, "b": 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("streams per poller after a stream left = %v, want %v", got, want)
+	}
+
+	// leaving twice or an unknown query is ignored
+	r.leave("a", second)
+	r.leave("a", second)
+	r.leave("c", 42)
+	if got, want := pollers(), map[string]int{"b": 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("streams per poller after the last stream left = %v, want %v", got, want)
+	}
+
+	o.Dispose()
+	if got := pollers(); len(got) != 0 {
+		t.Errorf("streams per poller after Dispose() = %v, want none", got)
+	}
+	if r.ctx.Err() == nil {
+		t.Errorf("the context of the pollers is not cancelled by Dispose()")
+	}
+}
+
+func TestStreamRegistryJoinReplaysHistory(t *testing.T) {
+	o := newTestDatasource(t, "singletenancy")
+	defer o.Dispose()
+	r := o.streams
+	qm := &models.QueryModel{Interval: "[1m]"}
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := &packetRecorder{}
+	r.join(o, "a", backend.PluginContext{}, qm, nil, backend.NewStreamSender(first))
+
+	r.mu.Lock()
+	poller := r.pollers["a"]
+	r.mu.Unlock()
+	poller.mu.Lock()
+	poller.history = []*data.Frame{streamFrame([]time.Time{t0}, []float64{1})}
+	poller.mu.Unlock()
+
+	second := &packetRecorder{}
+	r.join(o, "a", backend.PluginContext{}, qm, nil, backend.NewStreamSender(second))
+
+	if got := first.count(); got != 0 {
+		t.Errorf("frames sent to the first stream = %v, want 0", got)
+	}
+	if got := second.count(); got != 1 {
+		t.Errorf("frames sent to the joining stream = %v, want the history", got)
+	}
+}
+
+func TestPollStream(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Minute)
+	t0 := now.Add(-3 * time.Minute)
+
+	fake := newFakeOCI(t)
+	fake.handleJSON("POST "+summarizeMetricsDataPath, http.StatusOK, metricDataResponse(t0, map[string][]float64{"instance-a": {1, 2, 3}}))
+
+	o := newTestDatasource(t, "singletenancy")
+	o.setTenancyAccess(SingleTenancyKey, newFakeTenancyAccess(t, []string{"us-ashburn-1"}, []*fakeOCI{fake}))
+
+	recorder := &packetRecorder{}
+	poller := &streamPoller{
+		query: []byte(`{"tenancy": "select tenancy", "region": "us-ashburn-1", "namespace": "oci_computeagent",` +
+			` "queryText": "CpuUtilization[1m].mean()", "rawQuery": false, "frameFormat": "wide"}`),
+		interval: time.Minute,
+		senders:  map[int64]*backend.StreamSender{1: backend.NewStreamSender(recorder)},
+	}
+
+	o.pollStream(context.Background(), poller)
+	if got := recorder.count(); got != 1 {
+		t.Fatalf("frames sent by the first poll = %v, want 1", got)
+	}
+	if want := t0.Add(2 * time.Minute); !poller.lastTime.Equal(want) {
+		t.Errorf("last time = %v, want %v", poller.lastTime, want)
+	}
+	if len(poller.history) != 1 || poller.history[0].Rows() != 3 {
+		t.Fatalf("history = %v, want a frame of 3 rows", poller.history)
+	}
+
+	// the datapoints already sent are not sent again
+	o.pollStream(context.Background(), poller)
+	if got := recorder.count(); got != 1 {
+		t.Errorf("frames sent after a poll without new datapoint = %v, want 1", got)
+	}
+}
+
+func TestFramesAfter(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{t0, t0.Add(time.Minute), t0.Add(2 * time.Minute)}
+	frame := streamFrame(times, []float64{1, 2, 3})
+
+	tests := []struct {
+		name      string
+		frame     *data.Frame
+		after     time.Time
+		wantTimes []interface{}
+		wantLast  time.Time
+	}{
+		{name: "every row", frame: frame, after: time.Time{}, wantTimes: []interface{}{times[0], times[1], times[2]}, wantLast: times[2]},
+		{name: "rows newer only", frame: frame, after: times[0], wantTimes: []interface{}{times[1], times[2]}, wantLast: times[2]},
+		{name: "no new row", frame: frame, after: times[2], wantLast: times[2]},
+		{name: "no time field", frame: data.NewFrame("response", data.NewField("value", nil, []float64{1})), after: t0, wantLast: t0},
+		{name: "no field", frame: data.NewFrame("response"), after: t0, wantLast: t0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, last := framesAfter(tt.frame, tt.after)
+			if !last.Equal(tt.wantLast) {
+				t.Errorf("framesAfter() last = %v, want %v", last, tt.wantLast)
+			}
+			if tt.wantTimes == nil {
+				if got != nil {
+					t.Errorf("framesAfter() = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("framesAfter() = nil, want %v", tt.wantTimes)
+			}
+			if !reflect.DeepEqual(fieldValues(got.Fields[0]), tt.wantTimes) {
+				t.Errorf("framesAfter() times = %v, want %v", fieldValues(got.Fields[0]), tt.wantTimes)
+			}
+			if got.Fields[1].Len() != len(tt.wantTimes) || !reflect.DeepEqual(got.Fields[1].Labels, tt.frame.Fields[1].Labels) {
+				t.Errorf("framesAfter() values = %v, want %d values of the same labels", got.Fields[1], len(tt.wantTimes))
+			}
+		})
+	}
+
+	// the frame passed in is left unchanged
+	if frame.Rows() != 3 {
+		t.Errorf("framesAfter() changed the frame passed in")
+	}
+}
+
+func TestAppendRows(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	minute := func(i int) time.Time { return t0.Add(time.Duration(i) * time.Minute) }
+
+	tests := []struct {
+		name      string
+		history   *data.Frame
+		frame     *data.Frame
+		max       int
+		wantTimes []interface{}
+	}{
+		{
+			name:      "no history",
+			frame:     streamFrame([]time.Time{minute(0), minute(1)}, []float64{1, 2}),
+			max:       10,
+			wantTimes: []interface{}{minute(0), minute(1)},
+		},
+		{
+			name:      "rows appended",
+			history:   streamFrame([]time.Time{minute(0)}, []float64{1}),
+			frame:     streamFrame([]time.Time{minute(1)}, []float64{2}),
+			max:       10,
+			wantTimes: []interface{}{minute(0), minute(1)},
+		},
+		{
+			name:      "oldest rows dropped",
+			history:   streamFrame([]time.Time{minute(0), minute(1)}, []float64{1, 2}),
+			frame:     streamFrame([]time.Time{minute(2), minute(3)}, []float64{3, 4}),
+			max:       3,
+			wantTimes: []interface{}{minute(1), minute(2), minute(3)},
+		},
+		{
+			name:      "history replaced when the fields differ",
+			history:   data.NewFrame("response", data.NewField(data.TimeSeriesTimeFieldName, nil, []time.Time{minute(0)})),
+			frame:     streamFrame([]time.Time{minute(1)}, []float64{2}),
+			max:       10,
+			wantTimes: []interface{}{minute(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appendRows(tt.history, tt.frame, tt.max)
+			if !reflect.DeepEqual(fieldValues(got.Fields[0]), tt.wantTimes) {
+				t.Errorf("appendRows() times = %v, want %v", fieldValues(got.Fields[0]), tt.wantTimes)
+			}
+			if len(got.Fields) != len(tt.frame.Fields) || got.Fields[1].Len() != len(tt.wantTimes) {
+				t.Errorf("appendRows() = %v, want the fields of the frame with %d rows", got, len(tt.wantTimes))
+			}
+		})
+	}
+}
+
+func TestPollerPluginContext(t *testing.T) {
+	subscriber := backend.PluginContext{
+		OrgID:                      1,
+		PluginID:                   "oci-metrics-datasource",
+		User:                       &backend.User{Login: "viewer"},
+		DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: 1, Name: "subscriber"},
+	}
+
+	o := newTestDatasource(t, "singletenancy")
+	got := o.pollerPluginContext(subscriber)
+	if got.User != nil {
+		t.Errorf("pollerPluginContext() user = %v, want none", got.User)
+	}
+	if got.OrgID != subscriber.OrgID || got.PluginID != subscriber.PluginID {
+		t.Errorf("pollerPluginContext() = %+v, want the org and plugin of %+v", got, subscriber)
+	}
+	if got.DataSourceInstanceSettings != subscriber.DataSourceInstanceSettings {
+		t.Errorf("pollerPluginContext() settings = %v, want the settings of the subscriber without datasource settings", got.DataSourceInstanceSettings)
+	}
+
+	o.instanceSettings = &backend.DataSourceInstanceSettings{ID: 1, Name: "datasource"}
+	if got := o.pollerPluginContext(subscriber); got.DataSourceInstanceSettings != o.instanceSettings {
+		t.Errorf("pollerPluginContext() settings = %v, want the settings of the datasource", got.DataSourceInstanceSettings)
+	}
+}
@@ -0,0 +1,270 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+// timeWindow is a part of the time range of a metric query.
+type timeWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// parseInterval returns the duration of a query interval such as 1m, [5m], 1h or [1d].
+// The second value is false when the interval is not valid.
+func parseInterval(interval string) (time.Duration, bool) {
+	interval = strings.Trim(interval, "[] ")
+	if strings.HasSuffix(interval, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(interval, "d"))
+		if err != nil || days <= 0 {
+			return 0, false
+		}
+		return time.Duration(days) * 24 * time.Hour, true
+	}
+
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration <= 0 {
+		return 0, false
+	}
+
+	return duration, true
+}
+
+// autoIntervalWindow matches the [auto] window of a MQL query.
+var autoIntervalWindow = regexp.MustCompile(`(?i)\[\s*auto\s*\]`)
+
+// queryWindow matches the window of a MQL query, such as [5m].
+var queryWindow = regexp.MustCompile(`\[\s*(\d+[mhd])\s*\]`)
+
+// isAutoInterval tells whether the interval of a query is to be chosen by the plugin: auto, or no interval selected.
+func isAutoInterval(interval string) bool {
+	interval = strings.ToLower(strings.Trim(interval, "[] "))
+	return interval == "" || interval == constants.AUTO_INTERVAL || interval == constants.DEFAULT_INTERVAL_PLACEHOLDER
+}
+
+// resolveInterval returns the interval of a metric query, without brackets.
+//
+// The auto interval is the finest of constants.OCI_INTERVALS that is not finer than the minimum interval computed
+// by Grafana for the panel, and that returns at most MaxDataPoints and constants.METRIC_WINDOW_MAX_DATAPOINTS datapoints
+// per series. The coarsest interval is used when none does. Time ranges longer than a single request accepts are
+// split by metricQueryWindows, they do not make the interval coarser.
+//
+// Parameters:
+//   - interval: The interval of the query model, such as [5m] or auto.
+//   - query: The data query, its time range, interval and maximum number of datapoints drive the auto interval.
+//
+// Returns:
+//   - string: The interval, such as 5m.
+func resolveInterval(interval string, query backend.DataQuery) string {
+	if !isAutoInterval(interval) {
+		return strings.Trim(interval, "[] ")
+	}
+
+	timeRange := query.TimeRange.To.Sub(query.TimeRange.From)
+	maxDataPoints := int64(constants.METRIC_WINDOW_MAX_DATAPOINTS)
+	if query.MaxDataPoints > 0 && query.MaxDataPoints < maxDataPoints {
+		maxDataPoints = query.MaxDataPoints
+	}
+
+	for _, candidate := range constants.OCI_INTERVALS {
+		duration, _ := parseInterval(candidate)
+		if duration < query.Interval {
+			continue
+		}
+		if int64(timeRange/duration) > maxDataPoints {
+			continue
+		}
+		return candidate
+	}
+
+	return constants.OCI_INTERVALS[len(constants.OCI_INTERVALS)-1]
+}
+
+// resolveQueryText replaces the [auto] window of a MQL query with the interval of the query.
+//
+// Parameters:
+//   - queryText: The MQL query.
+//   - interval: The interval of the query, as returned by resolveInterval.
+//
+// Returns:
+//   - string: The MQL query to send to OCI.
+//   - string: The interval the query is aggregated on: the window of the MQL query when it sets one, the given interval otherwise.
+func resolveQueryText(queryText string, interval string) (string, string) {
+	queryText = autoIntervalWindow.ReplaceAllLiteralString(queryText, "["+interval+"]")
+	if match := queryWindow.FindStringSubmatch(queryText); match != nil {
+		interval = match[1]
+	}
+
+	return queryText, interval
+}
+
+// maxWindowForInterval returns the longest time range accepted by SummarizeMetricsData for an interval:
+// 7 days below 5 minutes, 30 days below 1 hour and 90 days above. The range is also limited to
+// constants.METRIC_WINDOW_MAX_DATAPOINTS datapoints per series, so that responses with many series
+// stay under the datapoints limit of the service.
+func maxWindowForInterval(interval time.Duration) time.Duration {
+	maxWindow := constants.METRIC_WINDOW_LIMIT_HOURLY
+	switch {
+	case interval < 5*time.Minute:
+		maxWindow = constants.METRIC_WINDOW_LIMIT_MINUTELY
+	case interval < time.Hour:
+		maxWindow = constants.METRIC_WINDOW_LIMIT_FIVE_MINUTES
+	}
+
+	if datapointsWindow := interval * constants.METRIC_WINDOW_MAX_DATAPOINTS; datapointsWindow < maxWindow {
+		maxWindow = datapointsWindow
+	}
+
+	return maxWindow
+}
+
+// metricQueryWindows splits the time range of a metric query into the windows to request separately.
+//
+// The windows are aligned on the interval, so that no aggregation bucket is split between two windows, and
+// none of them is longer than maxWindowForInterval. The whole range is returned as a single window when the
+// interval is not valid or when the range already fits.
+//
+// Parameters:
+//   - start: The start of the time range.
+//   - end: The end of the time range.
+//   - interval: The interval of the query, such as 1m or 1h.
+//
+// Returns:
+//   - []timeWindow: The windows covering the time range, in chronological order.
+func metricQueryWindows(start time.Time, end time.Time, interval string) []timeWindow {
+	duration, ok := parseInterval(interval)
+	if !ok || !end.After(start) {
+		return []timeWindow{{start: start, end: end}}
+	}
+
+	maxWindow := maxWindowForInterval(duration)
+	if end.Sub(start) <= maxWindow {
+		return []timeWindow{{start: start, end: end}}
+	}
+	// whole number of buckets per window
+	maxWindow = maxWindow.Truncate(duration)
+
+	windows := []timeWindow{}
+	windowStart := start
+	for windowStart.Before(end) {
+		windowEnd := windowStart.Truncate(duration).Add(maxWindow)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+		windows = append(windows, timeWindow{start: windowStart, end: windowEnd})
+		windowStart = windowEnd
+	}
+
+	return windows
+}
+
+// metricDataKey returns the key identifying a series in the responses of SummarizeMetricsData:
+// its name, resource group and dimensions.
+func metricDataKey(item monitoring.MetricData) string {
+	dimensionKeys := make([]string, 0, len(item.Dimensions))
+	for k := range item.Dimensions {
+		dimensionKeys = append(dimensionKeys, k)
+	}
+	sort.Strings(dimensionKeys)
+
+	var key strings.Builder
+	key.WriteString(stringValue(item.Name))
+	key.WriteString("|")
+	key.WriteString(stringValue(item.ResourceGroup))
+	for _, k := range dimensionKeys {
+		key.WriteString("|" + k + "=" + item.Dimensions[k])
+	}
+
+	return key.String()
+}
+
+// mergeMetricData stitches the series returned for the windows of a time range.
+//
+// The datapoints of the same series are concatenated in window order. A datapoint returned by two adjacent
+// windows, the bucket at their boundary, is kept once. Series are returned in the order of their first appearance.
+//
+// Parameters:
+//   - windowsData: The series of each window, in chronological order.
+//
+// Returns:
+//   - []monitoring.MetricData: The series covering the whole time range.
+func mergeMetricData(windowsData [][]monitoring.MetricData) []monitoring.MetricData {
+	merged := []monitoring.MetricData{}
+	seriesIndex := map[string]int{}
+	seenTimes := map[string]map[time.Time]bool{}
+
+	for _, items := range windowsData {
+		for _, item := range items {
+			key := metricDataKey(item)
+			datapoints := item.AggregatedDatapoints
+
+			i, ok := seriesIndex[key]
+			if !ok {
+				i = len(merged)
+				seriesIndex[key] = i
+				seenTimes[key] = map[time.Time]bool{}
+				item.AggregatedDatapoints = []monitoring.AggregatedDatapoint{}
+				merged = append(merged, item)
+			}
+
+			for _, datapoint := range datapoints {
+				if datapoint.Timestamp == nil || seenTimes[key][datapoint.Timestamp.Time] {
+					continue
+				}
+				seenTimes[key][datapoint.Timestamp.Time] = true
+				merged[i].AggregatedDatapoints = append(merged[i].AggregatedDatapoints, datapoint)
+			}
+		}
+	}
+
+	return merged
+}
+
+// alignDataPoints aligns the datapoints of a series on the given times. The times the series has no datapoint
+// for are filled according to the fill mode: null by default, so that the gaps are visible, zero
+// (constants.FILL_MODE_ZERO) or the previous value of the series (constants.FILL_MODE_PREVIOUS), the times
+// before the first datapoint of the series staying null.
+//
+// Parameters:
+//   - times: The times of all the series, in chronological order.
+//   - values: The datapoints of the series, keyed by time.
+//   - fillMode: The fill mode of the query.
+//
+// Returns:
+//   - []*float64: The value of the series at each time, nil when it is not filled.
+func alignDataPoints(times []time.Time, values map[time.Time]float64, fillMode string) []*float64 {
+	aligned := make([]*float64, len(times))
+
+	var previous *float64
+	for i, t := range times {
+		if value, ok := values[t]; ok {
+			aligned[i] = &value
+			previous = aligned[i]
+			continue
+		}
+
+		switch fillMode {
+		case constants.FILL_MODE_ZERO:
+			zero := 0.0
+			aligned[i] = &zero
+		case constants.FILL_MODE_PREVIOUS:
+			aligned[i] = previous
+		}
+	}
+
+	return aligned
+}
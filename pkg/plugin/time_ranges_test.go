@@ -0,0 +1,241 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/monitoring"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestMetricQueryWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		interval string
+		want     [][2]string
+	}{
+		{
+			name:     "range within the limit",
+			start:    "2024-01-01T00:00:00Z",
+			end:      "2024-01-01T12:00:00Z",
+			interval: "1m",
+			want:     [][2]string{{"2024-01-01T00:00:00Z", "2024-01-01T12:00:00Z"}},
+		},
+		{
+			name:     "invalid interval",
+			start:    "2024-01-01T00:00:00Z",
+			end:      "2024-02-01T00:00:00Z",
+			interval: "auto",
+			want:     [][2]string{{"2024-01-01T00:00:00Z", "2024-02-01T00:00:00Z"}},
+		},
+		{
+			name:     "end before start",
+			start:    "2024-01-02T00:00:00Z",
+			end:      "2024-01-01T00:00:00Z",
+			interval: "1m",
+			want:     [][2]string{{"2024-01-02T00:00:00Z", "2024-01-01T00:00:00Z"}},
+		},
+		{
+			name:     "minutely windows aligned on the interval",
+			start:    "2024-01-01T00:00:30Z",
+			end:      "2024-01-03T12:00:00Z",
+			interval: "1m",
+			want: [][2]string{
+				{"2024-01-01T00:00:30Z", "2024-01-02T00:00:00Z"},
+				{"2024-01-02T00:00:00Z", "2024-01-03T00:00:00Z"},
+				{"2024-01-03T00:00:00Z", "2024-01-03T12:00:00Z"},
+			},
+		},
+		{
+			name:     "hourly windows limited by the datapoints",
+			start:    "2024-01-01T00:00:00Z",
+			end:      "2024-04-10T00:00:00Z",
+			interval: "[1h]",
+			want: [][2]string{
+				{"2024-01-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+				{"2024-03-01T00:00:00Z", "2024-04-10T00:00:00Z"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := metricQueryWindows(mustTime(t, tt.start), mustTime(t, tt.end), tt.interval)
+			want := make([]timeWindow, 0, len(tt.want))
+			for _, w := range tt.want {
+				want = append(want, timeWindow{start: mustTime(t, w[0]), end: mustTime(t, w[1])})
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("metricQueryWindows() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeMetricData(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	datapoint := func(minutes int, value float64) monitoring.AggregatedDatapoint {
+		return monitoring.AggregatedDatapoint{
+			Timestamp: &common.SDKTime{Time: t0.Add(time.Duration(minutes) * time.Minute)},
+			Value:     common.Float64(value),
+		}
+	}
+	series := func(name string, dimensions map[string]string, datapoints ...monitoring.AggregatedDatapoint) monitoring.MetricData {
+		return monitoring.MetricData{Name: common.String(name), Dimensions: dimensions, AggregatedDatapoints: datapoints}
+	}
+	a := map[string]string{"resourceId": "a", "region": "r"}
+	b := map[string]string{"resourceId": "b"}
+
+	tests := []struct {
+		name        string
+		windowsData [][]monitoring.MetricData
+		want        []monitoring.MetricData
+	}{
+		{
+			name:        "single window",
+			windowsData: [][]monitoring.MetricData{{series("Cpu", a, datapoint(0, 1), datapoint(0, 1))}},
+			want:        []monitoring.MetricData{series("Cpu", a, datapoint(0, 1))},
+		},
+		{
+			name: "boundary bucket kept once",
+			windowsData: [][]monitoring.MetricData{
+				{series("Cpu", a, datapoint(0, 1), datapoint(1, 2))},
+				{series("Cpu", map[string]string{"region": "r", "resourceId": "a"}, datapoint(1, 3), datapoint(2, 4))},
+			},
+			want: []monitoring.MetricData{series("Cpu", a, datapoint(0, 1), datapoint(1, 2), datapoint(2, 4))},
+		},
+		{
+			name: "series in order of first appearance",
+			windowsData: [][]monitoring.MetricData{
+				{series("Cpu", b, datapoint(0, 1))},
+				{series("Cpu", a, datapoint(1, 2)), series("Cpu", b, datapoint(1, 3))},
+				{series("Memory", b, datapoint(2, 4))},
+			},
+			want: []monitoring.MetricData{
+				series("Cpu", b, datapoint(0, 1), datapoint(1, 3)),
+				series("Cpu", a, datapoint(1, 2)),
+				series("Memory", b, datapoint(2, 4)),
+			},
+		},
+		{
+			name: "datapoints without timestamp skipped",
+			windowsData: [][]monitoring.MetricData{
+				{series("Cpu", a, monitoring.AggregatedDatapoint{Value: common.Float64(1)})},
+				{series("Cpu", a, datapoint(1, 2))},
+			},
+			want: []monitoring.MetricData{series("Cpu", a, datapoint(1, 2))},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeMetricData(tt.windowsData)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeMetricData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveInterval(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		interval      string
+		timeRange     time.Duration
+		minInterval   time.Duration
+		maxDataPoints int64
+		want          string
+	}{
+		{name: "explicit interval", interval: "[5m]", timeRange: 365 * 24 * time.Hour, want: "5m"},
+		{name: "auto finest interval", interval: "auto", timeRange: 6 * time.Hour, maxDataPoints: 1000, want: "1m"},
+		{name: "no interval is auto", interval: "", timeRange: 6 * time.Hour, maxDataPoints: 1000, want: "1m"},
+		{name: "placeholder is auto", interval: "select interval", timeRange: 6 * time.Hour, maxDataPoints: 1000, want: "1m"},
+		{name: "limited by the max data points", interval: "[auto]", timeRange: 6 * time.Hour, maxDataPoints: 100, want: "5m"},
+		{name: "limited by the min interval", interval: "auto", timeRange: 6 * time.Hour, minInterval: 10 * time.Minute, maxDataPoints: 1000, want: "1h"},
+		{name: "limited by the datapoints per series", interval: "auto", timeRange: 30 * 24 * time.Hour, maxDataPoints: 100000, want: "1h"},
+		{name: "coarsest interval when none fits", interval: "auto", timeRange: 10 * 365 * 24 * time.Hour, maxDataPoints: 1000, want: "1d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := backend.DataQuery{
+				TimeRange:     backend.TimeRange{From: from, To: from.Add(tt.timeRange)},
+				Interval:      tt.minInterval,
+				MaxDataPoints: tt.maxDataPoints,
+			}
+			if got := resolveInterval(tt.interval, query); got != tt.want {
+				t.Errorf("resolveInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlignDataPoints(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{t0, t0.Add(time.Minute), t0.Add(2 * time.Minute), t0.Add(3 * time.Minute), t0.Add(4 * time.Minute)}
+	values := map[time.Time]float64{times[1]: 1, times[3]: 3}
+	value := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		fillMode string
+		values   map[time.Time]float64
+		want     []*float64
+	}{
+		{name: "null by default", fillMode: "", values: values, want: []*float64{nil, value(1), nil, value(3), nil}},
+		{name: "null", fillMode: constants.FILL_MODE_NULL, values: values, want: []*float64{nil, value(1), nil, value(3), nil}},
+		{name: "zero", fillMode: constants.FILL_MODE_ZERO, values: values, want: []*float64{value(0), value(1), value(0), value(3), value(0)}},
+		{name: "previous", fillMode: constants.FILL_MODE_PREVIOUS, values: values, want: []*float64{nil, value(1), value(1), value(3), value(3)}},
+		{name: "no datapoint", fillMode: constants.FILL_MODE_PREVIOUS, values: map[time.Time]float64{}, want: []*float64{nil, nil, nil, nil, nil}},
+		{
+			name:     "datapoints outside the times ignored",
+			fillMode: constants.FILL_MODE_ZERO,
+			values:   map[time.Time]float64{t0.Add(-time.Minute): 9, times[0]: 0.5},
+			want:     []*float64{value(0.5), value(0), value(0), value(0), value(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := alignDataPoints(times, tt.values, tt.fillMode)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alignDataPoints() = %v, want %v", derefAll(got), derefAll(tt.want))
+			}
+		})
+	}
+}
+
+// derefAll returns the values of aligned datapoints for display, nil for the missing ones.
+func derefAll(values []*float64) []interface{} {
+	display := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if v == nil {
+			display = append(display, nil)
+			continue
+		}
+		display = append(display, *v)
+	}
+	return display
+}
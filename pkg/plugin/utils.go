@@ -6,9 +6,9 @@
 package plugin
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/x509"
+	"encoding/pem"
 	"regexp"
 	"sort"
 	"strings"
@@ -20,15 +20,9 @@ import (
 	"github.com/oracle/oci-go-sdk/v65/common"
 	"github.com/oracle/oci-go-sdk/v65/monitoring"
 	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+	"github.com/pkg/errors"
 )
 
-// Prepare format to decode SecureJson
-func transcode(in, out interface{}) {
-	buf := new(bytes.Buffer)
-	json.NewEncoder(buf).Encode(in)
-	json.NewDecoder(buf).Decode(out)
-}
-
 // listMetrics will list all metrics with namespaces
 // API Operation: ListMetrics
 // Permission Required: METRIC_INSPECT
@@ -81,7 +75,7 @@ func listMetrics(ctx context.Context, mClient monitoring.MonitoringClient, req m
 // - ci: The cache instance to use for caching metadata.
 // - cacheKey: The key to use for caching metadata.
 // - fetchFor: A string indicating what data is being fetched for.
-// - mClient: The MonitoringClient instance to use for making API calls.
+// - ta: The TenancyAccess used to build the per-region MonitoringClient instances for the API calls.
 // - req: The ListMetricsRequest to use for fetching metrics metadata.
 // - regions: A slice of strings representing the regions to fetch metrics metadata from.
 //
@@ -92,7 +86,7 @@ func listMetricsMetadataFromAllRegion(
 	ci *ristretto.Cache,
 	cacheKey string,
 	fetchFor string,
-	mClient monitoring.MonitoringClient,
+	ta *TenancyAccess,
 	req monitoring.ListMetricsRequest,
 	regions []string) map[string][]string {
 
@@ -114,7 +108,7 @@ func listMetricsMetadataFromAllRegion(
 				if len(metadata) > 0 {
 					allRegionsData.Store(sRegion, metadata)
 				}
-			}(mClient, subscribedRegion)
+			}(ta.MonitoringClientForRegion(subscribedRegion), subscribedRegion)
 		}
 	}
 	wg.Wait()
@@ -330,6 +324,56 @@ func clientRetryPolicy() common.RetryPolicy {
 	return common.NewRetryPolicy(uint(15), clientRetryOperation, nextCallAt)
 }
 
+// isPrincipalEnvironment reports whether the environment authenticates with a principal of the
+// Grafana host (instance principal, resource principal or OKE workload identity) instead of user credentials.
+func isPrincipalEnvironment(environment string) bool {
+	switch environment {
+	case "OCI Instance", "OCI Resource Principal", "OCI Workload Identity":
+		return true
+	}
+	return false
+}
+
+// validatePrivateKey checks that the PEM private key of a profile can be decoded with its passphrase,
+// so that a malformed key and a wrong or missing passphrase are reported with different errors.
+//
+// Parameters:
+//   - profile: The profile name, used in errors.
+//   - privkey: The PEM encoded private key.
+//   - passphrase: The passphrase of the key, nil or empty when the key is not encrypted.
+//
+// Returns:
+//   - error: An error describing why the key cannot be used, otherwise nil.
+func validatePrivateKey(profile string, privkey string, passphrase *string) error {
+	block, _ := pem.Decode([]byte(privkey))
+	if block == nil {
+		return errors.New("Invalid Private Key in profile " + profile)
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return errors.New("Unsupported PKCS#8 encrypted Private Key in profile " + profile + ", convert it to a PKCS#1 encrypted key (openssl rsa -aes256)")
+	}
+
+	// legacy PEM encryption is the only one supported by the OCI SDK
+	encrypted := x509.IsEncryptedPEMBlock(block)
+	if encrypted && (passphrase == nil || *passphrase == "") {
+		return errors.New("Private Key in profile " + profile + " is encrypted, passphrase required")
+	}
+	if !encrypted {
+		// a passphrase set for a plain key is ignored by the SDK
+		passphrase = nil
+	}
+
+	if _, err := common.PrivateKeyFromBytes([]byte(privkey), passphrase); err != nil {
+		if encrypted {
+			// a wrong passphrase does not always fail the padding check, the key is then unreadable
+			return errors.New("Wrong passphrase for Private Key in profile " + profile)
+		}
+		return errors.New("Invalid Private Key in profile " + profile + ": " + err.Error())
+	}
+
+	return nil
+}
+
 // GetTenancyAccessKey retrieves the tenancy access key based on the tenancy mode.
 // If the tenancy mode is "multitenancy", it uses the provided tenancyOCID as the key.
 // Otherwise, it uses a predefined SingleTenancyKey.
@@ -353,8 +397,7 @@ func (o *OCIDatasource) GetTenancyAccessKey(tenancyOCID string) string {
 		takey = SingleTenancyKey
 	}
 
-	_, ok := o.tenancyAccess[takey]
-	if ok {
+	if o.getTenancyAccess(takey) != nil {
 		backend.Logger.Error("GetTenancyAccessKey", "Valid takey", takey)
 	} else {
 		backend.Logger.Error("GetTenancyAccessKey", "Invalid takey", takey)
@@ -640,28 +683,46 @@ func getUniqueIdsForLabels(namespace string, dimensions map[string]string, metri
 // addSelectedValuesLabels adds key-value pairs from selectedValuePairs to existingLabels.
 // Each element in selectedValuePairs is expected to be in the format "key=value".
 // The keys are converted to lowercase and the values are stripped of surrounding quotes.
+// The pairs matching several values, such as key=~"value1|value2", are labelled with the value of the series dimension.
 // If existingLabels is nil, a new map is created.
 //
 // Parameters:
 // - existingLabels: map[string]string - The map to which the key-value pairs will be added.
 // - selectedValuePairs: []string - A slice of strings containing key-value pairs in the format "key=value".
+// - dimensions: map[string]string - The dimensions of the series.
 //
 // Returns:
 // - map[string]string - The updated map with the added key-value pairs.
-func addSelectedValuesLabels(existingLabels map[string]string, selectedValuePairs []string) map[string]string {
+func addSelectedValuesLabels(existingLabels map[string]string, selectedValuePairs []string, dimensions map[string]string) map[string]string {
 	if existingLabels == nil {
 		existingLabels = map[string]string{}
 	}
 
 	for _, valuePair := range selectedValuePairs {
-		kv := strings.Split(valuePair, "=")
+		dimension, err := parseDimension(valuePair)
+		if err != nil {
+			continue
+		}
 
-		existingLabels[strings.ToLower(kv[0])] = strings.TrimPrefix(strings.TrimSuffix(kv[1], "\""), "\"")
+		if dimension.Operator == "=" {
+			existingLabels[strings.ToLower(dimension.Name)] = dimension.Value
+		} else if value, ok := dimensions[dimension.Name]; ok {
+			existingLabels[strings.ToLower(dimension.Name)] = value
+		}
 	}
 
 	return existingLabels
 }
 
+// stringValue returns the value of an optional string field of the OCI SDK, an empty string when it is not set.
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
 /*
 Function generates a custom metric label for the identified metric based on the
 legend format provided by the user where any known placeholders within the format
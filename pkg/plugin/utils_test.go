@@ -0,0 +1,73 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+// encryptedPrivateKey returns the test key encrypted with the legacy PEM encryption and the passphrase.
+func encryptedPrivateKey(t *testing.T, passphrase string) string {
+	t.Helper()
+	block, _ := pem.Decode([]byte(testPrivateKey(t)))
+	// the legacy PEM encryption is the only one the OCI SDK reads
+	encrypted, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, []byte(passphrase), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(encrypted))
+}
+
+func TestValidatePrivateKey(t *testing.T) {
+	passphrase := func(value string) *string {
+		return &value
+	}
+	encrypted := encryptedPrivateKey(t, "secret")
+
+	tests := []struct {
+		name       string
+		privkey    string
+		passphrase *string
+		wantErr    string
+	}{
+		{name: "plain key", privkey: testPrivateKey(t)},
+		{name: "plain key with a passphrase", privkey: testPrivateKey(t), passphrase: passphrase("ignored")},
+		{name: "encrypted key", privkey: encrypted, passphrase: passphrase("secret")},
+		{name: "encrypted key without passphrase", privkey: encrypted, wantErr: "is encrypted, passphrase required"},
+		{name: "encrypted key with an empty passphrase", privkey: encrypted, passphrase: passphrase(""), wantErr: "is encrypted, passphrase required"},
+		{name: "encrypted key with a wrong passphrase", privkey: encrypted, passphrase: passphrase("wrong"), wantErr: "Wrong passphrase"},
+		{name: "not PEM", privkey: "not a key", wantErr: "Invalid Private Key in profile DEFAULT"},
+		{
+			name:    "PKCS#8 encrypted key",
+			privkey: string(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("key")})),
+			wantErr: "Unsupported PKCS#8 encrypted Private Key",
+		},
+		{
+			name:    "malformed key",
+			privkey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("key")})),
+			wantErr: "Invalid Private Key in profile DEFAULT: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePrivateKey("DEFAULT", tt.privkey, tt.passphrase)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validatePrivateKey() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validatePrivateKey() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
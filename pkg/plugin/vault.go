@@ -0,0 +1,320 @@
+/*
+** Copyright © 2023 Oracle and/or its affiliates. All rights reserved.
+** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
+ */
+
+package plugin
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/common/auth"
+	"github.com/oracle/oci-go-sdk/v65/secrets"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/oracle/oci-grafana-metrics/pkg/plugin/constants"
+)
+
+// VaultKeyConfigurationProvider is a ConfigurationProvider for user principals whose API private key
+// is stored in an OCI Vault secret instead of the Grafana secure json data.
+//
+// The key is fetched when the provider is created and re-fetched in the background every
+// constants.VAULT_SECRET_REFRESH_INTERVAL, so a rotated secret is picked up without restarting Grafana.
+// The fingerprint is computed from the key, a rotated key therefore does not require to update the datasource.
+// The clients of the provider sign their requests with vaultRequestSigner, which reads the key and its key id
+// from the same snapshot.
+type VaultKeyConfigurationProvider struct {
+	tenancy    string
+	user       string
+	region     string
+	secretOCID string
+	passphrase *string
+	client     secrets.SecretsClient
+
+	mu          sync.RWMutex
+	key         *rsa.PrivateKey
+	fingerprint string
+	version     int64
+	fetchedAt   time.Time
+	refreshes   singleflight.Group
+}
+
+// NewVaultKeyConfigurationProvider - constructor
+//
+// Parameters:
+//   - client: The secrets client used to read the secret.
+//   - tenancy: The tenancy OCID.
+//   - user: The user OCID.
+//   - region: The region of the profile.
+//   - secretOCID: The OCID of the Vault secret holding the PEM private key.
+//   - passphrase: The passphrase of the private key.
+//
+// Returns:
+//   - *VaultKeyConfigurationProvider: The configuration provider.
+//   - error: An error if the secret cannot be read or does not hold a valid private key.
+func NewVaultKeyConfigurationProvider(client secrets.SecretsClient, tenancy, user, region, secretOCID string, passphrase *string) (*VaultKeyConfigurationProvider, error) {
+	p := &VaultKeyConfigurationProvider{
+		tenancy:    tenancy,
+		user:       user,
+		region:     region,
+		secretOCID: secretOCID,
+		passphrase: passphrase,
+		client:     client,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := p.fetch(ctx); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// vaultSecretsClient creates a secrets client authenticated with the principal of the Grafana host: OKE
+// workload identity when the resource principal environment is set in a Kubernetes pod without a resource
+// principal session token, resource principal when the resource principal environment is set otherwise, and
+// instance principal when it is not set. The client targets the region of the secret.
+//
+// Parameters:
+//   - secretOCID: The OCID of the secret, its region is used as endpoint.
+//
+// Returns:
+//   - secrets.SecretsClient: The secrets client.
+//   - error: An error if the principal or the client cannot be created.
+func vaultSecretsClient(secretOCID string) (secrets.SecretsClient, error) {
+	var configProvider common.ConfigurationProvider
+	var err error
+	switch {
+	case os.Getenv(auth.ResourcePrincipalVersionEnvVar) != "" && os.Getenv(auth.KubernetesServiceHostEnvVar) != "" &&
+		os.Getenv(auth.ResourcePrincipalRPSTEnvVar) == "":
+		configProvider, err = auth.OkeWorkloadIdentityConfigurationProvider()
+	case os.Getenv(auth.ResourcePrincipalVersionEnvVar) != "":
+		configProvider, err = auth.ResourcePrincipalConfigurationProvider()
+	default:
+		configProvider, err = auth.InstancePrincipalConfigurationProvider()
+	}
+	if err != nil {
+		return secrets.SecretsClient{}, errors.Wrap(err, "error with principal used to read vault secrets")
+	}
+
+	client, err := secrets.NewSecretsClientWithConfigurationProvider(configProvider)
+	if err != nil {
+		return secrets.SecretsClient{}, errors.Wrap(err, "error creating secrets client")
+	}
+	// ocid1.vaultsecret.<realm>.<region>.<unique id>
+	if parts := strings.Split(secretOCID, "."); len(parts) == 5 && parts[3] != "" {
+		client.SetRegion(parts[3])
+	}
+
+	return client, nil
+}
+
+// keyFingerprint returns the fingerprint of the public key of an API signing key, as shown in the OCI console.
+func keyFingerprint(key *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// fetch reads the current version of the secret and replaces the private key.
+func (p *VaultKeyConfigurationProvider) fetch(ctx context.Context) error {
+	res, err := p.client.GetSecretBundle(ctx, secrets.GetSecretBundleRequest{
+		SecretId: common.String(p.secretOCID),
+		Stage:    secrets.GetSecretBundleStageCurrent,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error reading vault secret "+p.secretOCID)
+	}
+	content, ok := res.SecretBundleContent.(secrets.Base64SecretBundleContentDetails)
+	if !ok || content.Content == nil {
+		return errors.New("vault secret " + p.secretOCID + " has no base64 content")
+	}
+	pemKey, err := base64.StdEncoding.DecodeString(*content.Content)
+	if err != nil {
+		return errors.Wrap(err, "error decoding vault secret "+p.secretOCID)
+	}
+	if err := validatePrivateKey("vault secret "+p.secretOCID, string(pemKey), p.passphrase); err != nil {
+		return err
+	}
+	key, err := common.PrivateKeyFromBytes(pemKey, p.passphrase)
+	if err != nil {
+		return errors.Wrap(err, "error reading private key of vault secret "+p.secretOCID)
+	}
+	fingerprint, err := keyFingerprint(key)
+	if err != nil {
+		return errors.Wrap(err, "error computing fingerprint of vault secret "+p.secretOCID)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if res.VersionNumber != nil && *res.VersionNumber != p.version && p.version != 0 {
+		backend.Logger.Info("VaultKeyConfigurationProvider", "secret", p.secretOCID, "rotated to version", *res.VersionNumber)
+	}
+	p.key = key
+	p.fingerprint = fingerprint
+	if res.VersionNumber != nil {
+		p.version = *res.VersionNumber
+	}
+	p.fetchedAt = time.Now()
+
+	return nil
+}
+
+// refreshIfNeeded starts a refresh of the secret once constants.VAULT_SECRET_REFRESH_INTERVAL elapsed.
+// The refresh runs in the background, the requests keep being signed with the current key meanwhile.
+func (p *VaultKeyConfigurationProvider) refreshIfNeeded() {
+	p.mu.RLock()
+	needed := p.needsRefresh(time.Now())
+	p.mu.RUnlock()
+	if !needed {
+		return
+	}
+
+	// a single refresh runs at a time, the requests arriving meanwhile do not start another one
+	p.refreshes.DoChan("refresh", func() (interface{}, error) {
+		p.refresh()
+		return nil, nil
+	})
+}
+
+// needsRefresh tells whether the secret is to be fetched again, the caller holds p.mu.
+func (p *VaultKeyConfigurationProvider) needsRefresh(now time.Time) bool {
+	return now.Sub(p.fetchedAt) >= constants.VAULT_SECRET_REFRESH_INTERVAL
+}
+
+// refresh re-fetches the secret. On failure the current key is kept and the next attempt waits for another interval.
+func (p *VaultKeyConfigurationProvider) refresh() {
+	p.mu.Lock()
+	now := time.Now()
+	if !p.needsRefresh(now) {
+		p.mu.Unlock()
+		return
+	}
+	p.fetchedAt = now
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := p.fetch(ctx); err != nil {
+		backend.Logger.Error("VaultKeyConfigurationProvider", "secret", p.secretOCID, "keeping current key, refresh failed", err)
+	}
+}
+
+// snapshot returns the current private key together with its key id, read under the same lock so that a refresh
+// can never pair the new key with the fingerprint of the previous one.
+func (p *VaultKeyConfigurationProvider) snapshot() (*rsa.PrivateKey, string) {
+	p.refreshIfNeeded()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.key, fmt.Sprintf("%s/%s/%s", p.tenancy, p.user, p.fingerprint)
+}
+
+// PrivateRSAKey returns the private key read from the vault secret.
+func (p *VaultKeyConfigurationProvider) PrivateRSAKey() (*rsa.PrivateKey, error) {
+	key, _ := p.snapshot()
+	return key, nil
+}
+
+// KeyID returns the key id built from the tenancy, the user and the fingerprint of the current key.
+func (p *VaultKeyConfigurationProvider) KeyID() (string, error) {
+	_, keyID := p.snapshot()
+	return keyID, nil
+}
+
+// TenancyOCID returns the tenancy OCID of the profile.
+func (p *VaultKeyConfigurationProvider) TenancyOCID() (string, error) {
+	if p.tenancy == "" {
+		return "", fmt.Errorf("tenancy OCID can not be empty")
+	}
+	return p.tenancy, nil
+}
+
+// UserOCID returns the user OCID of the profile.
+func (p *VaultKeyConfigurationProvider) UserOCID() (string, error) {
+	if p.user == "" {
+		return "", fmt.Errorf("user OCID can not be empty")
+	}
+	return p.user, nil
+}
+
+// KeyFingerprint returns the fingerprint of the current key.
+func (p *VaultKeyConfigurationProvider) KeyFingerprint() (string, error) {
+	p.refreshIfNeeded()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.fingerprint, nil
+}
+
+// Region returns the region of the profile.
+func (p *VaultKeyConfigurationProvider) Region() (string, error) {
+	return p.region, nil
+}
+
+// AuthType returns the authentication type of the provider.
+func (p *VaultKeyConfigurationProvider) AuthType() (common.AuthConfig, error) {
+	return common.AuthConfig{AuthType: common.UserPrincipal, IsFromConfigFile: false, OboToken: nil}, nil
+}
+
+// snapshotKeyProvider is a key provider holding a snapshot of the key and the key id of a vault provider.
+type snapshotKeyProvider struct {
+	key   *rsa.PrivateKey
+	keyID string
+}
+
+// PrivateRSAKey returns the key of the snapshot.
+func (k snapshotKeyProvider) PrivateRSAKey() (*rsa.PrivateKey, error) {
+	return k.key, nil
+}
+
+// KeyID returns the key id of the snapshot.
+func (k snapshotKeyProvider) KeyID() (string, error) {
+	return k.keyID, nil
+}
+
+// vaultRequestSigner signs each request with a single snapshot of the key of a vault provider. The default signer
+// of the SDK reads the key and the key id with two calls, a rotation of the secret in between would sign the
+// request with the new key under the fingerprint of the previous one.
+type vaultRequestSigner struct {
+	provider *VaultKeyConfigurationProvider
+}
+
+// Sign signs the request with the key and the key id of the current snapshot.
+func (s vaultRequestSigner) Sign(request *http.Request) error {
+	key, keyID := s.provider.snapshot()
+	return common.DefaultRequestSigner(snapshotKeyProvider{key: key, keyID: keyID}).Sign(request)
+}
+
+// useVaultSigner makes a client of a vault provider sign its requests with vaultRequestSigner, the clients of the
+// other providers are left unchanged.
+//
+// Parameters:
+//   - client: The base client of the OCI service client.
+//   - config: The configuration provider of the client.
+func useVaultSigner(client *common.BaseClient, config common.ConfigurationProvider) {
+	if vkProvider, ok := config.(*VaultKeyConfigurationProvider); ok {
+		client.Signer = vaultRequestSigner{provider: vkProvider}
+	}
+}
@@ -4,39 +4,91 @@
 */
 
 import React, { PureComponent } from 'react';
-import { Input, Select, InlineField, FieldSet, InlineSwitch, TextArea } from '@grafana/ui';
+import { Input, Select, InlineField, FieldSet, InlineSwitch, TextArea, Button } from '@grafana/ui';
 import {
   DataSourcePluginOptionsEditorProps,
   onUpdateDatasourceJsonDataOptionSelect,
   onUpdateDatasourceJsonDataOption,
-  onUpdateDatasourceJsonDataOptionChecked,
   onUpdateDatasourceSecureJsonDataOption,
   SelectableValue,
 } from '@grafana/data';
-import { OCIDataSourceOptions } from './types';
+import { OCIDataSourceOptions, OCIProfile, OCIXTenancy, OCISecureJsonData, DefaultOCIOptions } from './types';
 import {
   AuthProviders,
   TenancyChoices,
   AuthProviderOptions,
   TenancyChoiceOptions,
+  isPrincipalProvider,
+  AuthTypes,
+  AuthTypeOptions,
 } from './config.options';
 import {
   regions,
 } from './regionlist';
 
-interface Props extends DataSourcePluginOptionsEditorProps<OCIDataSourceOptions> {
+interface Props extends DataSourcePluginOptionsEditorProps<OCIDataSourceOptions, OCISecureJsonData> {
   onUpdateDatasourceJsonDataOptionSelect: (props: Props, field: string) => (option: SelectableValue<string>) => void;
 }
 
 interface State {
-  dynamicRegion0: string[];
-  dynamicRegion1: string[];
-  dynamicRegion2: string[];
-  dynamicRegion3: string[];
-  dynamicRegion4: string[];
-  dynamicRegion5: string[];
+  dynamicRegions: string[];
 }
 
+/**
+ * Secured settings stored for every profile, with the profile index as suffix.
+ */
+const profileSecureFields = ['tenancy', 'user', 'fingerprint', 'privkey', 'privkeypass', 'customdomain', 'token'];
+
+/**
+ * Number of numbered profiles the previous versions of the ConfigEditor could configure.
+ */
+const legacyProfilesCount = 6;
+
+/**
+ * profileSecureKey
+ *
+ * Returns the key of a secured setting of a profile: the field followed by the index of a profile migrated
+ * from the numbered settings, or by an underscore and the profile name. The key never depends on the
+ * position of the profile in the list.
+ *
+ * @param {OCIProfile} profile - The profile.
+ * @param {string} field - The secured setting, e.g. privkey.
+ * @returns {string} The key of the setting in the secure json data.
+ */
+const profileSecureKey = (profile: OCIProfile, field: string): string =>
+  profile.index !== undefined ? `${field}${profile.index}` : `${field}_${profile.name}`;
+
+/**
+ * legacyProfiles
+ *
+ * Builds the profiles list from the numbered settings of previous versions (profile0, region0, ...).
+ * The block number becomes the profile index so that the existing secured settings are kept.
+ *
+ * @param {OCIDataSourceOptions} jsonData - The datasource settings.
+ * @returns {OCIProfile[]} The migrated profiles, a DEFAULT profile when nothing is configured.
+ */
+const legacyProfiles = (jsonData: OCIDataSourceOptions): OCIProfile[] => {
+  const profiles: OCIProfile[] = [];
+  for (let block = 0; block < legacyProfilesCount; block++) {
+    const name = jsonData[`profile${block}`];
+    if (!name) {
+      break;
+    }
+    profiles.push({
+      index: block,
+      name: name,
+      region: jsonData[`region${block}`],
+      customregionbool: jsonData[`customregionbool${block}`],
+      customregion: jsonData[`customregion${block}`],
+      authtype: jsonData[`authtype${block}`],
+    });
+  }
+  if (profiles.length === 0) {
+    profiles.push({ index: 0, name: DefaultOCIOptions.ConfigProfile });
+  }
+  return profiles;
+};
+
 /**
  * ConfigEditor Component
  *
@@ -45,158 +97,153 @@ interface State {
  * and credentials for accessing OCI resources.
  */
 export class ConfigEditor extends PureComponent<Props, State> {
-    // Initialize state with a default empty value
+    // Initialize state with the static regions merged with the regions of the configured profiles
     state: State = {
-      dynamicRegion0: [
-        ...regions,
-        ...(this.props.options.jsonData.region0
-          ? [this.props.options.jsonData.region0]
-          : []),
-      ], // Merge static regions and custom region0
-      dynamicRegion1: [
-        ...regions,
-        ...(this.props.options.jsonData.region1
-          ? [this.props.options.jsonData.region1]
-          : []),
-      ], // Merge static regions and custom region1
-      dynamicRegion2: [
+      dynamicRegions: [
         ...regions,
-        ...(this.props.options.jsonData.region2
-          ? [this.props.options.jsonData.region2]
-          : []),
-      ], // Merge static regions and custom region2
-      dynamicRegion3: [
-        ...regions,
-        ...(this.props.options.jsonData.region3
-          ? [this.props.options.jsonData.region3]
-          : []),
-      ], // Merge static regions and custom region3
-      dynamicRegion4: [
-        ...regions,
-        ...(this.props.options.jsonData.region4
-          ? [this.props.options.jsonData.region4]
-          : []),
-      ], // Merge static regions and custom region4
-      dynamicRegion5: [
-        ...regions,
-        ...(this.props.options.jsonData.region5
-          ? [this.props.options.jsonData.region5]
-          : []),
-      ], // Merge static regions and custom region5
+        ...(this.props.options.jsonData.profiles || [])
+          .map((profile) => profile.region || '')
+          .filter((region) => region !== '' && !regions.includes(region)),
+      ],
     };
 
     componentDidMount() {
       const { options, onOptionsChange } = this.props;
       const { jsonData } = options;
-  
-      if (!jsonData.profile0) {
+
+      if (!jsonData.profiles || jsonData.profiles.length === 0) {
         onOptionsChange({
           ...options,
           jsonData: {
             ...jsonData,
-            profile0: "DEFAULT",
+            profiles: legacyProfiles(jsonData),
           },
         });
       }
-    }  
-  
+    }
+
   /**
- * render
- *
- * This method renders the UI for the ConfigEditor component. It creates the form
- * for configuring the OCI data source, including fields for:
- * - Authentication Provider
- * - Cross Tenancy OCID (optional)
- * - Tenancy Mode (Single/Multi-tenancy)
- * - Config Profile Names
- * - Dedicated/Custom Regions
- * - Commercial Regions
- * - User OCIDs, Tenancy OCIDs, Fingerprints, and Private Keys.
- *
- * @returns {JSX.Element} The JSX to render.
- */
-  render() {
-    const { options } = this.props;
+   * updateProfile
+   *
+   * Updates the settings of the profile at the given position.
+   *
+   * @param {number} position - The position of the profile in the profiles list.
+   * @param {Partial<OCIProfile>} patch - The settings to change.
+   */
+  updateProfile = (position: number, patch: Partial<OCIProfile>) => {
+    const { options, onOptionsChange } = this.props;
+    const profiles = [...(options.jsonData.profiles || [])];
+    profiles[position] = { ...profiles[position], ...patch };
+    onOptionsChange({ ...options, jsonData: { ...options.jsonData, profiles } });
+  };
 
-    return (
-      <FieldSet label="Connection Details">
-        <InlineField
-          label="Authentication Provider"
-          labelWidth={28}
-          tooltip="Specify which OCI credentials chain to use"
-        >
-          <Select
-            className="width-30"
-            value={options.jsonData.environment || ''}
-            options={AuthProviderOptions}
-            defaultValue={options.jsonData.environment}
-            onChange={(option) => {
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'environment')(option);
-            }}
-          />
-        </InlineField>
-        {options.jsonData.environment === AuthProviders.OCI_INSTANCE  && (
-              <>
-      <InlineField
-          label="Cross Tenancy ocid (optional)"
-          labelWidth={28}
-          tooltip="AssumeRole compliant Cross Tenancy configuration. Do not use if you are not using Cross Tenancy configuration"
-        >
-        <Input
-          className="width-30"
-          value={options.jsonData.xtenancy0}
-          onChange={onUpdateDatasourceJsonDataOption(this.props, 'xtenancy0')}
-        />
-      </InlineField>
-            </>
-        )}
+  /**
+   * addProfile
+   *
+   * Appends an empty profile, its secured settings are keyed by its name.
+   */
+  addProfile = () => {
+    const { options, onOptionsChange } = this.props;
+    const profiles = options.jsonData.profiles || [];
+    onOptionsChange({
+      ...options,
+      jsonData: { ...options.jsonData, profiles: [...profiles, { name: '' }] },
+    });
+  };
 
-{/**
- * --------------------------------------------------------------------------
- *                          User Principals
- * --------------------------------------------------------------------------
- */}   
+  /**
+   * removeProfile
+   *
+   * Removes the profile at the given position and resets its secured settings.
+   *
+   * @param {number} position - The position of the profile in the profiles list.
+   */
+  removeProfile = (position: number) => {
+    const { options, onOptionsChange } = this.props;
+    const profiles = [...(options.jsonData.profiles || [])];
+    const [removed] = profiles.splice(position, 1);
+    const secureJsonFields = { ...options.secureJsonFields };
+    const secureJsonData: OCISecureJsonData = { ...(options.secureJsonData || {}) };
+    profileSecureFields.forEach((field) => {
+      secureJsonFields[profileSecureKey(removed, field)] = false;
+      secureJsonData[profileSecureKey(removed, field)] = '';
+    });
+    onOptionsChange({
+      ...options,
+      jsonData: { ...options.jsonData, profiles },
+      secureJsonFields,
+      secureJsonData,
+    });
+  };
 
-      {options.jsonData.environment === AuthProviders.OCI_USER  && (
-              <>
-        <InlineField              
-              label="Tenancy Mode"
-              labelWidth={28}
-              tooltip="Choose if want to enable multi-tenancy mode to fetch metrics accross multiple OCI tenancies"
-            >
-              <Select
-                className="width-30"
-                value={options.jsonData.tenancymode || ''}
-                options={TenancyChoiceOptions}
-                defaultValue={options.jsonData.tenancymode}
-                onChange={(option) => {
-                  onUpdateDatasourceJsonDataOptionSelect(this.props, 'tenancymode')(option);
-                }}
-              />
-            </InlineField>
-            </>
-        )}        
-            <br></br>
+  /**
+   * updateXtenancy
+   *
+   * Updates the target tenancy at the given position.
+   *
+   * @param {number} position - The position of the target tenancy in the list.
+   * @param {Partial<OCIXTenancy>} patch - The settings to change.
+   */
+  updateXtenancy = (position: number, patch: Partial<OCIXTenancy>) => {
+    const { options, onOptionsChange } = this.props;
+    const xtenancies = [...(options.jsonData.xtenancies || [])];
+    xtenancies[position] = { ...xtenancies[position], ...patch };
+    onOptionsChange({ ...options, jsonData: { ...options.jsonData, xtenancies } });
+  };
 
-{/**
- * --------------------------------------------------------------------------
- *                          DEFAULT Tenancy
- * --------------------------------------------------------------------------
- */}
+  /**
+   * addXtenancy
+   *
+   * Appends an empty target tenancy.
+   */
+  addXtenancy = () => {
+    const { options, onOptionsChange } = this.props;
+    const xtenancies = [...(options.jsonData.xtenancies || []), { ocid: '' }];
+    onOptionsChange({ ...options, jsonData: { ...options.jsonData, xtenancies } });
+  };
+
+  /**
+   * removeXtenancy
+   *
+   * Removes the target tenancy at the given position.
+   *
+   * @param {number} position - The position of the target tenancy in the list.
+   */
+  removeXtenancy = (position: number) => {
+    const { options, onOptionsChange } = this.props;
+    const xtenancies = (options.jsonData.xtenancies || []).filter((_, current) => current !== position);
+    onOptionsChange({ ...options, jsonData: { ...options.jsonData, xtenancies } });
+  };
 
-{/* User Principals - Default tenancy */}
-  {options.jsonData.environment === AuthProviders.OCI_USER && (
-      <FieldSet label="DEFAULT Connection Details">
+  /**
+   * renderProfile
+   *
+   * Renders the connection details of a profile: name, region or dedicated region,
+   * credentials and authentication type.
+   *
+   * @param {OCIProfile} profile - The profile to render.
+   * @param {number} position - The position of the profile in the profiles list.
+   * @returns {JSX.Element} The JSX to render.
+   */
+  renderProfile(profile: OCIProfile, position: number) {
+    const { options } = this.props;
+    const secureField = (field: string) => profileSecureKey(profile, field);
+    const configured = (field: string) => (options.secureJsonFields[secureField(field)] ? 'configured' : '');
+    // the secured settings of a profile keyed by its name would be lost by a rename
+    const nameLocked = position === 0 || (profile.index === undefined && profileSecureFields.some((field) => configured(field) !== ''));
 
+    return (
+      <FieldSet key={position} label={position === 0 ? 'DEFAULT Connection Details' : `Tenancy-${position} Connection Details`}>
       <InlineField
           label="Config Profile Name"
           labelWidth={28}
-          tooltip="Config profile name. Default value is DEFAULT."
+          tooltip="Config profile name. Default value is DEFAULT. The secured settings are saved under the profile name: remove the tenancy and add it again to rename it."
         >
         <Input
           className="width-30"
-          readOnly
-          value={options.jsonData.profile0}
+          readOnly={nameLocked}
+          value={profile.name}
+          onChange={(event) => this.updateProfile(position, { name: event.currentTarget.value })}
         />
       </InlineField>
       <InlineField
@@ -206,15 +253,13 @@ export class ConfigEditor extends PureComponent<Props, State> {
         >
           <InlineSwitch
             className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.customregionbool0 ? options.jsonData.customregionbool0 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'customregionbool0')}
+            value={profile.customregionbool || false}
+            onChange={(event) => this.updateProfile(position, { customregionbool: event.currentTarget.checked })}
           />
-        </InlineField>
-        </FieldSet>    
-      )}
+      </InlineField>
 
-  {/* Custom Region 0 */}
-  {options.jsonData.environment === AuthProviders.OCI_USER && options.jsonData?.customregionbool0 && (
+  {/* Custom Region */}
+  {profile.customregionbool && (
   <>
     <InlineField
             label="Region"
@@ -223,28 +268,27 @@ export class ConfigEditor extends PureComponent<Props, State> {
           >
             <Input
               className="width-30"
-              defaultValue={options.jsonData.customregion0}
-              onChange={onUpdateDatasourceJsonDataOption(this.props, 'customregion0')}
+              value={profile.customregion || ''}
+              onChange={(event) => this.updateProfile(position, { customregion: event.currentTarget.value })}
             />
     </InlineField>
-    
+
     <InlineField
       label="Domain"
       labelWidth={28}
       tooltip="Custom Domain"
     >
       <Input
-        placeholder={options.secureJsonFields.customdomain0 ? 'configured' : ''}
+        placeholder={configured('customdomain')}
         className="width-30"
-        onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'customdomain0')}
+        onChange={onUpdateDatasourceSecureJsonDataOption(this.props, secureField('customdomain'))}
       />
     </InlineField>
   </>
   )}
 
-  {/* Commercial Region 0 */}
-  {options.jsonData.environment === AuthProviders.OCI_USER && !options.jsonData?.customregionbool0 && (
-  <>
+  {/* Commercial Region */}
+  {!profile.customregionbool && (
       <InlineField
           label="Region"
           labelWidth={28}
@@ -252,42 +296,24 @@ export class ConfigEditor extends PureComponent<Props, State> {
         >
           <Select
             className="width-30"
-            value={
-              this.props.options.jsonData.region0
-                ? { label: this.props.options.jsonData.region0, value: this.props.options.jsonData.region0 }
-                : undefined
-            }
+            value={profile.region ? { label: profile.region, value: profile.region } : undefined}
             allowCustomValue
-            onCreateOption={(customRegion0) => {
-              const newOption: SelectableValue<string> = {
-                label: customRegion0,
-                value: customRegion0,
-              };
+            onCreateOption={(customRegion) => {
               // Update regions array to include the new option
               this.setState((prevState) => ({
-                dynamicRegion0: [...prevState.dynamicRegion0, customRegion0],
-              }));                    
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region0')(newOption);
+                dynamicRegions: [...prevState.dynamicRegions, customRegion],
+              }));
+              this.updateProfile(position, { region: customRegion });
             }}
-            options={this.state.dynamicRegion0.map((region) => ({
+            options={this.state.dynamicRegions.map((region) => ({
               label: region,
               value: region,
             }))}
-            defaultValue={
-              this.props.options.jsonData.region0
-                ? { label: this.props.options.jsonData.region0, value: this.props.options.jsonData.region0 }
-                : undefined
-            }
-            onChange={(option) => {
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region0')(option);
-            }}
+            onChange={(option) => this.updateProfile(position, { region: option.value })}
           />
         </InlineField>
-        </>
       )}
-  {/* DEFAULT Conf */}
-  {options.jsonData.environment === AuthProviders.OCI_USER && (
-  <>          
+
         <InlineField
               label="User OCID"
               labelWidth={28}
@@ -295,8 +321,8 @@ export class ConfigEditor extends PureComponent<Props, State> {
             >
               <Input
                 className="width-30"
-                placeholder={options.secureJsonFields.user0 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'user0')}
+                placeholder={configured('user')}
+                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, secureField('user'))}
                 />
       </InlineField>
       <InlineField
@@ -306,21 +332,36 @@ export class ConfigEditor extends PureComponent<Props, State> {
             >
               <Input
                 className="width-30"
-                placeholder={options.secureJsonFields.tenancy0 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'tenancy0')}
+                placeholder={configured('tenancy')}
+                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, secureField('tenancy'))}
                 />
       </InlineField>
+      {!profile.privkeysecret && (
       <InlineField
               label="Fingerprint"
               labelWidth={28}
               tooltip="Fingerprint"
             >
               <Input
-                placeholder={options.secureJsonFields.fingerprint0 ? 'configured' : ''}
+                placeholder={configured('fingerprint')}
+                className="width-30"
+                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, secureField('fingerprint'))}
+                />
+      </InlineField>
+      )}
+      <InlineField
+              label="Vault Secret OCID (optional)"
+              labelWidth={28}
+              tooltip="OCI Vault secret holding the private key, read with the instance or resource principal of the Grafana host and re-read on rotation. Leave empty to store the key in Grafana."
+            >
+              <Input
                 className="width-30"
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'fingerprint0')}
+                placeholder="ocid1.vaultsecret.oc1..xxx"
+                value={profile.privkeysecret || ''}
+                onChange={(event) => this.updateProfile(position, { privkeysecret: event.currentTarget.value })}
                 />
       </InlineField>
+      {!profile.privkeysecret && (
       <InlineField
               label="Private Key"
               labelWidth={28}
@@ -329,877 +370,237 @@ export class ConfigEditor extends PureComponent<Props, State> {
               <TextArea
                 type="text"
                 className="width-30"
-                placeholder={options.secureJsonFields.privkey0 ? 'configured' : ''}
+                placeholder={configured('privkey')}
                 cols={20}
                 rows={4}
                 maxLength={4096}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'privkey0')}
+                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, secureField('privkey'))}
                 />
-      </InlineField>       
-      {/* </FieldSet>     */}
-      </>
-      )}
-
-
-{/**
- * --------------------------------------------------------------------------
- *                          Multitenancy 1
- * --------------------------------------------------------------------------
- */}
-
-{/* User Principals - Multitenancy Tenancy 1*/}
-<br/>
-        {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.environment === AuthProviders.OCI_USER && (
-      <FieldSet label="Tenancy-1 Connection Details">
-      <InlineField
-              label="Config Profile Name"
-              labelWidth={28}
-              tooltip="Config profile name. Default value is DEFAULT."
-            >
-              <Input
-                className="width-30"
-                defaultValue={options.jsonData.profile1}
-                onChange={onUpdateDatasourceJsonDataOption(this.props, 'profile1')}
-              />
       </InlineField>
-      <InlineField
-          label="Dedicated Region"
-          labelWidth={28}
-          tooltip="Configure Dedicated Region YES/NO (default: NO)"
-        >
-          <InlineSwitch
-            className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.customregionbool1 ? options.jsonData.customregionbool1 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'customregionbool1')}
-          />
-        </InlineField>
-        </FieldSet>    
-      )}
-
-  {/* Custom Region 1 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.environment === AuthProviders.OCI_USER && options.jsonData?.customregionbool1 && (
-  <>
-    <InlineField
-            label="Region"
-            labelWidth={28}
-            tooltip="Custom Region"
-          >
-            <Input
-              className="width-30"
-              defaultValue={options.jsonData.customregion1}
-              onChange={onUpdateDatasourceJsonDataOption(this.props, 'customregion1')}
-            />
-    </InlineField>
-    
-    <InlineField
-      label="Domain"
-      labelWidth={28}
-      tooltip="Custom Domain"
-    >
-      <Input
-        placeholder={options.secureJsonFields.customdomain1 ? 'configured' : ''}
-        className="width-30"
-        onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'customdomain1')}
-      />
-    </InlineField>
-  </>
-  )}
-
-  {/* Commercial Region 1 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.environment === AuthProviders.OCI_USER && !options.jsonData?.customregionbool1 && (
-  <>
-      <InlineField
-          label="Region"
-          labelWidth={28}
-          tooltip="Specify the Region"
-        >
-          <Select
-            className="width-30"
-            value={
-              this.props.options.jsonData.region1
-                ? { label: this.props.options.jsonData.region1, value: this.props.options.jsonData.region1 }
-                : undefined
-            }
-            allowCustomValue
-            onCreateOption={(customRegion1) => {
-              const newOption: SelectableValue<string> = {
-                label: customRegion1,
-                value: customRegion1,
-              };
-              // Update regions array to include the new option
-              this.setState((prevState) => ({
-                dynamicRegion1: [...prevState.dynamicRegion1, customRegion1],
-              }));                    
-              // this.handleRegionChange(newOption, 1);
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region1')(newOption);
-            }}
-            options={this.state.dynamicRegion1.map((region) => ({
-              label: region,
-              value: region,
-            }))}
-            defaultValue={
-              this.props.options.jsonData.region1
-                ? { label: this.props.options.jsonData.region1, value: this.props.options.jsonData.region1 }
-                : undefined
-            }
-            onChange={(option) => {
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region1')(option);
-            }}
-          />      
-        </InlineField>
-        </>
       )}
-  {/* DEFAULT Conf */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.environment === AuthProviders.OCI_USER && (
-  <> 
-        <InlineField
-              label="User OCID"
-              labelWidth={28}
-              tooltip="User OCID"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.user1 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'user1')}
-                />
-      </InlineField>
       <InlineField
-              label="Tenancy OCID"
+              label="Private Key Passphrase"
               labelWidth={28}
-              tooltip="Tenancy OCID"
+              tooltip="Passphrase of the private key, only needed if the key is encrypted"
             >
               <Input
+                type="password"
                 className="width-30"
-                placeholder={options.secureJsonFields.tenancy1 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'tenancy1')}
+                placeholder={configured('privkeypass')}
+                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, secureField('privkeypass'))}
                 />
       </InlineField>
       <InlineField
-              label="Fingerprint"
+              label="Authentication Type"
               labelWidth={28}
-              tooltip="Fingerprint"
+              tooltip="API key signing, or security token created with oci session authenticate (default: API key)"
             >
-              <Input
+              <Select
                 className="width-30"
-                placeholder={options.secureJsonFields.fingerprint1 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'fingerprint1')}
-                />
+                options={AuthTypeOptions}
+                value={AuthTypeOptions.find((option) => option.value === profile.authtype) || AuthTypeOptions[0]}
+                onChange={(option) => this.updateProfile(position, { authtype: option.value })}
+              />
       </InlineField>
+      {profile.authtype === AuthTypes.SECURITY_TOKEN && (
       <InlineField
-              label="Private Key"
+              label="Security Token"
               labelWidth={28}
-              tooltip="Private Key"
+              tooltip="Content of the security token file. The Private Key field holds the session key."
             >
               <TextArea
                 type="text"
                 className="width-30"
-                placeholder={options.secureJsonFields.privkey1 ? 'configured' : ''}
+                placeholder={configured('token')}
                 cols={20}
                 rows={4}
-                maxLength={4096}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'privkey1')}
+                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, secureField('token'))}
                 />
       </InlineField>
-      <InlineField
-          label="Add another Tenancy ?"
+      )}
+      {position > 0 && (
+        <Button variant="secondary" icon="trash-alt" onClick={() => this.removeProfile(position)}>
+          Remove Tenancy
+        </Button>
+      )}
+      </FieldSet>
+    );
+  }
+
+  /**
+ * render
+ *
+ * This method renders the UI for the ConfigEditor component. It creates the form
+ * for configuring the OCI data source, including fields for:
+ * - Authentication Provider
+ * - Cross Tenancy OCID (optional), or the target tenancies of principals in multi-tenancy mode
+ * - Tenancy Mode (Single/Multi-tenancy)
+ * - One section per configured profile, any number of profiles in multi-tenancy mode
+ *
+ * @returns {JSX.Element} The JSX to render.
+ */
+  render() {
+    const { options } = this.props;
+    const multitenancy = options.jsonData.tenancymode === TenancyChoices.multitenancy;
+    const profiles = options.jsonData.profiles || [];
+    const xtenancies = options.jsonData.xtenancies || [];
+
+    return (
+      <FieldSet label="Connection Details">
+        <InlineField
+          label="Authentication Provider"
           labelWidth={28}
-          tooltip="Add Another tenancy YES/NO"
+          tooltip="Specify which OCI credentials chain to use"
         >
-          <InlineSwitch
+          <Select
             className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.addon1 ? options.jsonData.addon1 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'addon1')}
+            value={options.jsonData.environment || ''}
+            options={AuthProviderOptions}
+            defaultValue={options.jsonData.environment}
+            onChange={(option) => {
+              onUpdateDatasourceJsonDataOptionSelect(this.props, 'environment')(option);
+            }}
           />
         </InlineField>
-        </>
-        )}      
-
-
-{/**
- * --------------------------------------------------------------------------
- *                          Multitenancy 2
- * --------------------------------------------------------------------------
- */}
-
-{/* User Principals - Multitenancy Tenancy 2*/}
-<br/>
-{options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon1 === true && options.jsonData.environment === AuthProviders.OCI_USER && (
-      <FieldSet label="Tenancy-2 Connection Details">
-      <InlineField
-              label="Config Profile Name"
+        {isPrincipalProvider(options.jsonData.environment) && (
+              <>
+        <InlineField
+              label="Tenancy Mode"
               labelWidth={28}
-              tooltip="Config profile name. Default value is DEFAULT."
+              tooltip="Multi-tenancy mode reaches several tenancies through cross tenancy policies"
             >
-              <Input
+              <Select
                 className="width-30"
-                defaultValue={options.jsonData.profile2}
-                onChange={onUpdateDatasourceJsonDataOption(this.props, 'profile2')}
+                value={options.jsonData.tenancymode || ''}
+                options={TenancyChoiceOptions}
+                defaultValue={options.jsonData.tenancymode}
+                onChange={(option) => {
+                  onUpdateDatasourceJsonDataOptionSelect(this.props, 'tenancymode')(option);
+                }}
               />
-      </InlineField>
-      <InlineField
-          label="Dedicated Region"
-          labelWidth={28}
-          tooltip="Configure Dedicated Region YES/NO (default: NO)"
-        >
-          <InlineSwitch
-            className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.customregionbool2 ? options.jsonData.customregionbool2 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'customregionbool2')}
-          />
         </InlineField>
-        </FieldSet>    
-      )}
-
-  {/* Custom Region 2 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon1 === true && options.jsonData.environment === AuthProviders.OCI_USER && options.jsonData?.customregionbool2 && (
-  <>
-    <InlineField
-            label="Region"
-            labelWidth={28}
-            tooltip="Custom Region"
-          >
-            <Input
-              className="width-30"
-              defaultValue={options.jsonData.customregion2}
-              onChange={onUpdateDatasourceJsonDataOption(this.props, 'customregion2')}
-            />
-    </InlineField>
-    
-    <InlineField
-      label="Domain"
-      labelWidth={28}
-      tooltip="Custom Domain"
-    >
-      <Input
-        placeholder={options.secureJsonFields.customdomain2 ? 'configured' : ''}
-        className="width-30"
-        onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'customdomain2')}
-      />
-    </InlineField>
-  </>
-  )}
-
-  {/* Commercial Region 2 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon1 === true && options.jsonData.environment === AuthProviders.OCI_USER && !options.jsonData?.customregionbool2 && (
-  <>
+      {!multitenancy && (
       <InlineField
-          label="Region"
+          label="Cross Tenancy ocid (optional)"
           labelWidth={28}
-          tooltip="Specify the Region"
+          tooltip="AssumeRole compliant Cross Tenancy configuration. Do not use if you are not using Cross Tenancy configuration"
         >
-          <Select
-            className="width-30"
-            value={
-              this.props.options.jsonData.region2
-                ? { label: this.props.options.jsonData.region2, value: this.props.options.jsonData.region2 }
-                : undefined
-            }
-            allowCustomValue
-            onCreateOption={(customRegion2) => {
-              const newOption: SelectableValue<string> = {
-                label: customRegion2,
-                value: customRegion2,
-              };
-              // Update regions array to include the new option
-              this.setState((prevState) => ({
-                dynamicRegion2: [...prevState.dynamicRegion2, customRegion2],
-              }));                    
-              // this.handleRegionChange(newOption, 2);
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region2')(newOption);
-            }}
-            options={this.state.dynamicRegion2.map((region) => ({
-              label: region,
-              value: region,
-            }))}
-            defaultValue={
-              this.props.options.jsonData.region2
-                ? { label: this.props.options.jsonData.region2, value: this.props.options.jsonData.region2 }
-                : undefined
-            }
-            onChange={(option) => {
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region2')(option);
-            }}
-          />      
-        </InlineField>
-        </>
-      )}
-  {/* DEFAULT Conf */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon1 === true && options.jsonData.environment === AuthProviders.OCI_USER && (
-  <> 
-        <InlineField
-              label="User OCID"
-              labelWidth={28}
-              tooltip="User OCID"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.user2 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'user2')}
-                />
+        <Input
+          className="width-30"
+          value={options.jsonData.xtenancy0}
+          onChange={onUpdateDatasourceJsonDataOption(this.props, 'xtenancy0')}
+        />
       </InlineField>
-      <InlineField
-              label="Tenancy OCID"
+      )}
+      {multitenancy && (
+      <FieldSet label="Target Tenancies">
+        {xtenancies.map((xtenancy, position) => (
+          <InlineField
+              key={position}
+              label={`Target Tenancy ${position + 1}`}
               labelWidth={28}
-              tooltip="Tenancy OCID"
+              tooltip="Name and OCID of a tenancy the principal can reach through cross tenancy policies"
             >
+            <>
               <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.tenancy2 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'tenancy2')}
-                />
-      </InlineField>
-      <InlineField
-              label="Fingerprint"
-              labelWidth={28}
-              tooltip="Fingerprint"
-            >
+                className="width-15"
+                placeholder={`tenancy-${position + 1}`}
+                value={xtenancy.name || ''}
+                onChange={(event) => this.updateXtenancy(position, { name: event.currentTarget.value })}
+              />
               <Input
                 className="width-30"
-                placeholder={options.secureJsonFields.fingerprint2 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'fingerprint2')}
-                />
-      </InlineField>
-      <InlineField
-              label="Private Key"
-              labelWidth={28}
-              tooltip="Private Key"
-            >
-              <TextArea
-                type="text"
-                className="width-30"
-                placeholder={options.secureJsonFields.privkey2 ? 'configured' : ''}
-                cols={20}
-                rows={4}
-                maxLength={4096}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'privkey2')}
-                />
-      </InlineField>
-      <InlineField
-          label="Add another Tenancy ?"
-          labelWidth={28}
-          tooltip="Add Another tenancy YES/NO"
-        >
-          <InlineSwitch
-            className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.addon2 ? options.jsonData.addon2 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'addon2')}
-          />
-        </InlineField>
-        </>
+                placeholder="ocid1.tenancy.oc1..xxx"
+                value={xtenancy.ocid}
+                onChange={(event) => this.updateXtenancy(position, { ocid: event.currentTarget.value })}
+              />
+              <Button variant="secondary" icon="trash-alt" aria-label="Remove target tenancy" onClick={() => this.removeXtenancy(position)} />
+            </>
+          </InlineField>
+        ))}
+        <Button variant="secondary" icon="plus" onClick={this.addXtenancy}>
+          Add Target Tenancy
+        </Button>
+      </FieldSet>
+      )}
+            </>
         )}
 
-
-
 {/**
  * --------------------------------------------------------------------------
- *                          Multitenancy 3
+ *                          User Principals
  * --------------------------------------------------------------------------
  */}
 
-{/* User Principals - Multitenancy Tenancy 3*/}
-<br/>
-{options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon2 === true && options.jsonData.environment === AuthProviders.OCI_USER && (
-      <FieldSet label="Tenancy-3 Connection Details">
-      <InlineField
-              label="Config Profile Name"
-              labelWidth={28}
-              tooltip="Config profile name. Default value is DEFAULT."
-            >
-              <Input
-                className="width-30"
-                defaultValue={options.jsonData.profile3}
-                onChange={onUpdateDatasourceJsonDataOption(this.props, 'profile3')}
-              />
-      </InlineField>
-      <InlineField
-          label="Dedicated Region"
-          labelWidth={28}
-          tooltip="Configure Dedicated Region YES/NO (default: NO)"
-        >
-          <InlineSwitch
-            className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.customregionbool3 ? options.jsonData.customregionbool3 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'customregionbool3')}
-          />
-        </InlineField>
-        </FieldSet>    
-      )}
-
-  {/* Custom Region 3 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon2 === true && options.jsonData.environment === AuthProviders.OCI_USER && options.jsonData?.customregionbool3 && (
-  <>
-    <InlineField
-            label="Region"
-            labelWidth={28}
-            tooltip="Custom Region"
-          >
-            <Input
-              className="width-30"
-              defaultValue={options.jsonData.customregion3}
-              onChange={onUpdateDatasourceJsonDataOption(this.props, 'customregion3')}
-            />
-    </InlineField>
-    
-    <InlineField
-      label="Domain"
-      labelWidth={28}
-      tooltip="Custom Domain"
-    >
-      <Input
-        placeholder={options.secureJsonFields.customdomain3 ? 'configured' : ''}
-        className="width-30"
-        onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'customdomain3')}
-      />
-    </InlineField>
-  </>
-  )}
-
-  {/* Commercial Region 3 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon2 === true && options.jsonData.environment === AuthProviders.OCI_USER && !options.jsonData?.customregionbool3 && (
-  <>
-      <InlineField
-          label="Region"
-          labelWidth={28}
-          tooltip="Specify the Region"
-        >
-          <Select
-            className="width-30"
-            value={
-              this.props.options.jsonData.region3
-                ? { label: this.props.options.jsonData.region3, value: this.props.options.jsonData.region3 }
-                : undefined
-            }
-            allowCustomValue
-            onCreateOption={(customRegion3) => {
-              const newOption: SelectableValue<string> = {
-                label: customRegion3,
-                value: customRegion3,
-              };
-              // Update regions array to include the new option
-              this.setState((prevState) => ({
-                dynamicRegion3: [...prevState.dynamicRegion3, customRegion3],
-              }));                    
-              // this.handleRegionChange(newOption, 3);
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region3')(newOption);
-            }}
-            options={this.state.dynamicRegion3.map((region) => ({
-              label: region,
-              value: region,
-            }))}
-            defaultValue={
-              this.props.options.jsonData.region3
-                ? { label: this.props.options.jsonData.region3, value: this.props.options.jsonData.region3 }
-                : undefined
-            }
-            onChange={(option) => {
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region3')(option);
-            }}
-          />      
-        </InlineField>
-        </>
-      )}
-  {/* DEFAULT Conf */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon2 === true && options.jsonData.environment === AuthProviders.OCI_USER && (
-  <> 
-        <InlineField
-              label="User OCID"
-              labelWidth={28}
-              tooltip="User OCID"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.user3 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'user3')}
-                />
-      </InlineField>
-      <InlineField
-              label="Tenancy OCID"
-              labelWidth={28}
-              tooltip="Tenancy OCID"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.tenancy3 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'tenancy3')}
-                />
-      </InlineField>
-      <InlineField
-              label="Fingerprint"
-              labelWidth={28}
-              tooltip="Fingerprint"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.fingerprint3 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'fingerprint3')}
-                />
-      </InlineField>
-      <InlineField
-              label="Private Key"
-              labelWidth={28}
-              tooltip="Private Key"
-            >
-              <TextArea
-                type="text"
-                className="width-30"
-                placeholder={options.secureJsonFields.privkey3 ? 'configured' : ''}
-                cols={20}
-                rows={4}
-                maxLength={4096}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'privkey3')}
-                />
-      </InlineField>
-      <InlineField
-          label="Add another Tenancy ?"
-          labelWidth={28}
-          tooltip="Add Another tenancy YES/NO"
-        >
-          <InlineSwitch
-            className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.addon3 ? options.jsonData.addon3 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'addon3')}
-          />
-        </InlineField>
-        </>
-        )}
-
 {/**
  * --------------------------------------------------------------------------
- *                          Multitenancy 4
+ *                          OCI Config File
  * --------------------------------------------------------------------------
  */}
 
-{/* User Principals - Multitenancy Tenancy 4 */}
-<br/>
-{options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon3 === true && options.jsonData.environment === AuthProviders.OCI_USER && (
-      <FieldSet label="Tenancy-4 Connection Details">
-      <InlineField
-              label="Config Profile Name"
+      {options.jsonData.environment === AuthProviders.OCI_CONFIG_FILE && (
+              <>
+        <InlineField
+              label="Config File Path"
               labelWidth={28}
-              tooltip="Config profile name. Default value is DEFAULT."
+              tooltip="OCI CLI config file on the Grafana host, reloaded when it changes. All its profiles are used in multi-tenancy mode, only DEFAULT otherwise."
             >
               <Input
                 className="width-30"
-                defaultValue={options.jsonData.profile4}
-                onChange={onUpdateDatasourceJsonDataOption(this.props, 'profile4')}
+                placeholder="~/.oci/config"
+                value={options.jsonData.configfilepath || ''}
+                onChange={onUpdateDatasourceJsonDataOption(this.props, 'configfilepath')}
               />
-      </InlineField>
-      <InlineField
-          label="Dedicated Region"
-          labelWidth={28}
-          tooltip="Configure Dedicated Region YES/NO (default: NO)"
-        >
-          <InlineSwitch
-            className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.customregionbool4 ? options.jsonData.customregionbool4 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'customregionbool4')}
-          />
-        </InlineField>
-        </FieldSet>    
-      )}
-
-  {/* Custom Region 4 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon3 === true && options.jsonData.environment === AuthProviders.OCI_USER && options.jsonData?.customregionbool4 && (
-  <>
-    <InlineField
-            label="Region"
-            labelWidth={28}
-            tooltip="Custom Region"
-          >
-            <Input
-              className="width-30"
-              defaultValue={options.jsonData.customregion4}
-              onChange={onUpdateDatasourceJsonDataOption(this.props, 'customregion4')}
-            />
-    </InlineField>
-    
-    <InlineField
-      label="Domain"
-      labelWidth={28}
-      tooltip="Custom Domain"
-    >
-      <Input
-        placeholder={options.secureJsonFields.customdomain4 ? 'configured' : ''}
-        className="width-30"
-        onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'customdomain4')}
-      />
-    </InlineField>
-  </>
-  )}
-
-  {/* Commercial Region 4 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon3 === true && options.jsonData.environment === AuthProviders.OCI_USER && !options.jsonData?.customregionbool4 && (
-  <>
-      <InlineField
-          label="Region"
-          labelWidth={28}
-          tooltip="Specify the Region"
-        >
-          <Select
-            className="width-30"
-            value={
-              this.props.options.jsonData.region4
-                ? { label: this.props.options.jsonData.region4, value: this.props.options.jsonData.region4 }
-                : undefined
-            }
-            allowCustomValue
-            onCreateOption={(customRegion4) => {
-              const newOption: SelectableValue<string> = {
-                label: customRegion4,
-                value: customRegion4,
-              };
-              // Update regions array to include the new option
-              this.setState((prevState) => ({
-                dynamicRegion4: [...prevState.dynamicRegion4, customRegion4],
-              }));                    
-              // this.handleRegionChange(newOption, 4);
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region4')(newOption);
-            }}
-            options={this.state.dynamicRegion4.map((region) => ({
-              label: region,
-              value: region,
-            }))}
-            defaultValue={
-              this.props.options.jsonData.region4
-                ? { label: this.props.options.jsonData.region4, value: this.props.options.jsonData.region4 }
-                : undefined
-            }
-            onChange={(option) => {
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region4')(option);
-            }}
-          />      
-        </InlineField>
-        </>
-      )}
-  {/* DEFAULT Conf */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon3 === true && options.jsonData.environment === AuthProviders.OCI_USER && (
-  <> 
+            </InlineField>
         <InlineField
-              label="User OCID"
-              labelWidth={28}
-              tooltip="User OCID"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.user4 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'user4')}
-                />
-      </InlineField>
-      <InlineField
-              label="Tenancy OCID"
-              labelWidth={28}
-              tooltip="Tenancy OCID"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.tenancy4 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'tenancy4')}
-                />
-      </InlineField>
-      <InlineField
-              label="Fingerprint"
-              labelWidth={28}
-              tooltip="Fingerprint"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.fingerprint4 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'fingerprint4')}
-                />
-      </InlineField>
-      <InlineField
-              label="Private Key"
+              label="Tenancy Mode"
               labelWidth={28}
-              tooltip="Private Key"
+              tooltip="Choose if want to enable multi-tenancy mode to fetch metrics accross multiple OCI tenancies"
             >
-              <TextArea
-                type="text"
+              <Select
                 className="width-30"
-                placeholder={options.secureJsonFields.privkey4 ? 'configured' : ''}
-                cols={20}
-                rows={4}
-                maxLength={3096}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'privkey4')}
-                />
-      </InlineField>
-      <InlineField
-          label="Add another Tenancy ?"
-          labelWidth={28}
-          tooltip="Add Another tenancy YES/NO"
-        >
-          <InlineSwitch
-            className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.addon4 ? options.jsonData.addon4 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'addon4')}
-          />
-        </InlineField>
-        </>
+                value={options.jsonData.tenancymode || ''}
+                options={TenancyChoiceOptions}
+                defaultValue={options.jsonData.tenancymode}
+                onChange={(option) => {
+                  onUpdateDatasourceJsonDataOptionSelect(this.props, 'tenancymode')(option);
+                }}
+              />
+            </InlineField>
+            </>
         )}
-        
-
-{/**
- * --------------------------------------------------------------------------
- *                          Multitenancy 5
- * --------------------------------------------------------------------------
- */}
 
-{/* User Principals - Multitenancy Tenancy 5 */}
-<br/>
-{options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon4 === true && options.jsonData.environment === AuthProviders.OCI_USER && (
-      <FieldSet label="Tenancy-5 Connection Details">
-      <InlineField
-              label="Config Profile Name"
+      {options.jsonData.environment === AuthProviders.OCI_USER  && (
+              <>
+        <InlineField
+              label="Tenancy Mode"
               labelWidth={28}
-              tooltip="Config profile name. Default value is DEFAULT."
+              tooltip="Choose if want to enable multi-tenancy mode to fetch metrics accross multiple OCI tenancies"
             >
-              <Input
+              <Select
                 className="width-30"
-                defaultValue={options.jsonData.profile5}
-                onChange={onUpdateDatasourceJsonDataOption(this.props, 'profile5')}
+                value={options.jsonData.tenancymode || ''}
+                options={TenancyChoiceOptions}
+                defaultValue={options.jsonData.tenancymode}
+                onChange={(option) => {
+                  onUpdateDatasourceJsonDataOptionSelect(this.props, 'tenancymode')(option);
+                }}
               />
-      </InlineField>
-      <InlineField
-          label="Dedicated Region"
-          labelWidth={28}
-          tooltip="Configure Dedicated Region YES/NO (default: NO)"
-        >
-          <InlineSwitch
-            className="width-30"
-            defaultChecked={options.jsonData && options.jsonData.customregionbool5 ? options.jsonData.customregionbool5 : false}
-            onChange={onUpdateDatasourceJsonDataOptionChecked(this.props, 'customregionbool5')}
-          />
-        </InlineField>
-        </FieldSet>    
-      )}
+            </InlineField>
+            <br></br>
 
-  {/* Custom Region 5 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon4 === true && options.jsonData.environment === AuthProviders.OCI_USER && options.jsonData?.customregionbool5 && (
-  <>
-    <InlineField
-            label="Region"
-            labelWidth={28}
-            tooltip="Custom Region"
-          >
-            <Input
-              className="width-30"
-              defaultValue={options.jsonData.customregion5}
-              onChange={onUpdateDatasourceJsonDataOption(this.props, 'customregion5')}
-            />
-    </InlineField>
-    
-    <InlineField
-      label="Domain"
-      labelWidth={28}
-      tooltip="Custom Domain"
-    >
-      <Input
-        placeholder={options.secureJsonFields.customdomain5 ? 'configured' : ''}
-        className="width-30"
-        onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'customdomain5')}
-      />
-    </InlineField>
-  </>
-  )}
+            {/* DEFAULT profile, followed by the additional tenancies in multi-tenancy mode */}
+            {profiles
+              .slice(0, multitenancy ? profiles.length : 1)
+              .map((profile, position) => this.renderProfile(profile, position))}
 
-  {/* Commercial Region 5 */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon4 === true && options.jsonData.environment === AuthProviders.OCI_USER && !options.jsonData?.customregionbool5 && (
-  <>
-      <InlineField
-          label="Region"
-          labelWidth={28}
-          tooltip="Specify the Region"
-        >
-          <Select
-            className="width-30"
-            value={
-              this.props.options.jsonData.region5
-                ? { label: this.props.options.jsonData.region5, value: this.props.options.jsonData.region5 }
-                : undefined
-            }
-            allowCustomValue
-            onCreateOption={(customRegion5) => {
-              const newOption: SelectableValue<string> = {
-                label: customRegion5,
-                value: customRegion5,
-              };
-              // Update regions array to include the new option
-              this.setState((prevState) => ({
-                dynamicRegion5: [...prevState.dynamicRegion5, customRegion5],
-              }));                    
-              // this.handleRegionChange(newOption, 5);
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region5')(newOption);
-            }}
-            options={this.state.dynamicRegion5.map((region) => ({
-              label: region,
-              value: region,
-            }))}
-            defaultValue={
-              this.props.options.jsonData.region5
-                ? { label: this.props.options.jsonData.region5, value: this.props.options.jsonData.region5 }
-                : undefined
-            }
-            onChange={(option) => {
-              onUpdateDatasourceJsonDataOptionSelect(this.props, 'region5')(option);
-            }}
-          />      
-        </InlineField>
-        </>
-      )}
-  {/* DEFAULT Conf */}
-  {options.jsonData.tenancymode === TenancyChoices.multitenancy && options.jsonData.addon4 === true && options.jsonData.environment === AuthProviders.OCI_USER && (
-  <> 
-        <InlineField
-              label="User OCID"
-              labelWidth={28}
-              tooltip="User OCID"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.user5 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'user5')}
-                />
-      </InlineField>
-      <InlineField
-              label="Tenancy OCID"
-              labelWidth={28}
-              tooltip="Tenancy OCID"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.tenancy5 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'tenancy5')}
-                />
-      </InlineField>
-      <InlineField
-              label="Fingerprint"
-              labelWidth={28}
-              tooltip="Fingerprint"
-            >
-              <Input
-                className="width-30"
-                placeholder={options.secureJsonFields.fingerprint5 ? 'configured' : ''}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'fingerprint5')}
-                />
-      </InlineField>
-      <InlineField
-              label="Private Key"
-              labelWidth={28}
-              tooltip="Private Key"
-            >
-              <TextArea
-                type="text"
-                className="width-30"
-                placeholder={options.secureJsonFields.privkey5 ? 'configured' : ''}
-                cols={20}
-                rows={4}
-                maxLength={3096}
-                onChange={onUpdateDatasourceSecureJsonDataOption(this.props, 'privkey5')}
-                />
-      </InlineField>
-        </>
+            {multitenancy && (
+              <Button variant="secondary" icon="plus" onClick={this.addProfile}>
+                Add Tenancy
+              </Button>
+            )}
+            </>
         )}
-
-
       </FieldSet>
     );
   }
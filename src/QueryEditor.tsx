@@ -4,11 +4,24 @@
 */
 
 import React, { useEffect, useState } from 'react';
-import { InlineField, InlineFieldRow, FieldSet, SegmentAsync, AsyncMultiSelect, Input, TextArea, RadioButtonGroup } from '@grafana/ui';
+import { InlineField, InlineFieldRow, FieldSet, SegmentAsync, AsyncMultiSelect, Input, TextArea, RadioButtonGroup, InlineSwitch } from '@grafana/ui';
 import { QueryEditorProps, SelectableValue } from '@grafana/data';
 import { getTemplateSrv } from '@grafana/runtime';
 import { OCIDataSource } from './datasource';
-import { OCIDataSourceOptions, AggregationOptions, IntervalOptions, OCIQuery, QueryPlaceholder } from './types';
+import {
+  OCIDataSourceOptions,
+  AggregationOptions,
+  IntervalOptions,
+  OCIQuery,
+  FillModes,
+  FillModeOptions,
+  FrameFormats,
+  FrameFormatOptions,
+  QueryPlaceholder,
+  QueryTypes,
+  QueryTypeOptions,
+  isAlarmQuery,
+} from './types';
 import QueryModel from './query_model';
 import { TenancyChoices } from './config.options';
 
@@ -29,6 +42,7 @@ export const QueryEditor: React.FC<Props> = (props) => {
   const [hasLegacyTenancy, setHasLegacyTenancy] = useState(false);
   const [queryValue, setQueryValue] = useState(query.queryTextRaw);
   const [queryRawValue, setQueryRawValue] = useState(query.rawQuery);
+  const [queryError, setQueryError] = useState<string | undefined>(undefined);
   const [tenancyValue, setTenancyValue] = useState(query.tenancyName);
   const [regionValue, setRegionValue] = useState(query.region);
   const [compartmentValue, setCompartmentValue] = useState(query.compartmentName);
@@ -53,7 +67,13 @@ export const QueryEditor: React.FC<Props> = (props) => {
    * @param runQuery - A boolean indicating whether to run the query after applying changes. Defaults to true.
    */  
   const onApplyQueryChange = (changedQuery: OCIQuery, runQuery = true) => {
-    if (runQuery) {        
+    if (runQuery && isAlarmQuery(changedQuery)) {
+      // alarm queries only need a tenancy and a region
+      onChange({ ...changedQuery });
+      if (changedQuery.tenancy && changedQuery.region && changedQuery.region !== QueryPlaceholder.Region) {
+        onRunQuery();
+      }
+    } else if (runQuery) {        
       const queryModel = new QueryModel(changedQuery, getTemplateSrv());
 
       onChange({ ...changedQuery });
@@ -98,7 +118,7 @@ export const QueryEditor: React.FC<Props> = (props) => {
 
     if (query.tagsValues !== undefined && query.tagsValues?.length > 0) {
       for (const eachTag of query.tagsValues) {
-        let indexToSplit = eachTag.lastIndexOf('.');
+        let indexToSplit = eachTag.indexOf('=');
         let key = eachTag.substring(0, indexToSplit);
         let val = eachTag.substring(indexToSplit + 1);
 
@@ -112,11 +132,10 @@ export const QueryEditor: React.FC<Props> = (props) => {
     return [initialDimensions, initialTags];
   };
 
-  // const [initialDimensions, initialTags] = init();
-  const [initialDimensions] = init();
+  const [initialDimensions, initialTags] = init();
 
   const [dimensionValue, setDimensionValue] = useState<Array<SelectableValue<string>>>(initialDimensions);
-  // const [tagValue, setTagValue] = useState<Array<SelectableValue<string>>>(initialTags);
+  const [tagValue, setTagValue] = useState<Array<SelectableValue<string>>>(initialTags);
 
   /**
    * addTemplateVariablesToOptions
@@ -400,30 +419,36 @@ export const QueryEditor: React.FC<Props> = (props) => {
   };
   
   
-  // tags will be used in future release
-  // const getTagOptions = () => {
-  //   return new Promise<Array<SelectableValue<string>>>((resolve) => {
-  //     setTimeout(async () => {
-  //       const response = await datasource.getTags(
-  //         query.tenancy,
-  //         query.compartment,
-  //         query.compartmentName,
-  //         query.region,
-  //         query.namespace
-  //       );
-  //       const result = response.map((res: any) => {
-  //         return {
-  //           label: res.key,
-  //           value: res.key,
-  //           options: res.values.map((val: any) => {
-  //             return { label: res.key + ' - ' + val, value: res.key + '=' + val };
-  //           }),
-  //         };
-  //       });
-  //       resolve(result);
-  //     }, 0);
-  //   });
-  // };
+  /**
+   * getTagOptions
+   *
+   * Fetches the freeform and defined tags of the resources of the selected namespace from the data source.
+   *
+   * @returns A promise that resolves to an array of SelectableValue options grouped by tag key.
+   */  
+  const getTagOptions = () => {
+    return new Promise<Array<SelectableValue<string>>>((resolve) => {
+      setTimeout(async () => {
+        const response = await datasource.getTags(
+          query.tenancy,
+          query.compartment,
+          query.compartmentName,
+          query.region,
+          query.namespace
+        );
+        const result = response.map((res: any) => {
+          return {
+            label: res.key,
+            value: res.key,
+            options: res.values.map((val: any) => {
+              return { label: res.key + ' - ' + val, value: res.key + '=' + val };
+            }),
+          };
+        });
+        resolve(result);
+      }, 0);
+    });
+  };
 
 
   /**
@@ -503,18 +528,6 @@ export const QueryEditor: React.FC<Props> = (props) => {
    * @param {any} data - The selected namespace data.
    */  
   const onNamespaceChange = (data: any) => {
-    // tags will be use in future release
-    // new Promise<Array<SelectableValue<string>>>(() => {
-    //   setTimeout(async () => {
-    //     await datasource.getTags(
-    //       query.tenancy,
-    //       query.compartment,
-    //       query.compartmentName,
-    //       query.region,
-    //       data.label
-    //     );
-    //   }, 0);
-    // });
     setNamespaceValue(data);  
     onApplyQueryChange(
       {
@@ -523,6 +536,7 @@ export const QueryEditor: React.FC<Props> = (props) => {
         metricNames: data.value,
         resourcegroup: undefined,
         metric: undefined,
+        tagsValues: undefined,
       },
       false
     );
@@ -552,6 +566,31 @@ export const QueryEditor: React.FC<Props> = (props) => {
   const onQueryTextChange = (data: any) => {
     setQueryValue(data);
     onApplyQueryChange({ ...query, queryTextRaw: data });
+    validateQueryText(data);
+  };
+
+  /**
+   * validateQueryText
+   * 
+   * Validates the raw query with the backend, the error is displayed under the query.
+   *
+   * @param {string} queryText - The raw query.
+   */  
+  const validateQueryText = (queryText: string) => {
+    if (!queryText) {
+      setQueryError(undefined);
+      return;
+    }
+    datasource
+      .validateQuery(queryText)
+      .then((result) => {
+        if (result.valid || !result.error) {
+          setQueryError(undefined);
+        } else {
+          setQueryError(`${result.error.message} (line ${result.error.line}, column ${result.error.column})`);
+        }
+      })
+      .catch(() => setQueryError(undefined));
   };
 
 
@@ -605,6 +644,78 @@ export const QueryEditor: React.FC<Props> = (props) => {
   };
 
 
+  /**
+   * onQueryTypeChange
+   * 
+   * Handles the change of the query type.
+   *
+   * @param {string} data - The new query type.
+   */  
+  const onQueryTypeChange = (data: string) => {
+    onApplyQueryChange({ ...query, queryType: data });
+  };
+
+
+  /**
+   * onAlarmNameChange
+   * 
+   * Handles the change of the alarm name of alarm queries.
+   *
+   * @param {string} data - The alarm display name, empty for all alarms.
+   */  
+  const onAlarmNameChange = (data: string) => {
+    onApplyQueryChange({ ...query, alarmName: data });
+  };
+
+
+  /**
+   * onAuditFilterChange
+   * 
+   * Handles the change of the event type and principal filters of audit queries.
+   *
+   * @param {Partial<OCIQuery>} data - The changed filter.
+   */  
+  const onAuditFilterChange = (data: Partial<OCIQuery>) => {
+    onApplyQueryChange({ ...query, ...data });
+  };
+
+
+  /**
+   * onFillModeChange
+   * 
+   * Handles the change of the fill mode.
+   *
+   * @param {FillModes} data - How the missing datapoints are filled.
+   */  
+  const onFillModeChange = (data: FillModes) => {
+    onApplyQueryChange({ ...query, fillMode: data });
+  };
+
+
+  /**
+   * onFrameFormatChange
+   * 
+   * Handles the change of the frame format.
+   *
+   * @param {FrameFormats} data - The format of the frames returned for the series.
+   */  
+  const onFrameFormatChange = (data: FrameFormats) => {
+    onApplyQueryChange({ ...query, frameFormat: data });
+  };
+
+
+  /**
+   * onStreamChange
+   * 
+   * Handles the change of the streaming switch.
+   *
+   * @param {boolean} data - True to stream the query through Grafana Live.
+   */  
+  const onStreamChange = (data: boolean) => {
+    onApplyQueryChange({ ...query, stream: data });
+  };
+
+
   /**
    * onDimensionChange
    * 
@@ -658,17 +769,23 @@ export const QueryEditor: React.FC<Props> = (props) => {
       query.dimensionValues = newDimensionValues;
     }
   };
-  // Tags will be use in future release
-  // const onTagChange = (data: any) => {
-  //   let newTagsValues: string[] = [];
+  /**
+   * onTagChange
+   * 
+   * Handles the change of the tags selection, only the series of the resources carrying the tags are returned.
+   *
+   * @param {any} data - The selected tags.
+   */  
+  const onTagChange = (data: any) => {
+    let newTagsValues: string[] = [];
 
-  //   data.map((incomingT: any) => {
-  //     newTagsValues.push(incomingT.value);
-  //   });
+    data.map((incomingT: any) => {
+      newTagsValues.push(incomingT.value);
+    });
 
-  //   setTagValue(data);
-  //   onApplyQueryChange({ ...query, tagsValues: newTagsValues });
-  // };
+    setTagValue(data);
+    onApplyQueryChange({ ...query, tagsValues: newTagsValues });
+  };
 
   // set tenancyName in case dashboard was created with version 4.x
   if (query.tenancy && !hasLegacyTenancy && !query.tenancyName) {
@@ -707,9 +824,23 @@ export const QueryEditor: React.FC<Props> = (props) => {
     setHasLegacyRawValue(true);    
 }
 
+  const alarmQuery = isAlarmQuery(query);
+
   return (        
     <>
       <FieldSet>
+        <InlineFieldRow>
+          <InlineField label="QUERY TYPE" labelWidth={20}>
+            <RadioButtonGroup
+              options={QueryTypeOptions}
+              size="sm"
+              value={query.queryType || QueryTypes.Metrics}
+              onChange={(data) => {
+                onQueryTypeChange(data);
+              }}
+            />
+          </InlineField>
+        </InlineFieldRow>
         <InlineFieldRow>
           {tmode === TenancyChoices.multitenancy && (
             <>
@@ -735,6 +866,7 @@ export const QueryEditor: React.FC<Props> = (props) => {
         </InlineField>
             </>
           )}
+        {!alarmQuery && (
         <InlineField grow={true} className='container text-right'>
           <RadioButtonGroup
             options={editorModes}
@@ -745,6 +877,7 @@ export const QueryEditor: React.FC<Props> = (props) => {
             }}
           />
         </InlineField>             
+        )}
         </InlineFieldRow>   
         <InlineFieldRow>
           <InlineField label="REGION" labelWidth={20}>
@@ -774,6 +907,43 @@ export const QueryEditor: React.FC<Props> = (props) => {
             />
           </InlineField>
         </InlineFieldRow>
+        {query.queryType === QueryTypes.AuditEvents && (
+        <InlineFieldRow>
+          <InlineField label="EVENT TYPE" labelWidth={20} tooltip="Part of the event type or name, e.g. LaunchInstance, leave empty for all the events">
+            <Input
+              className="width-14"
+              defaultValue={query.eventType}
+              onBlur={(event) => {
+                onAuditFilterChange({ eventType: event.target.value });
+              }}
+            />
+          </InlineField>
+          <InlineField label="PRINCIPAL" labelWidth={20} tooltip="Part of the name of the user or principal that made the request">
+            <Input
+              className="width-14"
+              defaultValue={query.principal}
+              onBlur={(event) => {
+                onAuditFilterChange({ principal: event.target.value });
+              }}
+            />
+          </InlineField>
+        </InlineFieldRow>
+        )}
+        {alarmQuery && query.queryType !== QueryTypes.AuditEvents && (
+        <InlineFieldRow>
+          <InlineField label="ALARM NAME" labelWidth={20} tooltip="Display name of the alarm, leave empty for all the alarms of the compartment">
+            <Input
+              className="width-28"
+              defaultValue={query.alarmName}
+              onBlur={(event) => {
+                onAlarmNameChange(event.target.value);
+              }}
+            />
+          </InlineField>
+        </InlineFieldRow>
+        )}
+        {!alarmQuery && (
+          <>
         <InlineFieldRow>
           <InlineField label="NAMESPACE" labelWidth={20}>
             <SegmentAsync
@@ -826,6 +996,8 @@ export const QueryEditor: React.FC<Props> = (props) => {
                       label="RAW QUERY"
                       labelWidth={20}
                       tooltip="type metric raw query"
+                      invalid={queryError !== undefined}
+                      error={queryError}
                     >
                       <TextArea
                         type="text"
@@ -893,6 +1065,26 @@ export const QueryEditor: React.FC<Props> = (props) => {
               />
             </>
           </InlineField>
+        </InlineFieldRow>
+        <InlineFieldRow>
+          <InlineField label="TAGS" labelWidth={20} grow={true} tooltip="Only the series of the resources carrying the selected tags are displayed">
+            <>
+              <AsyncMultiSelect
+                loadOptions={getTagOptions}
+                isSearchable={true}
+                defaultOptions={true}
+                allowCustomValue={false}
+                isClearable={true}
+                closeMenuOnSelect={false}
+                placeholder={QueryPlaceholder.Tags}
+                value={tagValue}
+                noOptionsMessage="No tagged resource found"
+                onChange={(data) => {
+                  onTagChange(data);
+                }}
+              />
+            </>
+          </InlineField>
         </InlineFieldRow>
           </>
             )}         
@@ -909,6 +1101,54 @@ export const QueryEditor: React.FC<Props> = (props) => {
               </> 
           </InlineField>
         </InlineFieldRow>
+        <InlineFieldRow>
+          <InlineField
+            label="FILL"
+            labelWidth={20}
+            tooltip="How the datapoints missing from a series are shown: null leaves a gap, zero and previous fill the gap with zero or with the previous value of the series"
+          >
+            <RadioButtonGroup
+              options={FillModeOptions}
+              size="sm"
+              value={query.fillMode || FillModes.Null}
+              onChange={(data) => {
+                onFillModeChange(data);
+              }}
+            />
+          </InlineField>
+        </InlineFieldRow>
+        <InlineFieldRow>
+          <InlineField
+            label="FORMAT"
+            labelWidth={20}
+            tooltip="Multi returns a frame per series, wide returns a single frame with a column per series, as expected by some transformations"
+          >
+            <RadioButtonGroup
+              options={FrameFormatOptions}
+              size="sm"
+              value={query.frameFormat || FrameFormats.Multi}
+              onChange={(data) => {
+                onFrameFormatChange(data);
+              }}
+            />
+          </InlineField>
+        </InlineFieldRow>
+        <InlineFieldRow>
+          <InlineField
+            label="STREAM"
+            labelWidth={20}
+            tooltip="Push new datapoints to the panel at the query interval through Grafana Live, instead of refreshing the whole time range"
+          >
+            <InlineSwitch
+              value={query.stream ?? false}
+              onChange={(event) => {
+                onStreamChange(event.currentTarget.checked);
+              }}
+            />
+          </InlineField>
+        </InlineFieldRow>
+          </>
+        )}
 
       </FieldSet>
     </>
@@ -19,13 +19,32 @@ import { SelectableValue } from '@grafana/data';
  * Enumerates the available authentication providers for the OCI data source.
  *
  * @property {string} OCI_USER - Represents the 'local' authentication method, where OCI user credentials are used.
+ * @property {string} OCI_CONFIG_FILE - Represents the 'OCI Config File' authentication method, where the profiles are read from an OCI CLI config file on the Grafana host.
  * @property {string} OCI_INSTANCE - Represents the 'OCI Instance' authentication method, where the Grafana instance is running within an OCI environment and uses instance principals.
+ * @property {string} OCI_RESOURCE - Represents the 'OCI Resource Principal' authentication method, where the Grafana instance is running in an OCI service (e.g. OCI Functions) and uses resource principals.
+ * @property {string} OCI_WORKLOAD - Represents the 'OCI Workload Identity' authentication method, where the Grafana instance is running in an OKE pod and uses OKE workload identity.
  */
 export enum AuthProviders {
   OCI_USER = 'local',
+  OCI_CONFIG_FILE = 'OCI Config File',
   OCI_INSTANCE = 'OCI Instance',
+  OCI_RESOURCE = 'OCI Resource Principal',
+  OCI_WORKLOAD = 'OCI Workload Identity',
 }
 
+/**
+ * @function isPrincipalProvider
+ * @description
+ * Tells whether the authentication provider uses a principal of the Grafana host instead of user credentials.
+ *
+ * @param {string | undefined} environment - The configured authentication provider.
+ * @returns {boolean} True for instance principal, resource principal and workload identity.
+ */
+export const isPrincipalProvider = (environment?: string): boolean =>
+  environment === AuthProviders.OCI_INSTANCE ||
+  environment === AuthProviders.OCI_RESOURCE ||
+  environment === AuthProviders.OCI_WORKLOAD;
+
 /**
  * @constant namespaces
  * @description
@@ -48,7 +67,7 @@ export const namespaces = ['oci_computeagent', 'oci_blockstore', 'oci_lbaas', 'o
  * // Example usage:
  * // const myEnvironment = environments[1]; // 'OCI Instance'
  */
-export const environments = ['local', 'OCI Instance'];
+export const environments = ['local', 'OCI Config File', 'OCI Instance', 'OCI Resource Principal', 'OCI Workload Identity'];
 
 /**
  * @enum TenancyChoices
@@ -100,9 +119,60 @@ export const AuthProviderOptions = [
     value: AuthProviders.OCI_USER,
     description: 'The grafana instance is configured with oci user principals',
   },
+  {
+    label: 'OCI Config File',
+    value: AuthProviders.OCI_CONFIG_FILE,
+    description: 'The grafana instance reads oci user principals from an OCI CLI config file and its key files',
+  },
   {
     label: 'OCI Instance',
     value: AuthProviders.OCI_INSTANCE,
     description: 'The grafana instance is configured in OCI environment',
   },
+  {
+    label: 'OCI Resource Principal',
+    value: AuthProviders.OCI_RESOURCE,
+    description: 'The grafana instance runs in an OCI service that provides resource principals',
+  },
+  {
+    label: 'OCI Workload Identity',
+    value: AuthProviders.OCI_WORKLOAD,
+    description: 'The grafana instance runs in an OKE pod configured with workload identity',
+  },
+] as Array<SelectableValue<string>>;
+
+/**
+ * @enum AuthTypes
+ * @description
+ * Enumerates the available authentication types of a user principal profile.
+ *
+ * @property {string} API_KEY - Requests are signed with the API signing key of the user.
+ * @property {string} SECURITY_TOKEN - Requests are signed with a session key and a security token created with `oci session authenticate`.
+ */
+export enum AuthTypes {
+  API_KEY = 'api_key',
+  SECURITY_TOKEN = 'security_token',
+}
+
+/**
+ * @constant AuthTypeOptions
+ * @description
+ * An array of selectable value options for choosing the authentication type of a user principal profile.
+ *
+ * @type {SelectableValue<string>[]}
+ * @example
+ * // Example usage:
+ * // <Select options={AuthTypeOptions} />
+ */
+export const AuthTypeOptions = [
+  {
+    label: 'API Key',
+    value: AuthTypes.API_KEY,
+    description: 'Sign requests with the API signing key of the user',
+  },
+  {
+    label: 'Security Token',
+    value: AuthTypes.SECURITY_TOKEN,
+    description: 'Sign requests with a session key and a security token (oci session authenticate)',
+  },
 ] as Array<SelectableValue<string>>;
@@ -4,14 +4,23 @@
 */
 
 import _,{ isString} from 'lodash';
-import { DataSourceInstanceSettings, ScopedVars, MetricFindValue } from '@grafana/data';
-import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
+import {
+  DataSourceInstanceSettings,
+  ScopedVars,
+  MetricFindValue,
+  DataQueryRequest,
+  DataQueryResponse,
+  LiveChannelScope,
+} from '@grafana/data';
+import { DataSourceWithBackend, getGrafanaLiveSrv, getTemplateSrv } from '@grafana/runtime';
+import { Observable, merge } from 'rxjs';
 import {
   OCIResourceItem,
   OCINamespaceWithMetricNamesItem,
   OCIResourceGroupWithMetricNamesItem,
   ResponseParser,
   OCIResourceMetadataItem,
+  OCIQueryValidation,
 } from './resource.response.parser';
 import {
   OCIDataSourceOptions,
@@ -26,7 +35,8 @@ import {
   tenanciesQueryRegex,
   DEFAULT_TENANCY,
   compartmentsQueryRegex,
-  SetAutoInterval,
+  AUTO_INTERVAL,
+  isAlarmQuery,
 } from "./types";
 import QueryModel from './query_model';
 
@@ -51,6 +61,8 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
   constructor(instanceSettings: DataSourceInstanceSettings<OCIDataSourceOptions>) {
     super(instanceSettings);
     this.jsonData = instanceSettings.jsonData;
+    // annotations use the query editor with the alarm history query type
+    this.annotations = {};
   }
  
 
@@ -95,13 +107,12 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
     const templateSrv = getTemplateSrv();
     const interpolatedQ = _.cloneDeep(query);
 
-    const TimeStart = parseInt(getTemplateSrv().replace("${__from}"), 10)
-    const TimeEnd  = parseInt(getTemplateSrv().replace("${__to}"), 10)
     if (this.isVariable(interpolatedQ.interval)) {
       interpolatedQ.interval = templateSrv.replace(interpolatedQ.interval, scopedVars);
     }
-    if (interpolatedQ.interval === QueryPlaceholder.Interval || interpolatedQ.interval === "auto" || interpolatedQ.interval === undefined){
-      interpolatedQ.interval = SetAutoInterval(TimeStart, TimeEnd);
+    // the auto interval is chosen by the backend from the time range of the query
+    if (interpolatedQ.interval === QueryPlaceholder.Interval || interpolatedQ.interval === undefined){
+      interpolatedQ.interval = AUTO_INTERVAL;
     }
     interpolatedQ.region = templateSrv.replace(interpolatedQ.region, scopedVars);
     interpolatedQ.tenancy = templateSrv.replace(interpolatedQ.tenancy, scopedVars);
@@ -110,6 +121,9 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
     interpolatedQ.resourcegroup = templateSrv.replace(interpolatedQ.resourcegroup, scopedVars);
     interpolatedQ.metric = templateSrv.replace(interpolatedQ.metric, scopedVars);
     interpolatedQ.queryTextRaw = templateSrv.replace(interpolatedQ.queryTextRaw, scopedVars);
+    interpolatedQ.alarmName = templateSrv.replace(interpolatedQ.alarmName, scopedVars);
+    interpolatedQ.eventType = templateSrv.replace(interpolatedQ.eventType, scopedVars);
+    interpolatedQ.principal = templateSrv.replace(interpolatedQ.principal, scopedVars);
 
     if (interpolatedQ.dimensionValues) {
       for (let i = 0; i < interpolatedQ.dimensionValues.length; i++) {
@@ -139,6 +153,59 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
     return interpolatedQ;
   }
 
+  /**
+   * Runs the queries of a panel. Queries with streaming enabled are subscribed through Grafana Live,
+   * the backend polls OCI at the query interval and pushes the new datapoints; the other queries
+   * are sent to the backend as usual.
+   *
+   * @param {DataQueryRequest<OCIQuery>} request - The query request.
+   * @returns {Observable<DataQueryResponse>} The query responses.
+   */
+  query(request: DataQueryRequest<OCIQuery>): Observable<DataQueryResponse> {
+    const streamTargets = request.targets.filter(
+      (target) => target.stream && !isAlarmQuery(target) && this.filterQuery(target)
+    );
+    if (streamTargets.length === 0) {
+      return super.query(request);
+    }
+
+    const observables: Array<Observable<DataQueryResponse>> = streamTargets.map((target) => {
+      const query = this.applyTemplateVariables(target, request.scopedVars);
+      return getGrafanaLiveSrv().getDataStream({
+        key: `${request.requestId}.${target.refId}`,
+        addr: {
+          scope: LiveChannelScope.DataSource,
+          namespace: this.uid,
+          path: `metrics/${this.streamPath(query)}`,
+          data: query,
+        },
+      });
+    });
+
+    const otherTargets = request.targets.filter((target) => !streamTargets.includes(target));
+    if (otherTargets.length > 0) {
+      observables.push(super.query({ ...request, targets: otherTargets }));
+    }
+
+    return merge(...observables);
+  }
+
+  /**
+   * Returns a channel path for a streamed query, identical queries share the same channel.
+   *
+   * @param {OCIQuery} query - The interpolated query.
+   * @returns {string} The channel path.
+   */
+  streamPath(query: OCIQuery): string {
+    const { refId, hide, key, datasource, ...model } = query;
+    const text = JSON.stringify(model);
+    let hash = 5381;
+    for (let i = 0; i < text.length; i++) {
+      hash = ((hash << 5) + hash + text.charCodeAt(i)) | 0;
+    }
+    return (hash >>> 0).toString(16);
+  }
+
 
   /**
    * Interpolates properties of an object using template variables.
@@ -738,4 +805,17 @@ export class OCIDataSource extends DataSourceWithBackend<OCIQuery, OCIDataSource
       return new ResponseParser().parseTags(response);
     });
   }
+
+  /**
+   * Validates a MQL query with the backend parser, before it is run.
+   *
+   * @param queryText - The MQL query, template variables are interpolated before the validation.
+   * @returns A promise that resolves to the validation result, with the location of the error when the query is not valid.
+   */
+  async validateQuery(queryText: string): Promise<OCIQueryValidation> {
+    const reqBody: JSON = {
+      query: getTemplateSrv().replace(queryText),
+    } as unknown as JSON;
+    return this.postResource(OCIResourceCall.Validate, reqBody);
+  }
 }
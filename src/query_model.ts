@@ -3,9 +3,9 @@
 ** Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl.
 */
 
-import { OCIQuery, QueryPlaceholder, AggregationOptions, SetAutoInterval } from './types';
+import { OCIQuery, QueryPlaceholder, AggregationOptions, AUTO_INTERVAL } from './types';
 import { ScopedVars } from '@grafana/data';
-import { TemplateSrv, getTemplateSrv } from '@grafana/runtime';
+import { TemplateSrv } from '@grafana/runtime';
 
 export default class QueryModel {
   target: OCIQuery;
@@ -99,25 +99,12 @@ export default class QueryModel {
     }  else {
       // if builder mode is used then:
       // add interval
-      let convertedInterval;
-      try {
-        // Check for special cases or undefined interval
-        if (this.target.interval === QueryPlaceholder.Interval || this.target.interval === "auto" || !this.target.interval) {
-          const timeStart = parseInt(getTemplateSrv().replace("${__from}"), 10);
-          const timeEnd = parseInt(getTemplateSrv().replace("${__to}"), 10);
-      
-          if (isNaN(timeStart) || isNaN(timeEnd)) {
-            convertedInterval = "[1m]"; // Default interval if parsing fails
-          } else {
-            convertedInterval = SetAutoInterval(timeStart, timeEnd); // Use custom function
-          }
-        } else {
-          convertedInterval = this.target.interval; // Use existing interval for other cases
-        }
-      } catch (error) {
-        convertedInterval = "[1m]"; // Default interval on error
+      // the [auto] window is replaced by the backend with the interval chosen from the time range
+      let convertedInterval = this.target.interval;
+      if (this.target.interval === QueryPlaceholder.Interval || this.target.interval === AUTO_INTERVAL || !this.target.interval) {
+        convertedInterval = '[' + AUTO_INTERVAL + ']';
       }
-   
+
       queryText += convertedInterval;
 
       // add dimensions
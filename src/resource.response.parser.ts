@@ -49,6 +49,24 @@ export interface OCIResourceMetadataItem {
   values: string[];
 }
 
+/**
+ * @interface OCIQueryValidation
+ * @description Represents the result of the validation of a MQL query.
+ * @property {boolean} valid - Whether the query is valid.
+ * @property {string} query - The query as rewritten by the backend, when valid.
+ * @property {object} error - The error and its location in the query, when not valid.
+ */
+export interface OCIQueryValidation {
+  valid: boolean;
+  query?: string;
+  error?: {
+    message: string;
+    position: number;
+    line: number;
+    column: number;
+  };
+}
+
 /**
  * @class ResponseParser
  * @description Provides methods for parsing responses from OCI API calls.
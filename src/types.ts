@@ -57,6 +57,10 @@ export enum OCIResourceCall {
    * Represents the API call to list tags.
    */
   Tags = 'tags',
+  /**
+   * Represents the API call to validate a MQL query.
+   */
+  Validate = 'validate',
 }
 
 /**
@@ -117,6 +121,11 @@ export interface DimensionPart {
 
 export type UnitOptions = 'minute' | 'hour';
 
+/**
+ * The auto interval, chosen by the backend from the time range and the maximum number of datapoints of the panel.
+ */
+export const AUTO_INTERVAL = 'auto';
+
 /**
  * Represents the available interval options for metric queries.
  */
@@ -125,7 +134,7 @@ export const IntervalOptions = [
   { label: '5 minutes', value: '[5m]', description: 'Maximum time range supported: 30 days' },
   { label: '1 hour', value: '[1h]', description: 'Maximum time range supported: 90 days' },
   { label: '1 day', value: '[1d]', description: 'Maximum time range supported: 90 days' },
-  { label: 'Auto', value: 'auto', description: 'Automatic selection of interval accordingly to OCI default' },
+  { label: 'Auto', value: AUTO_INTERVAL, description: 'Finest interval fitting the time range and the panel width' },
 ];
 
 /**
@@ -153,6 +162,97 @@ export const GroupOptions = [
   { label: 'grouping', value: 'grouping()' },
 ];
 
+/**
+ * Represents how the datapoints missing from a series are filled.
+ */
+export enum FillModes {
+  /**
+   * Missing datapoints are null, gaps are shown.
+   */
+  Null = 'null',
+  /**
+   * Missing datapoints are zero.
+   */
+  Zero = 'zero',
+  /**
+   * Missing datapoints take the previous value of the series.
+   */
+  Previous = 'previous',
+}
+
+/**
+ * Represents the available fill mode options of the query editor.
+ */
+export const FillModeOptions = [
+  { label: 'Null', value: FillModes.Null },
+  { label: 'Zero', value: FillModes.Zero },
+  { label: 'Previous', value: FillModes.Previous },
+];
+
+/**
+ * Represents the frame formats of the metric queries, following the data plane contract of Grafana.
+ */
+export enum FrameFormats {
+  /**
+   * One frame per series, each series having its own times.
+   */
+  Multi = 'multi',
+  /**
+   * A single frame, the series sharing the time field.
+   */
+  Wide = 'wide',
+}
+
+/**
+ * Represents the available frame format options of the query editor.
+ */
+export const FrameFormatOptions = [
+  { label: 'Multi', value: FrameFormats.Multi },
+  { label: 'Wide', value: FrameFormats.Wide },
+];
+
+/**
+ * Represents the query types served by the backend.
+ */
+export enum QueryTypes {
+  /**
+   * Metric datapoints, the default query type.
+   */
+  Metrics = 'metrics',
+  /**
+   * State transitions of the alarms, used for annotations.
+   */
+  AlarmHistory = 'alarm_history',
+  /**
+   * Current status of the alarms, as a table.
+   */
+  AlarmStatus = 'alarm_status',
+  /**
+   * Events of the Audit service, as annotations and logs.
+   */
+  AuditEvents = 'audit_events',
+}
+
+/**
+ * Represents the available query type options of the query editor.
+ */
+export const QueryTypeOptions = [
+  { label: 'Metrics', value: QueryTypes.Metrics },
+  { label: 'Alarm history', value: QueryTypes.AlarmHistory },
+  { label: 'Alarms status', value: QueryTypes.AlarmStatus },
+  { label: 'Audit events', value: QueryTypes.AuditEvents },
+];
+
+/**
+ * Tells whether a query reads alarms or audit events instead of metric datapoints.
+ *
+ * @param {OCIQuery} query - The query.
+ * @returns {boolean} True for the alarm and audit query types.
+ */
+export const isAlarmQuery = (query: OCIQuery): boolean => {
+  return query.queryType !== undefined && query.queryType !== QueryTypes.Metrics;
+};
+
 /**
  * Represents the structure of an OCI query.
  */
@@ -241,117 +341,106 @@ export interface OCIQuery extends DataQuery {
    * The group by option.
    */
   groupBy?: string;
+  /**
+   * How the datapoints missing from a series are filled, null when not set.
+   */
+  fillMode?: FillModes;
+  /**
+   * The format of the frames returned for the series, multi when not set.
+   */
+  frameFormat?: FrameFormats;
+  /**
+   * Streams the query through Grafana Live: new datapoints are pushed at the query interval.
+   */
+  stream?: boolean;
+  /**
+   * The display name of the alarm for alarm queries, all alarms of the compartment when empty.
+   */
+  alarmName?: string;
+  /**
+   * The event type or event name filter of audit queries, e.g. LaunchInstance.
+   */
+  eventType?: string;
+  /**
+   * The principal name filter of audit queries.
+   */
+  principal?: string;
 }
 
 export const defaultQuery: Partial<OCIQuery> = {};
 
 /**
- * These are options configured for each DataSource instance
+ * Represents a tenancy profile of the User Principals environment.
+ * The secured settings of the profile are stored with the profile index as suffix
+ * (tenancy<index>, user<index>, fingerprint<index>, privkey<index>, privkeypass<index>, customdomain<index>, token<index>).
  */
-export interface OCIDataSourceOptions extends DataSourceJsonData {
-  tenancyName: string; // name of the base tenancy
-  environment?: string; // oci-cli, oci-instance
-  tenancymode?: string; // multi-profile, cross-tenancy-policy
-  xtenancy0: string;
-
-
-  addon1: boolean;
-  addon2: boolean;
-  addon3: boolean;
-  addon4: boolean;
-
-  customregionbool0: boolean;
-  customregionbool1: boolean;
-  customregionbool2: boolean;
-  customregionbool3: boolean;
-  customregionbool4: boolean;
-  customregionbool5: boolean;
-
-  customregion0: string
-  customregion1: string  
-  customregion2: string  
-  customregion3: string  
-  customregion4: string  
-  customregion5: string  
-
-
-	profile0: string;
-	region0: string;
-
-	profile1: string;
-	region1: string;
-
-	profile2: string;
-	region2: string;
-
-	profile3: string;
-	region3: string;
-
-	profile4: string;
-	region4: string;
-
-	profile5: string;
-	region5: string;
+export interface OCIProfile {
+  /**
+   * The suffix of the secured settings of a profile migrated from the numbered settings of previous versions.
+   * The secured settings of the other profiles are keyed by profile name, e.g. privkey_DEFAULT.
+   */
+  index?: number;
+  /**
+   * The config profile name, the first profile is always DEFAULT.
+   */
+  name: string;
+  /**
+   * The region code of the profile.
+   */
+  region?: string;
+  /**
+   * Indicates whether the profile uses a dedicated region.
+   */
+  customregionbool?: boolean;
+  /**
+   * The dedicated region code of the profile.
+   */
+  customregion?: string;
+  /**
+   * The authentication type of the profile, api_key (default) or security_token.
+   */
+  authtype?: string;
+  /**
+   * The OCID of an OCI Vault secret holding the private key, used instead of the privkey secured setting.
+   */
+  privkeysecret?: string;
 }
 
 /**
- * Value that is used in the backend, but never sent over HTTP to the frontend
+ * Represents a target tenancy reached by a principal through cross tenancy policies.
  */
-export interface OCISecureJsonData {
-	tenancy0: string;
-	user0: string;
-	privkey0: string;
-	fingerprint0: string;
-  customdomain0: string
-
-	tenancy1: string;
-	user1: string;
-	fingerprint1: string;
-	privkey1: string;
-  customdomain1: string
-
-	tenancy2: string;
-	user2: string;
-	fingerprint2: string;
-	privkey2: string;
-  customdomain2: string
+export interface OCIXTenancy {
+  /**
+   * The name of the tenancy shown in the tenancy selector, defaults to tenancy-<position>.
+   */
+  name?: string;
+  /**
+   * The OCID of the tenancy.
+   */
+  ocid: string;
+}
 
-	tenancy3: string;
-	user3: string;
-	fingerprint3: string;
-	privkey3: string;
-  customdomain3: string
+/**
+ * These are options configured for each DataSource instance
+ */
+export interface OCIDataSourceOptions extends DataSourceJsonData {
+  tenancyName: string; // name of the base tenancy
+  environment?: string; // local, OCI Config File, OCI Instance, OCI Resource Principal, OCI Workload Identity
+  tenancymode?: string; // multi-profile, cross-tenancy-policy
+  xtenancy0: string;
+  xtenancies?: OCIXTenancy[]; // target tenancies of principals in multitenancy mode
 
-	tenancy4: string;
-	user4: string;
-	fingerprint4: string;
-	privkey4: string;
-  customdomain4: string
+  profiles?: OCIProfile[];
+  configfilepath?: string; // OCI CLI config file of the OCI Config File environment, default ~/.oci/config
 
-	tenancy5: string;
-	user5: string;
-	fingerprint5: string;
-	privkey5: string;
-  customdomain5: string
+  // numbered settings of previous versions, migrated to profiles by the ConfigEditor and the backend
+  [legacySetting: `${'profile' | 'region' | 'customregion' | 'authtype'}${number}`]: string | undefined;
+  [legacySwitch: `customregionbool${number}`]: boolean | undefined;
 }
 
 /**
- * Function to automatically determine the appropriate interval based on the time range.
- *
- * @param timestamp1 - The start timestamp in milliseconds.
- * @param timestamp2 - The end timestamp in milliseconds.
- * @returns The suggested interval string (e.g., "[1m]", "[5m]", "[1h]").
+ * Value that is used in the backend, but never sent over HTTP to the frontend.
+ * Keys are the secured setting names with the profile name as suffix, e.g. tenancy_DEFAULT or privkey_tenancy2,
+ * or with the index of a profile migrated from the numbered settings, e.g. tenancy0.
  */
-export const SetAutoInterval = (timestamp1: number, timestamp2: number): string => {
-  const differenceInMs = timestamp2 - timestamp1;
-  const differenceInHours = differenceInMs / (1000 * 60 * 60);
-
-  // use limits and defaults specified here: https://docs.oracle.com/en-us/iaas/Content/Monitoring/Reference/mql.htm#Interval
-  if (differenceInHours <= 6) {
-    return "[1m]"; // Equal or Less than 6 hours, set to 1 minute interval
-  } else if (differenceInHours < 36) {
-    return "[5m]"; // Between 6 and 36 hours, set to 5 minute interval
-  } else {
-    return "[1h]"; // More than 36 hours, set to 1 hour interval
-  }
-};
+export type OCISecureJsonData = Record<string, string>;